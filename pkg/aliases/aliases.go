@@ -0,0 +1,166 @@
+// Package aliases keeps track of the names resources were given across scans, so that a resource
+// whose name changes keeps its place in the stack. Names change when a resource is renamed in the
+// cloud or when naming rules change between versions, and users rename resources in the generated
+// code. With --aliases, every scan updates the file it names with the name of every resource found,
+// by type and ID, along with the name it was first discovered under and the names it had since:
+//
+//	[
+//	    {
+//	        "type": "aws-native:s3:Bucket",
+//	        "id": "logs-2021",
+//	        "name": "awsnatives3Bucketlogs2021",
+//	        "original": "awsnatives3Bucketlogs",
+//	        "aliases": ["awsnatives3Bucketlogs"]
+//	    }
+//	]
+//
+// In read mode, resources are read with aliases of their previous names, so that Pulumi moves them
+// in the state rather than dropping them and reading them anew. Code renaming an imported resource
+// passes the original name as an alias the same way.
+package aliases
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/pulumi/pulumi-cloud-import/pkg/config"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+// Entry is the names of a resource.
+type Entry struct {
+	Type     string   `json:"type"`
+	ID       string   `json:"id"`
+	Name     string   `json:"name"`
+	Original string   `json:"original"`
+	Aliases  []string `json:"aliases,omitempty"`
+}
+
+var (
+	once    sync.Once
+	mu      sync.Mutex
+	entries = map[string]*Entry{}
+	found   = map[string]bool{}
+)
+
+func key(typ, id string) string {
+	return typ + "\x00" + id
+}
+
+// load reads the file given with --aliases, if it was written by an earlier scan.
+func load() {
+	once.Do(func() {
+		file := config.Aliases.Value()
+		if file == "" {
+			return
+		}
+		b, err := os.ReadFile(file)
+		if os.IsNotExist(err) {
+			return
+		}
+		if err != nil {
+			panic(fmt.Sprintf("failed to read aliases file: %v", err))
+		}
+		var list []Entry
+		if err := json.Unmarshal(b, &list); err != nil {
+			panic(fmt.Sprintf("failed to parse %s: %v", file, err))
+		}
+		for i := range list {
+			entries[key(list[i].Type, list[i].ID)] = &list[i]
+		}
+	})
+}
+
+// Record records that the resource of typ and id was found under name, and returns the names it
+// had in earlier scans.
+func Record(typ, id, name string) []string {
+	if config.Aliases.Value() == "" {
+		return nil
+	}
+	load()
+	mu.Lock()
+	defer mu.Unlock()
+	k := key(typ, id)
+	found[k] = true
+	e, ok := entries[k]
+	if !ok {
+		entries[k] = &Entry{Type: typ, ID: id, Name: name, Original: name}
+		return nil
+	}
+	if e.Name != name {
+		if !contains(e.Aliases, e.Name) {
+			e.Aliases = append(e.Aliases, e.Name)
+		}
+		e.Name = name
+	}
+	aliases := []string{}
+	for _, a := range e.Aliases {
+		if a != name {
+			aliases = append(aliases, a)
+		}
+	}
+	return aliases
+}
+
+// Options returns the resource options a resource is read with, an alias of each of the previous
+// names returned by Record.
+func Options(names []string) []pulumi.ResourceOption {
+	if len(names) == 0 {
+		return nil
+	}
+	aliases := make([]pulumi.Alias, len(names))
+	for i, n := range names {
+		aliases[i] = pulumi.Alias{Name: pulumi.String(n)}
+	}
+	return []pulumi.ResourceOption{pulumi.Aliases(aliases)}
+}
+
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Write writes the names of the resources found to the file given with --aliases. Resources of
+// earlier scans that weren't found are kept, as a scan may have been narrowed by filters.
+func Write() error {
+	file := config.Aliases.Value()
+	if file == "" {
+		return nil
+	}
+	load()
+	mu.Lock()
+	defer mu.Unlock()
+
+	list := make([]Entry, 0, len(entries))
+	for _, e := range entries {
+		list = append(list, *e)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Type != list[j].Type {
+			return list[i].Type < list[j].Type
+		}
+		return list[i].ID < list[j].ID
+	})
+	b, err := json.MarshalIndent(list, "", "    ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(file, b, 0644); err != nil {
+		return err
+	}
+	renamed := 0
+	for k := range found {
+		if len(entries[k].Aliases) > 0 {
+			renamed++
+		}
+	}
+	fmt.Printf("Names of %d resources written to %s, %d of them had other names before\n", len(found), file, renamed)
+	return nil
+}
@@ -0,0 +1,58 @@
+// Package backpressure bounds the memory importers use when resources are discovered faster than
+// they can be read or written.
+//
+// Discovered resources are queued in a buffer of --max-buffer entries (100000 by default), and
+// workers block once it is full. When PULUMI_CLOUD_IMPORT_MAX_MEMORY_MB is set, a watchdog also
+// pauses workers while the heap is above the limit and resources are still queued.
+package backpressure
+
+import (
+	"runtime"
+	"sync/atomic"
+	"time"
+
+	"github.com/pulumi/pulumi-cloud-import/pkg/config"
+)
+
+// MaxBuffer returns the number of discovered resources that can be queued, as set by --max-buffer.
+func MaxBuffer() int {
+	return config.MaxBuffer.Int()
+}
+
+// Watchdog samples heap usage and holds workers back while it is above a limit.
+type Watchdog struct {
+	limit uint64
+	over  atomic.Bool
+}
+
+// NewWatchdog returns a watchdog for the limit, in bytes, set in PULUMI_CLOUD_IMPORT_MAX_MEMORY_MB,
+// or nil when no limit is set. A nil watchdog never blocks.
+func NewWatchdog() *Watchdog {
+	mb := config.MaxMemoryMB.Int()
+	if mb == 0 {
+		return nil
+	}
+	w := &Watchdog{limit: uint64(mb) * 1024 * 1024}
+	go w.run()
+	return w
+}
+
+func (w *Watchdog) run() {
+	var stats runtime.MemStats
+	for {
+		runtime.ReadMemStats(&stats)
+		w.over.Store(stats.HeapAlloc > w.limit)
+		time.Sleep(500 * time.Millisecond)
+	}
+}
+
+// Wait blocks while the heap is above the limit and queued reports resources waiting to be
+// consumed. Once the queue is drained waiting would not free anything, so Wait returns.
+func (w *Watchdog) Wait(queued func() int) {
+	if w == nil {
+		return
+	}
+	for w.over.Load() && queued() > 0 {
+		time.Sleep(100 * time.Millisecond)
+	}
+}
@@ -0,0 +1,202 @@
+// Package broker obtains the credentials AWS accounts are scanned with, for organizations whose
+// credentials don't come from a profile per account or from a role assumed in every account. The
+// multi program gets the credentials of its targets with an account from a broker, and the AWS
+// program those of the member accounts of the organization with --aws-credential-broker.
+//
+// Brokers are configured in YAML, strings containing {account} being replaced with the account ID:
+//
+//	broker: vault
+//	path: aws/creds/{account}-readonly
+//
+// Brokers other than the ones below plug in with the exec broker.
+package broker
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Credentials are the credentials of an account: a profile, or access keys.
+type Credentials struct {
+	Profile         string
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+}
+
+// Environ returns the environment variables pointing the AWS SDK at c. Access keys take precedence
+// over the profile of the environment.
+func (c Credentials) Environ() map[string]string {
+	if c.AccessKeyID == "" {
+		return map[string]string{"AWS_PROFILE": c.Profile}
+	}
+	return map[string]string{
+		"AWS_ACCESS_KEY_ID":     c.AccessKeyID,
+		"AWS_SECRET_ACCESS_KEY": c.SecretAccessKey,
+		"AWS_SESSION_TOKEN":     c.SessionToken,
+	}
+}
+
+// Broker obtains the credentials of an account.
+type Broker interface {
+	Credentials(account string) (Credentials, error)
+}
+
+// Config configures a broker.
+type Config struct {
+	// static, sso, vault or exec
+	Broker string `yaml:"broker"`
+	// static: profile of each account ID
+	Profiles map[string]string `yaml:"profiles"`
+	// sso: profile of the accounts, e.g. sso-{account}
+	Profile string `yaml:"profile"`
+	// vault: address of Vault, defaults to VAULT_ADDR
+	Address string `yaml:"address"`
+	// vault: path of the credentials in the AWS secrets engine, e.g. aws/creds/{account}-readonly
+	Path string `yaml:"path"`
+	// exec: command printing the credentials of an account
+	Command []string `yaml:"command"`
+}
+
+// Load returns the broker configured by the YAML file at path.
+func Load(path string) (Broker, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var c Config
+	if err := yaml.Unmarshal(b, &c); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return c.New()
+}
+
+// New returns the broker configured by c.
+func (c *Config) New() (Broker, error) {
+	switch c.Broker {
+	case "static":
+		return staticBroker(c.Profiles), nil
+	case "sso":
+		if c.Profile == "" {
+			return nil, fmt.Errorf("the sso credential broker needs a profile")
+		}
+		return execBroker{"aws", "configure", "export-credentials", "--profile", c.Profile, "--format", "process"}, nil
+	case "vault":
+		if c.Path == "" {
+			return nil, fmt.Errorf("the vault credential broker needs a path")
+		}
+		address := c.Address
+		if address == "" {
+			address = os.Getenv("VAULT_ADDR")
+		}
+		if address == "" {
+			return nil, fmt.Errorf("the vault credential broker needs an address or VAULT_ADDR")
+		}
+		return vaultBroker{address: strings.TrimSuffix(address, "/"), path: c.Path}, nil
+	case "exec":
+		if len(c.Command) == 0 {
+			return nil, fmt.Errorf("the exec credential broker needs a command")
+		}
+		return execBroker(c.Command), nil
+	default:
+		return nil, fmt.Errorf("unsupported credential broker %q", c.Broker)
+	}
+}
+
+// expand replaces {account} in s with account.
+func expand(s, account string) string {
+	return strings.ReplaceAll(s, "{account}", account)
+}
+
+// staticBroker scans every account with the profile it's mapped to.
+type staticBroker map[string]string
+
+func (b staticBroker) Credentials(account string) (Credentials, error) {
+	profile, ok := b[account]
+	if !ok {
+		return Credentials{}, fmt.Errorf("no profile for account %s", account)
+	}
+	return Credentials{Profile: profile}, nil
+}
+
+// processCredentials is the output of an AWS credential_process, which the exec broker expects
+// its command to print.
+type processCredentials struct {
+	Version         int        `json:"Version"`
+	AccessKeyID     string     `json:"AccessKeyId"`
+	SecretAccessKey string     `json:"SecretAccessKey"`
+	SessionToken    string     `json:"SessionToken"`
+	Expiration      *time.Time `json:"Expiration"`
+}
+
+// execBroker runs a command printing credentials in the format of an AWS credential_process. The
+// account is passed in {account} arguments and in PULUMI_CLOUD_IMPORT_ACCOUNT. The sso broker
+// runs the AWS CLI, which refreshes SSO credentials after `aws sso login`.
+type execBroker []string
+
+func (b execBroker) Credentials(account string) (Credentials, error) {
+	args := make([]string, len(b))
+	for i, arg := range b {
+		args[i] = expand(arg, account)
+	}
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Env = append(os.Environ(), "PULUMI_CLOUD_IMPORT_ACCOUNT="+account)
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return Credentials{}, fmt.Errorf("%s failed: %w", args[0], err)
+	}
+	var c processCredentials
+	if err := json.Unmarshal(out, &c); err != nil {
+		return Credentials{}, fmt.Errorf("failed to parse the credentials printed by %s: %w", args[0], err)
+	}
+	if c.AccessKeyID == "" || c.SecretAccessKey == "" {
+		return Credentials{}, fmt.Errorf("%s printed no access key", args[0])
+	}
+	if c.Expiration != nil && time.Until(*c.Expiration) < 15*time.Minute {
+		fmt.Printf("credentials of account %s expire at %s, the scan may not complete\n", account, c.Expiration.Format(time.RFC3339))
+	}
+	return Credentials{AccessKeyID: c.AccessKeyID, SecretAccessKey: c.SecretAccessKey, SessionToken: c.SessionToken}, nil
+}
+
+// vaultBroker reads credentials from the AWS secrets engine of Vault, authenticating with
+// VAULT_TOKEN.
+type vaultBroker struct {
+	address string
+	path    string
+}
+
+func (b vaultBroker) Credentials(account string) (Credentials, error) {
+	req, err := http.NewRequest(http.MethodGet, b.address+"/v1/"+strings.TrimPrefix(expand(b.path, account), "/"), nil)
+	if err != nil {
+		return Credentials{}, err
+	}
+	req.Header.Set("X-Vault-Token", os.Getenv("VAULT_TOKEN"))
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return Credentials{}, err
+	}
+	defer resp.Body.Close()
+	var body struct {
+		Data struct {
+			AccessKey     string `json:"access_key"`
+			SecretKey     string `json:"secret_key"`
+			SecurityToken string `json:"security_token"`
+		} `json:"data"`
+		Errors []string `json:"errors"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return Credentials{}, fmt.Errorf("failed to parse the response of vault: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return Credentials{}, fmt.Errorf("vault returned %s: %s", resp.Status, strings.Join(body.Errors, ", "))
+	}
+	return Credentials{AccessKeyID: body.Data.AccessKey, SecretAccessKey: body.Data.SecretKey, SessionToken: body.Data.SecurityToken}, nil
+}
@@ -0,0 +1,98 @@
+// Package cancel aborts the listing of a single scope while a scan runs, so that one pathological
+// type can't hold a whole run hostage. Scopes to cancel are appended to the file given with
+// --cancel-file, one per line, e.g.
+//
+//	echo aws-native:logs:LogStream >> cancel.txt
+//
+// The file is checked every second. A scope being listed is aborted, the resources already found
+// are kept, and a scope that hasn't started yet is skipped. A scope is a type token for most
+// programs, a resource group ID for Azure and a group/version/resource for Kubernetes, as printed
+// in the events and the summary.
+package cancel
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pulumi/pulumi-cloud-import/pkg/config"
+)
+
+// poll is how often the file given with --cancel-file is checked.
+const poll = time.Second
+
+var (
+	once      sync.Once
+	mu        sync.Mutex
+	requested = map[string]bool{}
+	active    = map[string]context.CancelFunc{}
+)
+
+// watch starts polling the file given with --cancel-file.
+func watch() {
+	path := config.CancelFile.Value()
+	if path == "" {
+		return
+	}
+	go func() {
+		for range time.Tick(poll) {
+			f, err := os.Open(path)
+			if err != nil {
+				continue
+			}
+			scanner := bufio.NewScanner(f)
+			for scanner.Scan() {
+				if scope := strings.TrimSpace(scanner.Text()); scope != "" {
+					request(scope)
+				}
+			}
+			f.Close()
+		}
+	}()
+}
+
+// request cancels scope, now if it's being listed or as soon as it starts otherwise.
+func request(scope string) {
+	mu.Lock()
+	defer mu.Unlock()
+	if requested[scope] {
+		return
+	}
+	requested[scope] = true
+	if cancel, ok := active[scope]; ok {
+		fmt.Printf("cancelling the listing of %s\n", scope)
+		cancel()
+	}
+}
+
+// Scope returns the context scope is listed with, which is cancelled when scope is listed in
+// --cancel-file. done must be called once the listing is over.
+func Scope(scope string) (ctx context.Context, done func()) {
+	once.Do(watch)
+	ctx, cancel := context.WithCancel(context.Background())
+	mu.Lock()
+	defer mu.Unlock()
+	if requested[scope] {
+		fmt.Printf("skipping %s, cancelled with %s\n", scope, config.CancelFile.Value())
+		cancel()
+		return ctx, func() {}
+	}
+	active[scope] = cancel
+	return ctx, func() {
+		mu.Lock()
+		defer mu.Unlock()
+		delete(active, scope)
+		cancel()
+	}
+}
+
+// Cancelled reports whether scope was cancelled with --cancel-file.
+func Cancelled(scope string) bool {
+	mu.Lock()
+	defer mu.Unlock()
+	return requested[scope]
+}
@@ -0,0 +1,208 @@
+// Package chaos injects throttling, server errors and timeouts into the HTTP calls importers make
+// to cloud APIs, so that retry and error handling can be exercised locally without a misbehaving account.
+//
+// Failure injection is off unless PULUMI_CLOUD_IMPORT_CHAOS is set, e.g.
+//
+//	PULUMI_CLOUD_IMPORT_CHAOS=throttle=0.1,error=0.05,timeout=0.01
+//
+// Each value is the probability of a request failing that way. PULUMI_CLOUD_IMPORT_CHAOS_SEED makes
+// the injected failures reproducible: whether a request fails is derived from the seed, the request
+// and how many times the same request was sent before, so the same requests fail in every run
+// whichever order concurrent workers send them in.
+package chaos
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pulumi/pulumi-cloud-import/pkg/config"
+)
+
+// Rates are the probabilities, between 0 and 1, of a request being failed with the given kind of error.
+type Rates struct {
+	Throttle float64
+	Error    float64
+	Timeout  float64
+}
+
+func (r Rates) enabled() bool {
+	return r.Throttle > 0 || r.Error > 0 || r.Timeout > 0
+}
+
+// ParseRates parses a comma separated list of kind=probability pairs.
+func ParseRates(s string) (Rates, error) {
+	var r Rates
+	for _, part := range strings.Split(s, ",") {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 {
+			return r, fmt.Errorf("invalid chaos setting %q, expected kind=probability", part)
+		}
+		p, err := strconv.ParseFloat(strings.TrimSpace(kv[1]), 64)
+		if err != nil || p < 0 || p > 1 {
+			return r, fmt.Errorf("invalid probability %q for %s, expected a number between 0 and 1", kv[1], kv[0])
+		}
+		switch strings.TrimSpace(kv[0]) {
+		case "throttle":
+			r.Throttle = p
+		case "error":
+			r.Error = p
+		case "timeout":
+			r.Timeout = p
+		default:
+			return r, fmt.Errorf("unknown chaos kind %q, expected throttle, error or timeout", kv[0])
+		}
+	}
+	return r, nil
+}
+
+var (
+	envOnce  sync.Once
+	envRates Rates
+	envSeed  int64
+)
+
+func fromEnv() (Rates, int64) {
+	envOnce.Do(func() {
+		rates, err := ParseRates(config.Chaos.Value())
+		if err != nil {
+			panic(fmt.Sprintf("PULUMI_CLOUD_IMPORT_CHAOS: %v", err))
+		}
+		envRates = rates
+		envSeed = time.Now().UnixNano()
+		if s := config.ChaosSeed.Value(); s != "" {
+			seed, err := strconv.ParseInt(s, 10, 64)
+			if err != nil {
+				panic(fmt.Sprintf("PULUMI_CLOUD_IMPORT_CHAOS_SEED must be an integer: %v", err))
+			}
+			envSeed = seed
+		}
+		if rates.enabled() {
+			fmt.Printf("chaos mode enabled: throttle=%v error=%v timeout=%v seed=%d\n", rates.Throttle, rates.Error, rates.Timeout, envSeed)
+		}
+	})
+	return envRates, envSeed
+}
+
+// Enabled reports whether failure injection is configured through the environment.
+func Enabled() bool {
+	rates, _ := fromEnv()
+	return rates.enabled()
+}
+
+// Wrap returns a RoundTripper injecting failures at the rates configured in the environment, or rt
+// itself when chaos mode is off. A nil rt wraps http.DefaultTransport.
+func Wrap(rt http.RoundTripper) http.RoundTripper {
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+	rates, seed := fromEnv()
+	if !rates.enabled() {
+		return rt
+	}
+	return NewTransport(rt, rates, seed)
+}
+
+// Transport fails requests at the given rates instead of passing them on to the wrapped RoundTripper.
+type Transport struct {
+	next  http.RoundTripper
+	rates Rates
+	seed  int64
+
+	mu sync.Mutex
+	// sent counts the times each request was sent, so that retries of a request roll again
+	sent map[uint64]uint64
+}
+
+func NewTransport(next http.RoundTripper, rates Rates, seed int64) *Transport {
+	return &Transport{
+		next:  next,
+		rates: rates,
+		seed:  seed,
+		sent:  map[uint64]uint64{},
+	}
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	roll, err := t.roll(req)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case roll < t.rates.Throttle:
+		// 429 is treated as throttling by every SDK we use, the body makes AWS report a ThrottlingException
+		return fakeResponse(req, http.StatusTooManyRequests, `{"__type":"ThrottlingException","message":"Rate exceeded (injected by chaos mode)"}`), nil
+	case roll < t.rates.Throttle+t.rates.Error:
+		return fakeResponse(req, http.StatusInternalServerError, `{"__type":"InternalFailure","message":"Internal server error (injected by chaos mode)"}`), nil
+	case roll < t.rates.Throttle+t.rates.Error+t.rates.Timeout:
+		return nil, timeoutError{url: req.URL.String()}
+	}
+	return t.next.RoundTrip(req)
+}
+
+// roll returns a number between 0 and 1 derived from the seed, the method, URL, X-Amz-Target and
+// body of req, and the times the same request was sent before. AWS sends every call of a service to
+// the same URL, the operation being named by X-Amz-Target and its input in the body.
+func (t *Transport) roll(req *http.Request) (float64, error) {
+	h := fnv.New64a()
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], uint64(t.seed))
+	h.Write(buf[:])
+	fmt.Fprintf(h, "%s %s %s\n", req.Method, req.URL.String(), req.Header.Get("X-Amz-Target"))
+	if req.Body != nil && req.Body != http.NoBody {
+		body, err := io.ReadAll(req.Body)
+		if err != nil {
+			return 0, err
+		}
+		req.Body.Close()
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		h.Write(body)
+	}
+	key := h.Sum64()
+
+	t.mu.Lock()
+	n := t.sent[key]
+	t.sent[key]++
+	t.mu.Unlock()
+
+	binary.LittleEndian.PutUint64(buf[:], n)
+	h.Write(buf[:])
+	return float64(h.Sum64()>>11) / (1 << 53), nil
+}
+
+func fakeResponse(req *http.Request, status int, body string) *http.Response {
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": []string{"application/json"}},
+		Body:          io.NopCloser(bytes.NewBufferString(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
+
+// timeoutError looks like a network timeout to callers checking net.Error.
+type timeoutError struct {
+	url string
+}
+
+func (e timeoutError) Error() string {
+	return fmt.Sprintf("request to %s timed out (injected by chaos mode)", e.url)
+}
+
+func (e timeoutError) Timeout() bool   { return true }
+func (e timeoutError) Temporary() bool { return true }
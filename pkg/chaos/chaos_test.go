@@ -0,0 +1,65 @@
+package chaos
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// request is a Cloud Control call as the AWS SDK sends it: every operation is posted to the same
+// URL, with a body that can't be read again.
+func request(t *testing.T, target, body string) *http.Request {
+	req, err := http.NewRequest(http.MethodPost, "https://cloudcontrolapi.us-west-2.amazonaws.com/", io.NopCloser(strings.NewReader(body)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("X-Amz-Target", target)
+	return req
+}
+
+func TestRoll(t *testing.T) {
+	calls := []struct{ target, body string }{
+		{"CloudApiService.ListResources", `{"TypeName":"AWS::S3::Bucket"}`},
+		{"CloudApiService.ListResources", `{"TypeName":"AWS::EC2::VPC"}`},
+		{"CloudApiService.GetResource", `{"TypeName":"AWS::S3::Bucket"}`},
+	}
+	rolls := func(order []int) map[int]float64 {
+		tr := NewTransport(nil, Rates{}, 42)
+		rolls := map[int]float64{}
+		for _, i := range order {
+			req := request(t, calls[i].target, calls[i].body)
+			roll, err := tr.roll(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			rolls[i] = roll
+			// the body is still there for the next transport
+			if b, _ := io.ReadAll(req.Body); string(b) != calls[i].body {
+				t.Errorf("body of call %d is %q after the roll, want %q", i, b, calls[i].body)
+			}
+		}
+		return rolls
+	}
+
+	forward, backward := rolls([]int{0, 1, 2}), rolls([]int{2, 1, 0})
+	for i := range calls {
+		if forward[i] != backward[i] {
+			t.Errorf("call %d rolled %v and %v depending on the order of the calls", i, forward[i], backward[i])
+		}
+	}
+	if forward[0] == forward[1] {
+		t.Errorf("calls with different bodies rolled the same %v", forward[0])
+	}
+	if forward[0] == forward[2] {
+		t.Errorf("calls with different targets rolled the same %v", forward[0])
+	}
+
+	// a retry rolls again
+	tr := NewTransport(nil, Rates{}, 42)
+	first, _ := tr.roll(request(t, calls[0].target, calls[0].body))
+	retry, _ := tr.roll(request(t, calls[0].target, calls[0].body))
+	if first == retry {
+		t.Errorf("a retry rolled the same %v as the first attempt", first)
+	}
+}
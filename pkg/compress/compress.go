@@ -0,0 +1,84 @@
+// Package compress writes import files gzipped with --compress, as the import file of a large
+// account can weigh hundreds of megabytes while compressing to a tenth of it. import.json is then
+// written to import.json.gz, and shards to import-1.json.gz and so on:
+//
+//	go run . --import --compress
+//
+// The programs reading import files read compressed files as they are, and find import.json.gz
+// when passed import.json. `pulumi import` doesn't read compressed files: import them with
+// pulumi-cloud-import-bulk, or decompress them with gunzip first.
+package compress
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+
+	"github.com/pulumi/pulumi-cloud-import/pkg/config"
+)
+
+// Ext is the extension of compressed files.
+const Ext = ".gz"
+
+// Path returns the path a file written to path ends up at, path itself unless --compress is set.
+func Path(path string) string {
+	if config.Compress.Bool() {
+		return path + Ext
+	}
+	return path
+}
+
+// WriteFile writes b to path, gzipped to Path(path) with --compress.
+func WriteFile(path string, b []byte) error {
+	if !config.Compress.Bool() {
+		return os.WriteFile(path, b, 0644)
+	}
+	gz, err := Compress(b)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(Path(path), gz, 0644)
+}
+
+// Compress returns b gzipped.
+func Compress(b []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(b); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// ReadFile reads the file at path, decompressing it if it's gzipped. When path doesn't exist,
+// path.gz is read instead.
+func ReadFile(path string) ([]byte, error) {
+	b, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		if gz, gzErr := os.ReadFile(path + Ext); gzErr == nil {
+			b, err = gz, nil
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return Decompress(b)
+}
+
+// Decompress returns b decompressed if it's gzipped, and b itself otherwise.
+func Decompress(b []byte) ([]byte, error) {
+	// gzip streams start with the magic bytes 1f 8b
+	if len(b) < 2 || b[0] != 0x1f || b[1] != 0x8b {
+		return b, nil
+	}
+	r, err := gzip.NewReader(bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
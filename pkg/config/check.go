@@ -0,0 +1,130 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// HandleCommand runs `config check` or `config docs` and exits when the program is started with
+// either:
+//
+//	go run . config check
+//	go run . config docs
+func HandleCommand() {
+	if len(os.Args) < 3 || os.Args[1] != "config" {
+		return
+	}
+	switch os.Args[2] {
+	case "check":
+		if !Check(os.Stdout, os.Environ()) {
+			os.Exit(1)
+		}
+	case "docs":
+		Docs(os.Stdout)
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command config %s, expected check or docs\n", os.Args[2])
+		os.Exit(2)
+	}
+	os.Exit(0)
+}
+
+// Check prints every setting with its effective value and where the value comes from, followed by
+// the variables of environ starting with EnvPrefix that match no setting, which are otherwise
+// ignored without a word. It reports false when there are such variables.
+func Check(w io.Writer, environ []string) bool {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "FLAG\tENVIRONMENT VARIABLE\tVALUE\tSOURCE\tUSAGE")
+	known := map[string]bool{}
+	for _, s := range All {
+		known[s.Env()] = true
+		value, source, ok := s.Lookup()
+		if !ok {
+			value, source = s.Default, "default"
+		}
+		if value == "" {
+			value, source = "-", "-"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", s.Flag(), s.Env(), value, source, s.Usage)
+	}
+	tw.Flush()
+
+	unknown := []string{}
+	for _, kv := range environ {
+		name := strings.SplitN(kv, "=", 2)[0]
+		if strings.HasPrefix(name, EnvPrefix) && !known[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	sort.Strings(unknown)
+	for _, name := range unknown {
+		if suggestion := closest(name, known); suggestion != "" {
+			fmt.Fprintf(w, "unknown environment variable %s, did you mean %s?\n", name, suggestion)
+		} else {
+			fmt.Fprintf(w, "unknown environment variable %s\n", name)
+		}
+	}
+	return len(unknown) == 0
+}
+
+// Docs writes the Markdown table of every setting the README documents settings with.
+func Docs(w io.Writer) {
+	fmt.Fprintln(w, "| Flag | Environment variables | Default | Description |")
+	fmt.Fprintln(w, "| --- | --- | --- | --- |")
+	for _, s := range All {
+		env := "`" + s.Env() + "`"
+		for _, a := range s.Aliases {
+			env += ", `" + a + "`"
+		}
+		def := ""
+		if s.Default != "" {
+			def = "`" + s.Default + "`"
+		}
+		fmt.Fprintf(w, "| `%s` | %s | %s | %s |\n", s.Flag(), env, def, s.Usage)
+	}
+}
+
+// closest returns the known variable name is most likely a typo of, or "" when none is close.
+func closest(name string, known map[string]bool) string {
+	best, bestDistance := "", 4
+	for k := range known {
+		if d := distance(name, k); d < bestDistance || (d == bestDistance && k < best) {
+			best, bestDistance = k, d
+		}
+	}
+	return best
+}
+
+// distance returns the Levenshtein distance between a and b.
+func distance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
+
+func min(values ...int) int {
+	m := values[0]
+	for _, v := range values[1:] {
+		if v < m {
+			m = v
+		}
+	}
+	return m
+}
@@ -0,0 +1,133 @@
+// Package config defines the settings shared by the programs in one place, so that every program
+// reads a setting from the same flag and environment variable. A setting named "workers" is passed
+// as --workers or set in PULUMI_CLOUD_IMPORT_WORKERS, the flag taking precedence:
+//
+//	go run . --import --workers 5
+//	PULUMI_CLOUD_IMPORT_WORKERS=5 go run . --import
+//
+// Settings may also honor the environment variables of the cloud provider's own tools, like
+// ARM_LOCATION for the Azure region.
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pulumi/pulumi-cloud-import/pkg/flags"
+)
+
+// EnvPrefix is the prefix of the environment variables of all settings.
+const EnvPrefix = "PULUMI_CLOUD_IMPORT_"
+
+// Setting is a knob that can be passed as a flag or set in an environment variable.
+type Setting struct {
+	// Name is the flag without its leading dashes, e.g. max-buffer.
+	Name string
+	// Aliases are other environment variables honored, in order, when neither the flag nor the
+	// environment variable is set.
+	Aliases []string
+	// Default is the value used when the setting isn't given.
+	Default string
+	// Usage describes the setting.
+	Usage string
+	// Switch marks settings that are switched on by passing the flag alone.
+	Switch bool
+	// Filter marks settings narrowing down the resources scanned, which are recorded in the
+	// metadata of the import file.
+	Filter bool
+}
+
+// Flag returns the command line flag of the setting, e.g. --max-buffer.
+func (s Setting) Flag() string {
+	return "--" + s.Name
+}
+
+// Env returns the environment variable of the setting, e.g. PULUMI_CLOUD_IMPORT_MAX_BUFFER.
+func (s Setting) Env() string {
+	return EnvPrefix + strings.ToUpper(strings.ReplaceAll(s.Name, "-", "_"))
+}
+
+// Lookup returns the value the setting was given and where it was given, or false when it wasn't.
+func (s Setting) Lookup() (value string, source string, ok bool) {
+	if s.Switch {
+		if flags.Has(s.Flag()) {
+			return "true", s.Flag(), true
+		}
+	} else if v := flags.Value(s.Flag()); v != "" {
+		return v, s.Flag(), true
+	}
+	for _, env := range append([]string{s.Env()}, s.Aliases...) {
+		if v := os.Getenv(env); v != "" {
+			return v, env, true
+		}
+	}
+	return "", "", false
+}
+
+// Value returns the value of the setting, or its default.
+func (s Setting) Value() string {
+	if v, _, ok := s.Lookup(); ok {
+		return v
+	}
+	return s.Default
+}
+
+// Int returns the value of the setting as a positive integer, 0 when it isn't given and has no
+// default. It panics on other values.
+func (s Setting) Int() int {
+	v := s.Value()
+	if v == "" {
+		return 0
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 1 {
+		panic(fmt.Sprintf("%s must be a positive integer, got %q", s.describe(), v))
+	}
+	return n
+}
+
+// Bool reports whether the setting is switched on. Environment variables switch settings on with
+// any value but "false" and "0".
+func (s Setting) Bool() bool {
+	v := s.Value()
+	return v != "" && v != "false" && v != "0"
+}
+
+// Duration returns the value of the setting as a positive duration such as 10m, 0 when it isn't
+// given and has no default. It panics on other values.
+func (s Setting) Duration() time.Duration {
+	v := s.Value()
+	if v == "" {
+		return 0
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		panic(fmt.Sprintf("%s must be a positive duration such as 10m, got %q", s.describe(), v))
+	}
+	return d
+}
+
+// List returns the comma separated values of the setting, or nil when it isn't given.
+func (s Setting) List() []string {
+	v := s.Value()
+	if v == "" {
+		return nil
+	}
+	values := []string{}
+	for _, e := range strings.Split(v, ",") {
+		if e = strings.TrimSpace(e); e != "" {
+			values = append(values, e)
+		}
+	}
+	return values
+}
+
+func (s Setting) describe() string {
+	if _, source, ok := s.Lookup(); ok {
+		return source
+	}
+	return s.Flag()
+}
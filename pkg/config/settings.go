@@ -0,0 +1,389 @@
+package config
+
+// Settings shared by all programs.
+var (
+	Workers = Setting{
+		Name:  "workers",
+		Usage: "number of concurrent workers listing resources, 3 for AWS and Kubernetes and 10 for others by default, or auto to size them from the CPUs and throttling",
+	}
+	Debug = Setting{
+		Name:   "debug",
+		Switch: true,
+		Usage:  "log debugging output",
+	}
+	DebugTypes = Setting{
+		Name:  "debug-types",
+		Usage: "log debugging output only for these types, comma separated",
+	}
+	Output = Setting{
+		Name:    "output",
+		Default: "import.json",
+		Usage:   "path the import file is written to",
+	}
+	FromInventory = Setting{
+		Name:  "from-inventory",
+		Usage: "inventory export the import file is built from without calling the cloud: an AWS Config snapshot, an Azure Resource Graph export or a kubectl get -o json dump",
+	}
+	Stack = Setting{
+		Name:  "stack",
+		Usage: "stack whose resources are left out of the import file",
+	}
+	IgnoreChanges = Setting{
+		Name:  "ignore-changes",
+		Usage: "JSON file of the properties to ignore changes of per type, set on the resources read into the stack",
+	}
+	Aliases = Setting{
+		Name:  "aliases",
+		Usage: "JSON file of the names of resources, updated by every scan, whose previous names are aliases of the resources read into the stack",
+	}
+	NameLocale = Setting{
+		Name:  "name-locale",
+		Usage: "language non-ASCII names are transliterated for, de, uk, bg or sr, which changes the spelling of some letters",
+	}
+	NamePrefix = Setting{
+		Name:  "name-prefix",
+		Usage: "prefix of the names of every resource, e.g. imported- to tell imported resources apart from the ones written by hand",
+	}
+	NameSuffix = Setting{
+		Name:  "name-suffix",
+		Usage: "suffix of the names of every resource",
+	}
+	NameTemplate = Setting{
+		Name:  "name-template",
+		Usage: "AWS: template of resource names with .Service, .Type, .ID, .ShortID and .Tag, e.g. {{.Type}}-{{.Tag.Name}}-{{.ShortID}}",
+	}
+	Shards = Setting{
+		Name:  "shards",
+		Usage: "number of import files of roughly equal estimated import time the resources are split into",
+	}
+	MaxStackResources = Setting{
+		Name:  "max-stack-resources",
+		Usage: "number of resources read into the stack in read mode, the others being read into additional stacks named after it, e.g. dev-2",
+	}
+	StackChunk = Setting{
+		Name:  "stack-chunk",
+		Usage: "which of the stacks of a read mode run split with --max-stack-resources to read, set on the runs of the additional stacks",
+	}
+	Compress = Setting{
+		Name:   "compress",
+		Switch: true,
+		Usage:  "write the import file gzipped, to import.json.gz",
+	}
+	Version = Setting{
+		Name:   "version",
+		Switch: true,
+		Usage:  "print the version and exit",
+	}
+)
+
+// Settings scoping a scan.
+var (
+	CreatedAfter = Setting{
+		Name:   "created-after",
+		Filter: true,
+		Usage:  "only import resources created after this RFC 3339 timestamp or date",
+	}
+	CreatedBefore = Setting{
+		Name:   "created-before",
+		Filter: true,
+		Usage:  "only import resources created before this RFC 3339 timestamp or date",
+	}
+	OlderThan = Setting{
+		Name:   "older-than",
+		Filter: true,
+		Usage:  "only import resources created longer ago than this duration, e.g. 720h",
+	}
+	NewerThan = Setting{
+		Name:   "newer-than",
+		Filter: true,
+		Usage:  "only import resources created within this duration, e.g. 24h",
+	}
+	Ownership = Setting{
+		Name:   "ownership",
+		Switch: true,
+		Usage:  "classify resources as likely managed or unmanaged and write ownership.json",
+	}
+	CostTags = Setting{
+		Name:   "cost-tags",
+		Switch: true,
+		Usage:  "read the cost center, owner and environment tags of resources and write cost-tags.json",
+	}
+	InsightsOrg = Setting{
+		Name:  "insights-org",
+		Usage: "Pulumi Cloud organization to compare discovered resources with, writing untracked.json",
+	}
+	CloudURL = Setting{
+		Name:    "cloud-url",
+		Default: "https://api.pulumi.com",
+		Usage:   "API of the Pulumi Cloud searched with --insights-org",
+	}
+	Services = Setting{
+		Name:   "services",
+		Filter: true,
+		Usage:  "AWS: comma separated services to scan, e.g. s3,ec2",
+	}
+	Types = Setting{
+		Name:   "types",
+		Filter: true,
+		Usage:  "AWS: comma separated type tokens to scan, with glob patterns like aws-native:s3:*",
+	}
+	ExcludeTypes = Setting{
+		Name:   "exclude-types",
+		Filter: true,
+		Usage:  "AWS: comma separated type tokens left out of the scan, with glob patterns like aws-native:s3:*",
+	}
+	IncludeTag = Setting{
+		Name:   "include-tag",
+		Filter: true,
+		Usage:  "AWS and Azure: comma separated key=value tags, or keys alone, a resource must have to be scanned",
+	}
+	ExcludeTag = Setting{
+		Name:   "exclude-tag",
+		Filter: true,
+		Usage:  "AWS and Azure: comma separated key=value tags, or keys alone, of the resources left out of the scan",
+	}
+	VPCIDs = Setting{
+		Name:   "vpc-id",
+		Filter: true,
+		Usage:  "AWS: comma separated VPC IDs, only the resources associated with one of them are scanned",
+	}
+	ExcludeManaged = Setting{
+		Name:   "exclude-managed",
+		Switch: true,
+		Filter: true,
+		Usage:  "AWS: leave out the resources managed by CloudFormation stacks, including the ones deployed with the CDK",
+	}
+	SkipDefaults = Setting{
+		Name:   "skip-defaults",
+		Switch: true,
+		Filter: true,
+		Usage:  "AWS: leave out the default VPC, subnets and DHCP options, and the default security groups, route tables and network ACLs of VPCs",
+	}
+	ResourceGroups = Setting{
+		Name:   "resource-groups",
+		Filter: true,
+		Usage:  "Azure: comma separated resource groups to scan",
+	}
+	ExcludeResourceGroups = Setting{
+		Name:   "exclude-resource-groups",
+		Filter: true,
+		Usage:  "Azure: comma separated resource groups to skip",
+	}
+	Kinds = Setting{
+		Name:   "kinds",
+		Filter: true,
+		Usage:  "Kubernetes: comma separated kinds to scan",
+	}
+	ExcludeKinds = Setting{
+		Name:   "exclude-kinds",
+		Filter: true,
+		Usage:  "Kubernetes: comma separated kinds to skip",
+	}
+	APIGroups = Setting{
+		Name:   "api-groups",
+		Filter: true,
+		Usage:  "Kubernetes: comma separated API groups to scan, core for the core group",
+	}
+	ExcludeAPIGroups = Setting{
+		Name:   "exclude-api-groups",
+		Filter: true,
+		Usage:  "Kubernetes: comma separated API groups to skip, e.g. events.k8s.io,metrics.k8s.io",
+	}
+	IncludeExpensive = Setting{
+		Name:   "include-expensive",
+		Filter: true,
+		Switch: true,
+		Usage:  "list the types that take a request per parent or object, like AWS log streams or Kubernetes events",
+	}
+	IgnoreFile = Setting{
+		Name:   "ignore-file",
+		Filter: true,
+		Usage:  "file of resource ID patterns to skip, one per line, with * matching any characters",
+	}
+)
+
+// Settings selecting what a provider program connects to.
+var (
+	AWSRegion = Setting{
+		Name:    "region",
+		Aliases: []string{"AWS_REGION", "AWS_DEFAULT_REGION"},
+		Usage:   "AWS: region to scan",
+	}
+	AWSBackend = Setting{
+		Name:    "backend",
+		Default: "cloudcontrol",
+		Usage:   "AWS: how resources are discovered, cloudcontrol to list every type with Cloud Control, or resource-explorer to find the resources of common types with Resource Explorer",
+	}
+	AWSResourceModels = Setting{
+		Name:  "aws-resource-models",
+		Usage: "AWS: JSON file of resource models to list types with, by CloudFormation type",
+	}
+	AWSSnapshotProperties = Setting{
+		Name:   "snapshot-properties",
+		Switch: true,
+		Usage:  "AWS: write the properties of every resource, read with the cloud control API, to properties.json",
+	}
+	AWSRequiredProperties = Setting{
+		Name:    "required-properties",
+		Default: "true",
+		Usage:   "AWS: list the required properties of the types with required create-only properties in the import file, read with GetResource, false to skip the reads",
+	}
+	AWSTypeCacheTTL = Setting{
+		Name:    "type-cache-ttl",
+		Default: "168h",
+		Usage:   "AWS: how long the types found listable or not in an account and region are remembered between runs",
+	}
+	AWSRefreshTypes = Setting{
+		Name:   "refresh-types",
+		Switch: true,
+		Usage:  "AWS: check every type again instead of using the types remembered from earlier runs",
+	}
+	AWSUnsupportedTypes = Setting{
+		Name:  "unsupported-types",
+		Usage: "AWS: JSON file of the types failing to list or import along with why, skipped in addition to the built-in ones",
+	}
+	AWSFetchUnsupportedTypes = Setting{
+		Name:   "fetch-unsupported-types",
+		Switch: true,
+		Usage:  "AWS: also skip the types failing to list or import published since the release",
+	}
+	AWSSkippedTypes = Setting{
+		Name:    "skipped-types",
+		Default: "skipped-types.json",
+		Usage:   "AWS: file the types whose handlers failed in the last scans are remembered in and skipped from, empty to try every type",
+	}
+	AWSCheckpoint = Setting{
+		Name:    "checkpoint",
+		Default: "checkpoint.json",
+		Usage:   "AWS: file the progress of a scan generating an import file is recorded in, for --resume to pick up from, empty not to record it",
+	}
+	AWSResume = Setting{
+		Name:   "resume",
+		Switch: true,
+		Usage:  "AWS: pick up the scan recorded in --checkpoint where it stopped, rather than start over",
+	}
+	AWSRateLimit = Setting{
+		Name:  "aws-rate-limit",
+		Usage: "AWS: Cloud Control requests per second per service and region, shared by the workers, e.g. 5, or 5,ec2=2 to give some services another rate",
+	}
+	AWSScanRole = Setting{
+		Name:  "aws-scan-role",
+		Usage: "AWS: ARN of a role assumed to discover resources, e.g. a read-only one, rather than scanning with the credentials of the environment",
+	}
+	AWSOrganization = Setting{
+		Name:   "aws-organization",
+		Switch: true,
+		Usage:  "AWS: scan every active account of the organization, assuming --aws-assume-role in the member accounts",
+	}
+	AWSAssumeRole = Setting{
+		Name:    "aws-assume-role",
+		Default: "OrganizationAccountAccessRole",
+		Usage:   "AWS: name of the role assumed in the member accounts of the organization",
+	}
+	AWSCredentialBroker = Setting{
+		Name:  "aws-credential-broker",
+		Usage: "AWS: YAML file configuring the broker the credentials of the member accounts of the organization are obtained from, instead of assuming --aws-assume-role",
+	}
+	AWSAccounts = Setting{
+		Name:   "aws-accounts",
+		Filter: true,
+		Usage:  "AWS: comma separated account IDs of the organization to scan",
+	}
+	AWSSplitAccounts = Setting{
+		Name:   "aws-split-accounts",
+		Switch: true,
+		Usage:  "AWS: write an import file per account of the organization instead of a single one",
+	}
+	AzureLocation = Setting{
+		Name:    "location",
+		Aliases: []string{"ARM_LOCATION"},
+		Default: "westus2",
+		Usage:   "Azure: location to scan",
+	}
+	AzureSubscription = Setting{
+		Name:    "subscription",
+		Aliases: []string{"ARM_SUBSCRIPTION_ID", "AZURE_SUBSCRIPTION_ID"},
+		Usage:   "Azure: IDs of the subscriptions to scan, comma separated",
+	}
+	KubeContext = Setting{
+		Name:  "kube-context",
+		Usage: "Kubernetes: kubeconfig context to scan, instead of the current one",
+	}
+	RenderYAML = Setting{
+		Name:  "render-yaml",
+		Usage: "Kubernetes: directory the discovered objects are written to as YAML manifests, by namespace and kind",
+	}
+)
+
+// Settings tuning how programs run.
+var (
+	MaxBuffer = Setting{
+		Name:    "max-buffer",
+		Default: "100000",
+		Usage:   "number of discovered resources queued before workers wait",
+	}
+	MaxMemoryMB = Setting{
+		Name:  "max-memory-mb",
+		Usage: "heap size in MB above which workers pause",
+	}
+	Events = Setting{
+		Name:  "events",
+		Usage: "file or UNIX socket progress events are written to",
+	}
+	PauseFile = Setting{
+		Name:  "pause-file",
+		Usage: "file pausing the scan while it exists, in addition to SIGUSR1",
+	}
+	CancelFile = Setting{
+		Name:  "cancel-file",
+		Usage: "file of the scopes, one per line, whose listing is cancelled and skipped while the scan runs",
+	}
+	FetchTimeout = Setting{
+		Name:    "fetch-timeout",
+		Default: "5m",
+		Usage:   "timeout of schema and metadata downloads",
+	}
+)
+
+// Settings for debugging.
+var (
+	SupportBundle = Setting{
+		Name:   "support-bundle",
+		Switch: true,
+		Usage:  "write support-bundle.tar.gz with the manifest, summary, error reports, output and environment of the run, redacted as with --redact",
+	}
+	Redact = Setting{
+		Name:   "redact",
+		Switch: true,
+		Usage:  "redact emails and other sensitive values from logs and events",
+	}
+	RedactPatterns = Setting{
+		Name:  "redact-patterns",
+		Usage: "file of additional regular expressions to redact, one per line",
+	}
+	Chaos = Setting{
+		Name:  "chaos",
+		Usage: "rates of injected failures, e.g. throttle=0.1,error=0.05,timeout=0.01",
+	}
+	ChaosSeed = Setting{
+		Name:  "chaos-seed",
+		Usage: "seed of the injected failures, to reproduce a run",
+	}
+	Record = Setting{
+		Name:  "record",
+		Usage: "directory cloud API responses are recorded to",
+	}
+	Replay = Setting{
+		Name:  "replay",
+		Usage: "directory cloud API responses are replayed from",
+	}
+)
+
+// All lists every setting.
+var All = []Setting{
+	Workers, Debug, DebugTypes, Output, FromInventory, Stack, IgnoreChanges, Aliases, NameLocale, NamePrefix, NameSuffix, NameTemplate, Shards, MaxStackResources, StackChunk, Compress, Version,
+	CreatedAfter, CreatedBefore, OlderThan, NewerThan, Ownership, CostTags, InsightsOrg, CloudURL, Services, Types, ExcludeTypes, IncludeTag, ExcludeTag, VPCIDs, ExcludeManaged, SkipDefaults, ResourceGroups, ExcludeResourceGroups, Kinds, ExcludeKinds, APIGroups, ExcludeAPIGroups, IncludeExpensive, IgnoreFile,
+	AWSRegion, AWSBackend, AWSResourceModels, AWSSnapshotProperties, AWSRequiredProperties, AWSTypeCacheTTL, AWSRefreshTypes, AWSUnsupportedTypes, AWSFetchUnsupportedTypes, AWSSkippedTypes, AWSCheckpoint, AWSResume, AWSRateLimit, AWSScanRole, AWSOrganization, AWSAssumeRole, AWSCredentialBroker, AWSAccounts, AWSSplitAccounts, AzureLocation, AzureSubscription, KubeContext, RenderYAML,
+	MaxBuffer, MaxMemoryMB, Events, PauseFile, CancelFile, FetchTimeout,
+	SupportBundle, Redact, RedactPatterns, Chaos, ChaosSeed, Record, Replay,
+}
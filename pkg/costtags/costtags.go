@@ -0,0 +1,179 @@
+// Package costtags reads the cost allocation tags of discovered resources, the cost center, owner
+// and environment most organizations tag resources with under one spelling or another, like
+// CostCenter, cost-center or cost_centre.
+//
+// Pass --cost-tags to write the cost tags of every resource to cost-tags.json and print the number
+// of resources per cost center, owner and environment, so that the scan can be used for cost
+// allocation right away.
+package costtags
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pulumi/pulumi-cloud-import/pkg/config"
+	"github.com/pulumi/pulumi-cloud-import/pkg/redact"
+)
+
+// Path is where the cost tags are written to.
+const Path = "cost-tags.json"
+
+// Untagged is the group of the resources missing a cost tag.
+const Untagged = "(untagged)"
+
+// Tags are the cost allocation tags of a resource.
+type Tags struct {
+	CostCenter  string `json:"costCenter,omitempty"`
+	Owner       string `json:"owner,omitempty"`
+	Environment string `json:"environment,omitempty"`
+}
+
+// Entry is a discovered resource along with its cost tags.
+type Entry struct {
+	Type string `json:"type"`
+	Name string `json:"name"`
+	ID   string `json:"id"`
+	Tags
+}
+
+// File is the content of cost-tags.json: every resource and the number of resources per value of
+// each cost tag.
+type File struct {
+	Resources    []Entry        `json:"resources"`
+	CostCenters  map[string]int `json:"costCenters"`
+	Owners       map[string]int `json:"owners"`
+	Environments map[string]int `json:"environments"`
+}
+
+// keys are the tag keys each cost tag is read from, lower cased without separators, in order of
+// preference.
+var keys = struct{ costCenter, owner, environment []string }{
+	costCenter:  []string{"costcenter", "costcentre", "costcode", "billingcode", "chargecode"},
+	owner:       []string{"owner", "ownedby", "team"},
+	environment: []string{"environment", "env", "stage"},
+}
+
+// Extract returns the cost tags of a resource with the given tags, labels or annotations. Keys are
+// matched whatever their case and separators, and without the prefix of Kubernetes labels like
+// example.com/cost-center.
+func Extract(tags map[string]string) Tags {
+	normalized := map[string]string{}
+	for k, v := range tags {
+		if v == "" {
+			continue
+		}
+		key := k[strings.LastIndex(k, "/")+1:]
+		key = strings.Map(func(r rune) rune {
+			if r == '-' || r == '_' || r == ' ' || r == '.' {
+				return -1
+			}
+			return r
+		}, strings.ToLower(key))
+		// keys spelled differently may normalize the same, keeping the smallest value makes the
+		// result independent of map order
+		if current, ok := normalized[key]; !ok || v < current {
+			normalized[key] = v
+		}
+	}
+	first := func(candidates []string) string {
+		for _, c := range candidates {
+			if v, ok := normalized[c]; ok {
+				return v
+			}
+		}
+		return ""
+	}
+	return Tags{
+		CostCenter:  first(keys.costCenter),
+		Owner:       first(keys.owner),
+		Environment: first(keys.environment),
+	}
+}
+
+var (
+	mu      sync.Mutex
+	entries []Entry
+)
+
+// Enabled reports whether --cost-tags was passed.
+func Enabled() bool {
+	return config.CostTags.Bool()
+}
+
+// Add records the cost tags of a discovered resource with the given tags when --cost-tags is passed.
+func Add(typ, name, id string, tags map[string]string) {
+	if !Enabled() {
+		return
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	entries = append(entries, Entry{Type: typ, Name: name, ID: id, Tags: Extract(tags)})
+}
+
+// Report writes the resources recorded with Add to Path and prints the number of resources per
+// cost center, owner and environment.
+func Report() error {
+	if !Enabled() {
+		return nil
+	}
+	mu.Lock()
+	defer mu.Unlock()
+
+	r := File{
+		Resources:    entries,
+		CostCenters:  map[string]int{},
+		Owners:       map[string]int{},
+		Environments: map[string]int{},
+	}
+	if r.Resources == nil {
+		r.Resources = []Entry{}
+	}
+	for _, e := range entries {
+		r.CostCenters[orUntagged(e.CostCenter)]++
+		r.Owners[orUntagged(e.Owner)]++
+		r.Environments[orUntagged(e.Environment)]++
+	}
+
+	b, err := json.MarshalIndent(r, "", "    ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(Path, b, 0644); err != nil {
+		return err
+	}
+
+	printGroups("cost center", r.CostCenters)
+	printGroups("owner", r.Owners)
+	printGroups("environment", r.Environments)
+	fmt.Printf("Cost tags of every resource written to %s\n", Path)
+	return nil
+}
+
+func orUntagged(v string) string {
+	if v == "" {
+		return Untagged
+	}
+	return v
+}
+
+// printGroups prints the number of resources per value of a cost tag, largest first.
+func printGroups(tag string, counts map[string]int) {
+	values := make([]string, 0, len(counts))
+	for v := range counts {
+		values = append(values, v)
+	}
+	sort.Slice(values, func(i, j int) bool {
+		if counts[values[i]] != counts[values[j]] {
+			return counts[values[i]] > counts[values[j]]
+		}
+		return values[i] < values[j]
+	})
+	fmt.Printf("Resources per %s:\n", tag)
+	for _, v := range values {
+		redact.Printf("  %d %s\n", counts[v], v)
+	}
+}
@@ -0,0 +1,153 @@
+// Package coverage reports at the end of a scan which of the types known to the provider schema
+// were scanned, which of them returned no resources, which were skipped and why, and which failed
+// to list, as a percentage of the schema. It tells how much of a cloud the scan could possibly see.
+// The report is printed and written to coverage.json.
+package coverage
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/pulumi/pulumi-cloud-import/pkg/events"
+	"github.com/pulumi/pulumi-cloud-import/pkg/manifest"
+	"github.com/pulumi/pulumi-cloud-import/pkg/redact"
+)
+
+// Path is where the coverage report is written to.
+const Path = "coverage.json"
+
+// Type is a scanned type and the number of resources found of it.
+type Type struct {
+	Type      string `json:"type"`
+	Resources int    `json:"resources"`
+}
+
+// Reason is a type that wasn't scanned, and why.
+type Reason struct {
+	Type   string `json:"type"`
+	Reason string `json:"reason"`
+}
+
+// File is the content of coverage.json. NotScanned lists the known types left out by filters,
+// like --services or --kinds.
+type File struct {
+	Known         int      `json:"known"`
+	Scanned       int      `json:"scanned"`
+	Percent       float64  `json:"percent"`
+	WithResources []Type   `json:"withResources"`
+	Empty         []string `json:"empty"`
+	Skipped       []Reason `json:"skipped"`
+	Failed        []Reason `json:"failed"`
+	NotScanned    []string `json:"notScanned"`
+}
+
+var (
+	mu      sync.Mutex
+	known   = map[string]bool{}
+	scanned = map[string]bool{}
+	skipped = map[string]string{}
+	failed  = map[string]string{}
+)
+
+// Known records the types of the provider schema the scan could list.
+func Known(types ...string) {
+	mu.Lock()
+	defer mu.Unlock()
+	for _, t := range types {
+		known[t] = true
+	}
+}
+
+// Scanned records that the resources of typ were listed.
+func Scanned(types ...string) {
+	mu.Lock()
+	defer mu.Unlock()
+	for _, t := range types {
+		scanned[t] = true
+	}
+}
+
+// Skip records that the resources of typ weren't listed, and why. Skipped types are also recorded
+// in the run manifest.
+func Skip(typ, reason string) {
+	manifest.Skip(typ, reason)
+	mu.Lock()
+	defer mu.Unlock()
+	skipped[typ] = reason
+}
+
+// Failed records that listing the resources of typ failed. The first error of a type is kept.
+func Failed(typ string, err error) {
+	mu.Lock()
+	defer mu.Unlock()
+	if _, ok := failed[typ]; !ok {
+		failed[typ] = redact.String(err.Error())
+	}
+}
+
+// Report prints the coverage of the scan and writes it to Path. Types that failed to list aren't
+// counted as scanned, even when some of their resources were found.
+func Report() error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	counts := events.Counts()
+	types := make([]string, 0, len(known))
+	for t := range known {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+
+	r := File{
+		Known:         len(types),
+		WithResources: []Type{},
+		Empty:         []string{},
+		Skipped:       []Reason{},
+		Failed:        []Reason{},
+		NotScanned:    []string{},
+	}
+	for _, t := range types {
+		switch {
+		case failed[t] != "":
+			r.Failed = append(r.Failed, Reason{Type: t, Reason: failed[t]})
+		case scanned[t]:
+			r.Scanned++
+			if counts[t] > 0 {
+				r.WithResources = append(r.WithResources, Type{Type: t, Resources: counts[t]})
+			} else {
+				r.Empty = append(r.Empty, t)
+			}
+		case skipped[t] != "":
+			r.Skipped = append(r.Skipped, Reason{Type: t, Reason: skipped[t]})
+		default:
+			r.NotScanned = append(r.NotScanned, t)
+		}
+	}
+	if r.Known > 0 {
+		r.Percent = float64(r.Scanned) * 100 / float64(r.Known)
+	}
+
+	b, err := json.MarshalIndent(r, "", "    ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(Path, b, 0644); err != nil {
+		return err
+	}
+
+	fmt.Printf("Scanned %d of %d types of the schema (%.1f%%), %d of them had no resources\n", r.Scanned, r.Known, r.Percent, len(r.Empty))
+	if len(r.Skipped) > 0 {
+		fmt.Printf("  %d skipped\n", len(r.Skipped))
+	}
+	if len(r.Failed) > 0 {
+		fmt.Printf("  %d failed to list\n", len(r.Failed))
+	}
+	if len(r.NotScanned) > 0 {
+		fmt.Printf("  %d left out by filters\n", len(r.NotScanned))
+	}
+	fmt.Printf("Coverage of every type written to %s\n", Path)
+	return nil
+}
@@ -0,0 +1,88 @@
+// Package created scopes a scan to the resources created in a time window, given with
+// --created-after and --created-before as RFC 3339 timestamps or dates, or relative to now with
+// --newer-than and --older-than as durations:
+//
+//	go run . --import --created-after 2023-04-01 --created-before 2023-05-01T12:00:00Z
+//	go run . --import --older-than 720h
+//
+// Resources created recently are the ones most likely not to be managed yet. Resources whose
+// creation time isn't known, because the provider's API doesn't expose it, are kept and counted.
+package created
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/pulumi/pulumi-cloud-import/pkg/config"
+)
+
+// Window is the time window resources have to be created in.
+type Window struct {
+	After   time.Time
+	Before  time.Time
+	unknown int64
+	dropped int64
+}
+
+// FromFlags returns the window given with --created-after, --created-before, --newer-than and
+// --older-than, or nil when none is passed. A nil window keeps every resource. When both a time and
+// a duration bound the same side of the window, the narrower one applies.
+func FromFlags() *Window {
+	after, before := parse(config.CreatedAfter), parse(config.CreatedBefore)
+	now := time.Now()
+	if d := config.NewerThan.Duration(); d > 0 && now.Add(-d).After(after) {
+		after = now.Add(-d)
+	}
+	if d := config.OlderThan.Duration(); d > 0 && (before.IsZero() || now.Add(-d).Before(before)) {
+		before = now.Add(-d)
+	}
+	if after.IsZero() && before.IsZero() {
+		return nil
+	}
+	if !after.IsZero() && !before.IsZero() && !after.Before(before) {
+		panic("the window given with --created-after, --newer-than, --created-before and --older-than is empty")
+	}
+	return &Window{After: after, Before: before}
+}
+
+func parse(s config.Setting) time.Time {
+	v := s.Value()
+	if v == "" {
+		return time.Time{}
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02"} {
+		if t, err := time.Parse(layout, v); err == nil {
+			return t
+		}
+	}
+	panic(fmt.Sprintf("%s must be an RFC 3339 timestamp or a date like 2023-04-01, got %q", s.Flag(), v))
+}
+
+// Keep reports whether a resource created at t is in the window. A zero t means the creation time
+// is unknown, and the resource is kept.
+func (w *Window) Keep(t time.Time) bool {
+	if w == nil {
+		return true
+	}
+	if t.IsZero() {
+		atomic.AddInt64(&w.unknown, 1)
+		return true
+	}
+	if (!w.After.IsZero() && t.Before(w.After)) || (!w.Before.IsZero() && !t.Before(w.Before)) {
+		atomic.AddInt64(&w.dropped, 1)
+		return false
+	}
+	return true
+}
+
+// Report prints how many resources were left out, and how many were kept for lack of a creation time.
+func (w *Window) Report() {
+	if w == nil {
+		return
+	}
+	fmt.Printf("Skipped %d resources created outside of the time window\n", atomic.LoadInt64(&w.dropped))
+	if unknown := atomic.LoadInt64(&w.unknown); unknown > 0 {
+		fmt.Printf("Kept %d resources whose creation time is unknown\n", unknown)
+	}
+}
@@ -0,0 +1,80 @@
+// Package debuglog writes the debugging output of --debug. Lines are written whole, so that the
+// output of concurrent workers doesn't interleave, and tagged with the time since the program
+// started, the worker and the type being listed:
+//
+//	[12.345s worker 3 aws-native:ec2:Instance] listed 100 resources
+//
+// Pass --debug-types to log only for some types, e.g. the ones failing to list, rather than for
+// every type with --debug:
+//
+//	go run . --import --debug-types aws-native:ec2:Instance,aws-native:s3:Bucket
+package debuglog
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pulumi/pulumi-cloud-import/pkg/config"
+	"github.com/pulumi/pulumi-cloud-import/pkg/redact"
+)
+
+var (
+	mu      sync.Mutex
+	started = time.Now()
+	once    sync.Once
+	types   map[string]bool
+)
+
+// Logger logs the lines of a worker listing a type. Worker is 1-based, 0 for lines logged outside
+// of workers, and Type is empty for lines about no type in particular.
+type Logger struct {
+	Worker int
+	Type   string
+}
+
+// For returns the logger of worker listing typ.
+func For(worker int, typ string) Logger {
+	return Logger{Worker: worker, Type: typ}
+}
+
+// Enabled reports whether debugging output is logged for typ: --debug is passed, or typ is one of
+// --debug-types.
+func Enabled(typ string) bool {
+	if config.Debug.Bool() {
+		return true
+	}
+	once.Do(func() {
+		types = map[string]bool{}
+		for _, t := range config.DebugTypes.List() {
+			types[t] = true
+		}
+	})
+	return typ != "" && types[typ]
+}
+
+// Println logs a line when debugging output is enabled for the logger's type.
+func (l Logger) Println(a ...any) {
+	if !Enabled(l.Type) {
+		return
+	}
+	tags := []string{time.Since(started).Round(time.Millisecond).String()}
+	if l.Worker > 0 {
+		tags = append(tags, fmt.Sprintf("worker %d", l.Worker))
+	}
+	if l.Type != "" {
+		tags = append(tags, l.Type)
+	}
+	line := fmt.Sprintf("[%s] %s", strings.Join(tags, " "), fmt.Sprintln(a...))
+
+	mu.Lock()
+	defer mu.Unlock()
+	fmt.Fprint(os.Stdout, redact.String(line))
+}
+
+// Println logs a line about no worker or type in particular when --debug is passed.
+func Println(a ...any) {
+	Logger{}.Println(a...)
+}
@@ -0,0 +1,166 @@
+// Package denied records the API operations the cloud refused for lack of permissions, so that the
+// role or credentials a scan runs with can be fixed one run at a time instead of guessing from the
+// "Failed to list resources" lines of the log.
+//
+// Denials are told apart from other errors by their status code, 403, or by the error codes cloud
+// APIs answer with otherwise, like the 400 AccessDeniedException of AWS JSON APIs. The permission
+// is read from the error message when the API names it, e.g. ec2:DescribeVpcs for AWS,
+// Microsoft.Network/virtualNetworks/read for Azure or list on pods for Kubernetes.
+package denied
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Path is where the denied operations are written to.
+const Path = "denied.json"
+
+// maxBody is the number of bytes of an error response searched for the denied permission.
+const maxBody = 64 * 1024
+
+// Entry is an operation denied Count times.
+type Entry struct {
+	Service   string `json:"service"`
+	Operation string `json:"operation"`
+	Count     int    `json:"count"`
+}
+
+var (
+	mu     sync.Mutex
+	counts = map[Entry]int{}
+)
+
+var (
+	// awsAction matches the IAM action of AWS errors, e.g. "is not authorized to perform: ec2:DescribeVpcs"
+	awsAction = regexp.MustCompile(`not authorized to perform: ([A-Za-z0-9-]+):([A-Za-z0-9*]+)`)
+	// azureAction matches the action of Azure AuthorizationFailed errors, e.g. "does not have
+	// authorization to perform action 'Microsoft.Network/virtualNetworks/read'"
+	azureAction = regexp.MustCompile(`perform action '([^'/]+)/([^']+)'`)
+	// kubernetesVerb matches Kubernetes RBAC errors, e.g. `cannot list resource "pods" in API group ""`
+	kubernetesVerb = regexp.MustCompile(`cannot (\w+) resource \\?"([^"\\]+)\\?" in API group \\?"([^"\\]*)\\?"`)
+	// azureProvider matches the resource provider of Azure Resource Manager paths
+	azureProvider = regexp.MustCompile(`(?i)/providers/(Microsoft\.[^/]+)`)
+	// deniedMessage matches error bodies of denials answered without a 403
+	deniedMessage = regexp.MustCompile(`AccessDenied|UnauthorizedOperation|AuthorizationFailed|not authorized to perform`)
+)
+
+// Wrap returns a transport recording the requests denied by the cloud API.
+func Wrap(next http.RoundTripper) http.RoundTripper {
+	return transport{next: next}
+}
+
+type transport struct {
+	next http.RoundTripper
+}
+
+func (t transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	if err != nil || resp.StatusCode < 400 || resp.StatusCode >= 500 {
+		return resp, err
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxBody))
+	if err != nil {
+		return resp, err
+	}
+	// the caller reads the whole body, including what's past maxBody
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(body), resp.Body), resp.Body}
+
+	if resp.StatusCode == http.StatusForbidden ||
+		deniedMessage.MatchString(resp.Header.Get("X-Amzn-Errortype")) ||
+		deniedMessage.Match(body) {
+		add(operation(req, body))
+	}
+	return resp, nil
+}
+
+func add(service, operation string) {
+	mu.Lock()
+	defer mu.Unlock()
+	counts[Entry{Service: service, Operation: operation}]++
+}
+
+// operation returns the service and the operation a denied request needed permission for, read
+// from the error message when it names the permission and from the request otherwise.
+func operation(req *http.Request, body []byte) (string, string) {
+	if m := awsAction.FindSubmatch(body); m != nil {
+		return string(m[1]), string(m[2])
+	}
+	if m := azureAction.FindSubmatch(body); m != nil {
+		return string(m[1]), string(m[2])
+	}
+	if m := kubernetesVerb.FindSubmatch(body); m != nil {
+		group := string(m[3])
+		if group == "" {
+			group = "core"
+		}
+		return group, string(m[1]) + " " + string(m[2])
+	}
+
+	host := req.URL.Hostname()
+	// AWS JSON APIs name the operation in X-Amz-Target, e.g. CloudApiService.ListResources
+	if target := req.Header.Get("X-Amz-Target"); target != "" {
+		return strings.SplitN(host, ".", 2)[0], target[strings.LastIndex(target, ".")+1:]
+	}
+	// AWS query APIs name it in the Action parameter
+	if action := req.URL.Query().Get("Action"); action != "" {
+		return strings.SplitN(host, ".", 2)[0], action
+	}
+	if m := azureProvider.FindStringSubmatch(req.URL.Path); m != nil {
+		return m[1], req.Method + " " + req.URL.Path
+	}
+	return host, req.Method + " " + req.URL.Path
+}
+
+// Report prints the denied operations per service and writes them to Path. It does nothing when no
+// request was denied.
+func Report() error {
+	mu.Lock()
+	defer mu.Unlock()
+	if len(counts) == 0 {
+		return nil
+	}
+
+	entries := make([]Entry, 0, len(counts))
+	total := 0
+	for e, n := range counts {
+		e.Count = n
+		entries = append(entries, e)
+		total += n
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Service != entries[j].Service {
+			return entries[i].Service < entries[j].Service
+		}
+		return entries[i].Operation < entries[j].Operation
+	})
+
+	b, err := json.MarshalIndent(entries, "", "    ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(Path, b, 0644); err != nil {
+		return err
+	}
+
+	fmt.Printf("%d requests were denied, resources of these operations are missing from the scan:\n", total)
+	for i, e := range entries {
+		if i == 0 || entries[i-1].Service != e.Service {
+			fmt.Printf("  %s\n", e.Service)
+		}
+		fmt.Printf("    %s (%d)\n", e.Operation, e.Count)
+	}
+	fmt.Printf("Denied operations written to %s\n", Path)
+	return nil
+}
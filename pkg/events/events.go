@@ -0,0 +1,182 @@
+// Package events reports progress as newline delimited JSON, so that orchestrating systems can
+// display a live view of a scan. Events are written to the file or UNIX socket given with --events:
+//
+//	{"event":"started","scope":"aws-native:s3:Bucket","time":"..."}
+//	{"event":"discovered","scope":"aws-native:s3:Bucket","type":"aws-native:s3:Bucket","name":"...","id":"...","time":"..."}
+//	{"event":"error","scope":"aws-native:s3:Bucket","error":"...","time":"..."}
+//	{"event":"finished","scope":"aws-native:s3:Bucket","count":12,"time":"..."}
+//
+// The scope is the unit of work a worker lists: a type token for most programs, a resource group
+// for Azure, or a subscription for its resource groups, and a group/version/resource for Kubernetes.
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/pulumi/pulumi-cloud-import/pkg/config"
+	"github.com/pulumi/pulumi-cloud-import/pkg/redact"
+)
+
+// Event is a single progress event.
+type Event struct {
+	Event string    `json:"event"`
+	Scope string    `json:"scope,omitempty"`
+	Type  string    `json:"type,omitempty"`
+	Name  string    `json:"name,omitempty"`
+	ID    string    `json:"id,omitempty"`
+	Count int       `json:"count,omitempty"`
+	Error string    `json:"error,omitempty"`
+	Time  time.Time `json:"time"`
+}
+
+var (
+	once sync.Once
+	mu   sync.Mutex
+	out  io.Writer
+)
+
+// latency is the time spent listing the resources of a type, and how many were listed.
+type latency struct {
+	total time.Duration
+	count int
+}
+
+var (
+	timingsMu sync.Mutex
+	started   = map[string]time.Time{}
+	types     = map[string]map[string]int{}
+	latencies = map[string]*latency{}
+	durations = map[string]time.Duration{}
+	counts    = map[string]int{}
+)
+
+// open connects to the socket or opens the file given with --events, events are dropped when the
+// flag isn't passed.
+func open() {
+	once.Do(func() {
+		path := config.Events.Value()
+		if path == "" {
+			return
+		}
+		if fi, err := os.Stat(path); err == nil && fi.Mode()&os.ModeSocket != 0 {
+			conn, err := net.Dial("unix", path)
+			if err != nil {
+				panic(fmt.Sprintf("failed to connect to events socket: %v", err))
+			}
+			out = conn
+			return
+		}
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			panic(fmt.Sprintf("failed to open events file: %v", err))
+		}
+		out = f
+	})
+}
+
+// Emit writes e, setting its time.
+func Emit(e Event) {
+	open()
+	if out == nil {
+		return
+	}
+	e.Time = time.Now().UTC()
+	b, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	// progress is best effort, a consumer going away must not fail the scan
+	_, _ = out.Write(append(b, '\n'))
+}
+
+// Started reports that listing scope has started.
+func Started(scope string) {
+	timingsMu.Lock()
+	started[scope] = time.Now()
+	timingsMu.Unlock()
+	Emit(Event{Event: "started", Scope: scope})
+}
+
+// Finished reports that listing scope has finished after discovering count resources.
+func Finished(scope string, count int) {
+	timingsMu.Lock()
+	if start, ok := started[scope]; ok {
+		durations[scope] += time.Since(start)
+	}
+	if start, ok := started[scope]; ok && count > 0 {
+		// the time spent listing a scope is split evenly across the resources discovered in it
+		per := time.Since(start) / time.Duration(count)
+		for typ, n := range types[scope] {
+			l := latencies[typ]
+			if l == nil {
+				l = &latency{}
+				latencies[typ] = l
+			}
+			l.total += per * time.Duration(n)
+			l.count += n
+		}
+	}
+	delete(started, scope)
+	delete(types, scope)
+	timingsMu.Unlock()
+	Emit(Event{Event: "finished", Scope: scope, Count: count})
+}
+
+// Discovered reports a resource discovered while listing scope.
+func Discovered(scope, typ, name, id string) {
+	timingsMu.Lock()
+	if types[scope] == nil {
+		types[scope] = map[string]int{}
+	}
+	types[scope][typ]++
+	counts[typ]++
+	timingsMu.Unlock()
+	Emit(Event{Event: "discovered", Scope: scope, Type: typ, Name: redact.String(name), ID: redact.String(id)})
+}
+
+// Error reports an error listing scope.
+func Error(scope string, err error) {
+	Emit(Event{Event: "error", Scope: scope, Error: redact.String(err.Error())})
+}
+
+// Latencies returns the average time spent listing a resource, by type, measured between the
+// started and finished events of the scopes the resources were discovered in.
+func Latencies() map[string]time.Duration {
+	timingsMu.Lock()
+	defer timingsMu.Unlock()
+	averages := map[string]time.Duration{}
+	for typ, l := range latencies {
+		averages[typ] = l.total / time.Duration(l.count)
+	}
+	return averages
+}
+
+// Durations returns the time spent listing each scope, between its started and finished events.
+func Durations() map[string]time.Duration {
+	timingsMu.Lock()
+	defer timingsMu.Unlock()
+	copied := make(map[string]time.Duration, len(durations))
+	for scope, d := range durations {
+		copied[scope] = d
+	}
+	return copied
+}
+
+// Counts returns the number of resources discovered, by type.
+func Counts() map[string]int {
+	timingsMu.Lock()
+	defer timingsMu.Unlock()
+	copied := make(map[string]int, len(counts))
+	for typ, n := range counts {
+		copied[typ] = n
+	}
+	return copied
+}
@@ -0,0 +1,80 @@
+// Package fetch downloads the provider schemas and metadata importers map cloud types with. Requests
+// time out rather than hang, and are retried with the policies of package retry when the connection
+// fails or the server is struggling.
+//
+// PULUMI_CLOUD_IMPORT_FETCH_TIMEOUT sets the time a single attempt may take, e.g. "10m" for slow
+// connections, and defaults to 5 minutes.
+package fetch
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/pulumi/pulumi-cloud-import/pkg/config"
+	"github.com/pulumi/pulumi-cloud-import/pkg/retry"
+)
+
+// the transport is left unset so that requests go through http.DefaultTransport as wrapped for
+// chaos mode and recordings at the time of the request
+var client = &http.Client{Timeout: timeout()}
+
+func timeout() time.Duration {
+	return config.FetchTimeout.Duration()
+}
+
+// Get downloads url, retrying failed attempts with a growing delay.
+func Get(ctx context.Context, url string) ([]byte, error) {
+	return GetWithHeader(ctx, url, nil)
+}
+
+// GetWithHeader downloads url like Get, sending header with every attempt, e.g. to authenticate.
+func GetWithHeader(ctx context.Context, url string, header http.Header) ([]byte, error) {
+	var body []byte
+	err := retry.Do(ctx, func() error {
+		var err error
+		body, err = get(ctx, url, header)
+		return err
+	}, func(err error, class retry.Class, delay time.Duration) {
+		fmt.Printf("failed to download %s, retrying in %s: %v\n", url, delay.Round(time.Second), err)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", url, err)
+	}
+	return body, nil
+}
+
+// get makes a single attempt, classifying its failure for retries.
+func get(ctx context.Context, url string, header http.Header) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, retry.Stop(err)
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, transient(ctx, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		class, after := retry.Status(resp.StatusCode, resp.Header)
+		return nil, retry.Classify(fmt.Errorf("unexpected status %s", resp.Status), class, after)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, transient(ctx, err)
+	}
+	return body, nil
+}
+
+// transient classifies a failed connection as transient, unless ctx was cancelled.
+func transient(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return retry.Stop(err)
+	}
+	return retry.Classify(err, retry.Transient, 0)
+}
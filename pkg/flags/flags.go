@@ -0,0 +1,48 @@
+// Package flags reads the command line flags shared by the importers. Programs are started by the
+// Pulumi CLI with arguments of its own, so flags are picked out of os.Args rather than parsed strictly.
+package flags
+
+import (
+	"os"
+	"strings"
+)
+
+// Has reports whether the boolean flag name, e.g. "--import", was passed.
+func Has(name string) bool {
+	for _, arg := range os.Args[1:] {
+		if arg == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Value returns the value of the flag name passed as "--name=value" or "--name value", or "" when
+// it wasn't passed.
+func Value(name string) string {
+	args := os.Args[1:]
+	for i, arg := range args {
+		if strings.HasPrefix(arg, name+"=") {
+			return strings.TrimPrefix(arg, name+"=")
+		}
+		if arg == name && i+1 < len(args) {
+			return args[i+1]
+		}
+	}
+	return ""
+}
+
+// List returns the comma separated values of the flag name, or nil when it wasn't passed.
+func List(name string) []string {
+	v := Value(name)
+	if v == "" {
+		return nil
+	}
+	values := []string{}
+	for _, s := range strings.Split(v, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			values = append(values, s)
+		}
+	}
+	return values
+}
@@ -0,0 +1,191 @@
+// Package hierarchy reads resources in ReadMode so that every resource is read with its parent, as
+// `pulumi import` does from the parent of an import spec. Resources are discovered by concurrent
+// workers, so a child, like a pod, may be discovered before its parent, like its namespace. Such
+// children are held back until their parent has been read.
+//
+// With --max-stack-resources, resources are held back until the scan is over and split into
+// chunks of at most that many resources, each read into a stack of its own, see pkg/stackchunk.
+package hierarchy
+
+import (
+	"sort"
+
+	"github.com/pulumi/pulumi-cloud-import/pkg/config"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+// ReadFunc reads a resource with the given options and returns it, or nil when it failed to be read.
+type ReadFunc func(opts ...pulumi.ResourceOption) pulumi.Resource
+
+type pending struct {
+	name   string
+	parent string
+	read   ReadFunc
+}
+
+// Reader reads resources once their parent has been read. It is not safe for concurrent use.
+type Reader struct {
+	read    map[string]pulumi.Resource
+	waiting map[string][]pending
+	// max is the number of resources of a chunk, zero when resources aren't split
+	max int
+	// chunk is the chunk read, counting from 1
+	chunk  int
+	held   []pending
+	chunks []int
+}
+
+// NewReader returns a Reader with nothing read yet.
+func NewReader() *Reader {
+	chunk := config.StackChunk.Int()
+	if chunk == 0 {
+		chunk = 1
+	}
+	return &Reader{
+		read:    map[string]pulumi.Resource{},
+		waiting: map[string][]pending{},
+		max:     config.MaxStackResources.Int(),
+		chunk:   chunk,
+	}
+}
+
+// Read reads the resource named name, whose parent is named parent, with read. It is read right
+// away, with the parent passed as the Parent option, unless the parent hasn't been read yet, in
+// which case it is read after the parent. Resources split into chunks are held back until Flush.
+func (r *Reader) Read(name, parent string, read ReadFunc) {
+	if r.max > 0 {
+		r.held = append(r.held, pending{name: name, parent: parent, read: read})
+		return
+	}
+	r.readNow(name, parent, read)
+}
+
+func (r *Reader) readNow(name, parent string, read ReadFunc) {
+	if parent == "" {
+		r.done(name, read())
+		return
+	}
+	p, ok := r.read[parent]
+	if !ok {
+		r.waiting[parent] = append(r.waiting[parent], pending{name: name, parent: parent, read: read})
+		return
+	}
+	r.done(name, read(pulumi.Parent(p)))
+}
+
+// Flush reads the resources still waiting for a parent that was never discovered, or was left out
+// of the scan, without a parent. Call it once every resource was passed to Read. Resources split
+// into chunks are read first, those of the chunk given with --stack-chunk, the first by default.
+func (r *Reader) Flush() {
+	if r.max > 0 {
+		r.split()
+	}
+	for len(r.waiting) > 0 {
+		// parents that are waiting themselves are read along with their own parent
+		held := map[string]bool{}
+		for _, children := range r.waiting {
+			for _, c := range children {
+				held[c.name] = true
+			}
+		}
+		parents := []string{}
+		for parent := range r.waiting {
+			if !held[parent] {
+				parents = append(parents, parent)
+			}
+		}
+		sort.Strings(parents)
+		if len(parents) == 0 {
+			// resources naming each other as parent, break the cycle anywhere
+			for parent := range r.waiting {
+				parents = append(parents, parent)
+				break
+			}
+		}
+		for _, parent := range parents {
+			children := r.waiting[parent]
+			delete(r.waiting, parent)
+			for _, c := range children {
+				r.done(c.name, c.read())
+			}
+		}
+	}
+}
+
+// done records the resource read as name and reads the children waiting for it. Children of a
+// resource failing to be read are read without a parent.
+func (r *Reader) done(name string, res pulumi.Resource) {
+	if res != nil {
+		r.read[name] = res
+	}
+	children := r.waiting[name]
+	delete(r.waiting, name)
+	for _, c := range children {
+		if res == nil {
+			r.done(c.name, c.read())
+		} else {
+			r.done(c.name, c.read(pulumi.Parent(res)))
+		}
+	}
+}
+
+// Chunks returns the number of resources of every chunk the resources were split into, or nil when
+// they weren't. Call it after Flush.
+func (r *Reader) Chunks() []int {
+	return r.chunks
+}
+
+// split splits the resources held back into chunks of at most max resources and reads those of the
+// chunk read. Resources stay in the chunk of their parent, transitively, and trees of resources are
+// packed into chunks in the order of the name of their root, so that a resource stays in the same
+// stack from one scan to the next unless resources before it come or go. A tree of more than max
+// resources gets a chunk of its own.
+func (r *Reader) split() {
+	parents := map[string]string{}
+	for _, p := range r.held {
+		parents[p.name] = p.parent
+	}
+	root := func(name string) string {
+		seen := map[string]bool{}
+		for !seen[name] {
+			seen[name] = true
+			parent, ok := parents[name]
+			if !ok || parent == "" {
+				break
+			}
+			if _, ok := parents[parent]; !ok {
+				// the parent wasn't discovered, the resource is read without it
+				break
+			}
+			name = parent
+		}
+		return name
+	}
+	roots := map[string]string{}
+	sizes := map[string]int{}
+	for _, p := range r.held {
+		roots[p.name] = root(p.name)
+		sizes[roots[p.name]]++
+	}
+	names := make([]string, 0, len(sizes))
+	for name := range sizes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	chunkOf := map[string]int{}
+	r.chunks = []int{0}
+	for _, name := range names {
+		if last := len(r.chunks) - 1; r.chunks[last] > 0 && r.chunks[last]+sizes[name] > r.max {
+			r.chunks = append(r.chunks, 0)
+		}
+		r.chunks[len(r.chunks)-1] += sizes[name]
+		chunkOf[name] = len(r.chunks)
+	}
+	held := r.held
+	r.held = nil
+	for _, p := range held {
+		if chunkOf[roots[p.name]] == r.chunk {
+			r.readNow(p.name, p.parent, p.read)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+// Package ignore leaves out the resources listed in the ignore file named by --ignore-file. Many
+// unwanted resources are only recognizable by the shape of their ID, like the service-linked roles
+// of AWS or the node resource groups of AKS, so the file lists ID patterns, one per line:
+//
+//	# service-linked roles are managed by AWS
+//	arn:aws:iam::*:role/aws-service-role/*
+//	# node resource groups and everything in them are managed by AKS
+//	/subscriptions/*/resourceGroups/MC_*
+//
+// A * matches any run of characters, / and : included, and a ? any single character. Patterns match
+// the whole ID, whatever its case since Azure IDs aren't consistently cased. Lines starting with #
+// are comments.
+package ignore
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/pulumi/pulumi-cloud-import/pkg/config"
+	"github.com/pulumi/pulumi-cloud-import/pkg/debuglog"
+)
+
+var (
+	once     sync.Once
+	patterns []*regexp.Regexp
+	ignored  int64
+)
+
+func load() {
+	once.Do(func() {
+		path := config.IgnoreFile.Value()
+		if path == "" {
+			return
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			panic(fmt.Sprintf("failed to read ignore file: %v", err))
+		}
+		defer f.Close()
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			patterns = append(patterns, compile(line))
+		}
+		if err := scanner.Err(); err != nil {
+			panic(fmt.Sprintf("failed to read ignore file: %v", err))
+		}
+	})
+}
+
+// compile turns an ID pattern into a regular expression matching whole IDs.
+func compile(pattern string) *regexp.Regexp {
+	var b strings.Builder
+	b.WriteString("(?i)^")
+	for _, r := range pattern {
+		switch r {
+		case '*':
+			b.WriteString(".*")
+		case '?':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	b.WriteString("$")
+	return regexp.MustCompile(b.String())
+}
+
+// Match reports whether any of the IDs of a resource, e.g. its identifier and its ARN, matches a
+// pattern of the ignore file.
+func Match(ids ...string) bool {
+	load()
+	for _, re := range patterns {
+		for _, id := range ids {
+			if id != "" && re.MatchString(id) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Skip reports whether the resource of the given type with the given IDs is ignored, and counts it
+// when it is. The first ID is the one logged.
+func Skip(typ string, ids ...string) bool {
+	if !Match(ids...) {
+		return false
+	}
+	atomic.AddInt64(&ignored, 1)
+	debuglog.For(0, typ).Println("ignoring", ids[0])
+	return true
+}
+
+// Report prints how many resources were left out.
+func Report() {
+	if n := atomic.LoadInt64(&ignored); n > 0 {
+		fmt.Printf("Ignored %d resources matching %s\n", n, config.IgnoreFile.Value())
+	}
+}
@@ -0,0 +1,81 @@
+// Package ignorechanges sets the ignoreChanges option of the resources read into a stack, so that
+// properties the provider computes or the cloud changes on its own, like the last modified time of
+// a resource, don't show up as a diff on every update once the stack is onboarded. The properties
+// are listed per type in the JSON file named by --ignore-changes:
+//
+//	{
+//	    "aws-native:ec2:Instance": ["tags", "userData"],
+//	    "azure-native:*:*": ["tags"]
+//	}
+//
+// Types are matched with path.Match patterns, and a type gets the properties of every pattern it
+// matches.
+package ignorechanges
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"sync"
+
+	"github.com/pulumi/pulumi-cloud-import/pkg/config"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+var (
+	once  sync.Once
+	rules map[string][]string
+)
+
+func load() {
+	once.Do(func() {
+		file := config.IgnoreChanges.Value()
+		if file == "" {
+			return
+		}
+		b, err := os.ReadFile(file)
+		if err != nil {
+			panic(fmt.Sprintf("failed to read ignore changes file: %v", err))
+		}
+		if err := json.Unmarshal(b, &rules); err != nil {
+			panic(fmt.Sprintf("failed to parse %s: %v", file, err))
+		}
+		for pattern := range rules {
+			if _, err := path.Match(pattern, ""); err != nil {
+				panic(fmt.Sprintf("invalid type pattern %q in %s: %v", pattern, file, err))
+			}
+		}
+	})
+}
+
+// Properties returns the properties of typ whose changes are ignored, sorted.
+func Properties(typ string) []string {
+	load()
+	seen := map[string]bool{}
+	properties := []string{}
+	for pattern, props := range rules {
+		if ok, _ := path.Match(pattern, typ); !ok {
+			continue
+		}
+		for _, p := range props {
+			if !seen[p] {
+				seen[p] = true
+				properties = append(properties, p)
+			}
+		}
+	}
+	sort.Strings(properties)
+	return properties
+}
+
+// Options returns the resource options a resource of typ is read with, none when no property of
+// typ is ignored.
+func Options(typ string) []pulumi.ResourceOption {
+	properties := Properties(typ)
+	if len(properties) == 0 {
+		return nil
+	}
+	return []pulumi.ResourceOption{pulumi.IgnoreChanges(properties)}
+}
@@ -0,0 +1,322 @@
+package importer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pulumi/pulumi-cloud-import/pkg/aliases"
+	"github.com/pulumi/pulumi-cloud-import/pkg/backpressure"
+	"github.com/pulumi/pulumi-cloud-import/pkg/cancel"
+	"github.com/pulumi/pulumi-cloud-import/pkg/chaos"
+	"github.com/pulumi/pulumi-cloud-import/pkg/compress"
+	"github.com/pulumi/pulumi-cloud-import/pkg/config"
+	"github.com/pulumi/pulumi-cloud-import/pkg/coverage"
+	"github.com/pulumi/pulumi-cloud-import/pkg/created"
+	"github.com/pulumi/pulumi-cloud-import/pkg/debuglog"
+	"github.com/pulumi/pulumi-cloud-import/pkg/denied"
+	"github.com/pulumi/pulumi-cloud-import/pkg/events"
+	"github.com/pulumi/pulumi-cloud-import/pkg/hierarchy"
+	"github.com/pulumi/pulumi-cloud-import/pkg/ignore"
+	"github.com/pulumi/pulumi-cloud-import/pkg/ignorechanges"
+	"github.com/pulumi/pulumi-cloud-import/pkg/insights"
+	"github.com/pulumi/pulumi-cloud-import/pkg/manifest"
+	"github.com/pulumi/pulumi-cloud-import/pkg/naming"
+	"github.com/pulumi/pulumi-cloud-import/pkg/quarantine"
+	"github.com/pulumi/pulumi-cloud-import/pkg/recorder"
+	"github.com/pulumi/pulumi-cloud-import/pkg/redact"
+	"github.com/pulumi/pulumi-cloud-import/pkg/shard"
+	"github.com/pulumi/pulumi-cloud-import/pkg/stackchunk"
+	"github.com/pulumi/pulumi-cloud-import/pkg/stackstate"
+	"github.com/pulumi/pulumi-cloud-import/pkg/summary"
+	"github.com/pulumi/pulumi-cloud-import/pkg/supportbundle"
+	"github.com/pulumi/pulumi-cloud-import/pkg/version"
+	"github.com/pulumi/pulumi-cloud-import/pkg/workers"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+type ImportFile struct {
+	// Metadata tells which environment the import file was produced from, `pulumi import`
+	// ignores it
+	Metadata  *manifest.Header        `json:"metadata,omitempty"`
+	NameTable map[string]resource.URN `json:"nameTable"`
+	Resources []ImportSpec            `json:"resources"`
+}
+
+type ImportSpec struct {
+	Type              string   `json:"type"`
+	Name              string   `json:"name"`
+	ID                string   `json:"id"`
+	Parent            string   `json:"parent"`
+	Provider          string   `json:"provider"`
+	Version           string   `json:"version"`
+	PluginDownloadURL string   `json:"pluginDownloadUrl"`
+	Properties        []string `json:"properties"`
+	// Created is the creation time of the resource, when the provider's API exposes it. It scopes
+	// scans given a creation time window and is not written to the import file.
+	Created time.Time `json:"-"`
+}
+
+type Mode int64
+
+const (
+	ImportMode Mode = iota
+	ReadMode
+)
+
+// Discoverer lists the resources of a single cloud provider. Every importer built on this
+// package implements it, and Main takes care of running the workers, reading resources and
+// writing the import file.
+type Discoverer interface {
+	// Types returns the Pulumi type tokens this discoverer knows how to list.
+	Types() []string
+	// List lists all resources of the given type token and calls emit for each of them.
+	List(ctx context.Context, typ string, emit func(ImportSpec)) error
+}
+
+// Identifier is implemented by discoverers that can tell what they scan, e.g. the account or the
+// server, which is recorded in the run manifest. It must never return credentials.
+type Identifier interface {
+	Identity() map[string]string
+}
+
+// DebugLog logs a line when --debug is passed, see pkg/debuglog.
+func DebugLog(a ...any) {
+	debuglog.Println(a...)
+}
+
+// Main is the entrypoint shared by all importers: it runs the discoverer as a Pulumi program
+// reading every resource, or writes import.json when --import is passed.
+func Main(d Discoverer) {
+	version.HandleFlag()
+	supportbundle.Start()
+	defer supportbundle.Write()
+	isImportMode := IsImportMode()
+	if !isImportMode {
+		// the scan is recorded for the stacks the resources are split into to replay
+		stackchunk.Record()
+	}
+	http.DefaultTransport = denied.Wrap(workers.Wrap(chaos.Wrap(recorder.Wrap(http.DefaultTransport))))
+
+	// pulumi read resource mode
+	if !isImportMode {
+		supportbundle.Run(func(ctx *pulumi.Context) error {
+			_, err := BuildImportSpec(ctx, d, ReadMode)
+			if err != nil {
+				return err
+			}
+			return quarantine.Save()
+		})
+	} else {
+		mode := ImportMode
+		// resources already in the stack given with --stack are left out of the import file
+		state, err := stackstate.FromFlags()
+		if err != nil {
+			panic(err)
+		}
+		imports, err := BuildImportSpec(nil, d, mode)
+		if err != nil {
+			panic(err)
+		}
+		if err := insights.Diff(imports.Resources, specRef); err != nil {
+			panic(err)
+		}
+		imports.Resources, imports.NameTable = stackstate.Filter(state, imports.Resources, specRef)
+		fmt.Printf("Total resources: %d", len(imports.Resources))
+
+		imports.Metadata = manifest.NewHeader()
+		err = WriteImportFile(imports)
+		if err != nil {
+			panic(err)
+		}
+	}
+}
+
+// BuildImportSpec splits the discoverer's types across workers, collects the discovered
+// resources and, in ReadMode, reads each of them into the stack.
+func BuildImportSpec(ctx *pulumi.Context, d Discoverer, mode Mode) (ImportFile, error) {
+	imports := ImportFile{
+		Resources: []ImportSpec{},
+	}
+
+	var ops uint64
+
+	importChan := make(chan ImportSpec, backpressure.MaxBuffer())
+	watchdog := backpressure.NewWatchdog()
+	window := created.FromFlags()
+	var wg sync.WaitGroup
+
+	chunks := GetConcurrentWorkers()
+	typeChunks := make([][]string, chunks)
+	index := 0
+	// split input ino N chunks
+	coverage.Known(d.Types()...)
+	for _, t := range d.Types() {
+		typeChunks[index] = append(typeChunks[index], t)
+		index++
+		index = index % chunks
+	}
+
+	for i := 0; i < chunks; i++ {
+		types := typeChunks[i]
+		wg.Add(1)
+		go func(typeChunk []string, i int) {
+			defer func() {
+				if r := recover(); r != nil {
+					redact.Printf("encountered error processing resources: %v \n", r)
+				}
+			}()
+			defer wg.Done()
+
+			seen := map[string]bool{}
+			for _, t := range typeChunk {
+				workers.Wait(i)
+				events.Started(t)
+				count := 0
+				ctx, done := cancel.Scope(t)
+				err := d.List(ctx, t, func(r ImportSpec) {
+					key := r.Type + "/" + r.ID
+					if seen[key] || !window.Keep(r.Created) {
+						return
+					}
+					if ignore.Skip(t, r.ID) {
+						return
+					}
+					seen[key] = true
+					if r.Type == "" {
+						r.Type = t
+					}
+					if r.Name == "" {
+						r.Name = naming.Default.Name(r.ID)
+						redact.Println("resource", r.ID, "of type", r.Type, "has no name, using", r.Name)
+					}
+					atomic.AddUint64(&ops, 1)
+					debuglog.For(i+1, t).Println("count:", atomic.LoadUint64(&ops))
+					count++
+					events.Discovered(t, r.Type, r.Name, r.ID)
+					watchdog.Wait(func() int { return len(importChan) })
+					importChan <- r
+				})
+
+				done()
+				debuglog.For(i+1, t).Println("listed", count, "resources")
+				// just print out errors as info for now
+				// as some resources may require special permissions.
+				if cancel.Cancelled(t) {
+					coverage.Skip(t, "cancelled with --cancel-file")
+				} else if err != nil {
+					redact.Println("Failed to list resources of type", t, err)
+					events.Error(t, err)
+					coverage.Failed(t, err)
+				} else {
+					coverage.Scanned(t)
+				}
+				events.Finished(t, count)
+			}
+			fmt.Printf("worker %d of %d completed\n", i+1, chunks)
+		}(types, i)
+	}
+
+	go func() {
+		wg.Wait()
+		close(importChan)
+	}()
+	defer window.Report()
+	defer ignore.Report()
+
+	reader := hierarchy.NewReader()
+	for r := range importChan {
+		imports.Resources = append(imports.Resources, r)
+		previous := aliases.Record(r.Type, r.ID, r.Name)
+		if mode == ReadMode {
+			r := r
+			reader.Read(r.Name, r.Parent, func(opts ...pulumi.ResourceOption) pulumi.Resource {
+				var res pulumi.CustomResourceState
+				opts = append(opts, ignorechanges.Options(r.Type)...)
+				opts = append(opts, aliases.Options(previous)...)
+				// resources failing to register or to be read are set aside in quarantine.json
+				if err := ctx.ReadResource(r.Type, r.Name, pulumi.ID(r.ID), nil, &res, opts...); err != nil {
+					quarantine.Add(r, quarantine.StageRead, err)
+					return nil
+				}
+				quarantine.Watch(r, &res, nil)
+				return &res
+			})
+		}
+	}
+	reader.Flush()
+	if mode == ReadMode {
+		// the run of the first stack reports on the scan and updates the others
+		if stackchunk.Additional() {
+			return imports, nil
+		}
+		if err := stackchunk.Rotate(ctx, reader.Chunks()); err != nil {
+			return imports, err
+		}
+	}
+
+	if err := summary.Report(); err != nil {
+		return imports, err
+	}
+	if err := coverage.Report(); err != nil {
+		return imports, err
+	}
+	if err := aliases.Write(); err != nil {
+		return imports, err
+	}
+	if i, ok := d.(Identifier); ok {
+		for key, value := range i.Identity() {
+			manifest.Identify(key, value)
+		}
+	}
+	if err := manifest.Write(); err != nil {
+		return imports, err
+	}
+	return imports, denied.Report()
+}
+
+// write import file to disk, split into shards when --shards is passed
+func WriteImportFile(imports ImportFile) error {
+	shards := shard.Split(imports.Resources, specRef)
+	for i, resources := range shards {
+		f := ImportFile{Metadata: imports.Metadata, NameTable: imports.NameTable, Resources: resources}
+		// write the import file to disk
+		importFile, err := json.MarshalIndent(f, "", "    ")
+		if err != nil {
+			return err
+		}
+
+		err = compress.WriteFile(shard.Path(config.Output.Value(), i, len(shards)), importFile)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func specRef(r ImportSpec) stackstate.Ref {
+	return stackstate.Ref{Type: r.Type, ID: r.ID, Name: r.Name, Parent: r.Parent}
+}
+
+// check for presence of --import flag
+func IsImportMode() bool {
+	for _, arg := range os.Args {
+		if arg == "--import" {
+			return true
+		}
+	}
+	return false
+}
+
+// GetConcurrentWorkers returns the number of workers specified in PULUMI_CLOUD_IMPORT_WORKERS, or a
+// default of 10
+func GetConcurrentWorkers() int {
+	return workers.Count(10)
+}
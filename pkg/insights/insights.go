@@ -0,0 +1,117 @@
+// Package insights compares discovered resources with the resources Pulumi Cloud already tracks in
+// the stacks of an organization, to answer which resources aren't managed with Pulumi yet. Pass the
+// organization with --insights-org and a token in PULUMI_ACCESS_TOKEN:
+//
+//	go run . --import --insights-org my-org
+//
+// Resources are matched by ID alone, so that a bucket managed with the aws provider counts as
+// tracked when it is discovered as an aws-native bucket. The resources missing from Pulumi Cloud
+// are written to untracked.json.
+package insights
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/pulumi/pulumi-cloud-import/pkg/config"
+	"github.com/pulumi/pulumi-cloud-import/pkg/fetch"
+	"github.com/pulumi/pulumi-cloud-import/pkg/stackstate"
+)
+
+// Path is where the untracked resources are written to.
+const Path = "untracked.json"
+
+// pageSize is the number of resources requested per page of search results.
+const pageSize = 500
+
+// Entry is a discovered resource Pulumi Cloud doesn't track.
+type Entry struct {
+	Type string `json:"type"`
+	Name string `json:"name"`
+	ID   string `json:"id"`
+}
+
+type searchResponse struct {
+	Resources []struct {
+		Type string `json:"type"`
+		ID   string `json:"id"`
+	} `json:"resources"`
+}
+
+// Enabled reports whether --insights-org was passed.
+func Enabled() bool {
+	return config.InsightsOrg.Value() != ""
+}
+
+// Diff writes the resources Pulumi Cloud doesn't track to Path and prints how many there are, when
+// --insights-org is passed.
+func Diff[T any](resources []T, ref func(T) stackstate.Ref) error {
+	if !Enabled() {
+		return nil
+	}
+	org := config.InsightsOrg.Value()
+	tracked, err := search(context.Background(), org)
+	if err != nil {
+		return err
+	}
+
+	untracked := []Entry{}
+	for _, r := range resources {
+		spec := ref(r)
+		// IDs are compared case insensitively, Azure returns them in varying case
+		if !tracked[strings.ToLower(spec.ID)] {
+			untracked = append(untracked, Entry{Type: spec.Type, Name: spec.Name, ID: spec.ID})
+		}
+	}
+
+	b, err := json.MarshalIndent(untracked, "", "    ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(Path, b, 0644); err != nil {
+		return err
+	}
+	fmt.Printf("%d of %d discovered resources are not tracked by any stack of %s\n", len(untracked), len(resources), org)
+	fmt.Printf("Untracked resources written to %s\n", Path)
+	return nil
+}
+
+// search returns the lowercased IDs of every resource in the stacks of org, from the resource
+// search API of Pulumi Cloud.
+func search(ctx context.Context, org string) (map[string]bool, error) {
+	token := os.Getenv("PULUMI_ACCESS_TOKEN")
+	if token == "" {
+		return nil, fmt.Errorf("PULUMI_ACCESS_TOKEN must be set to search the resources of %s", org)
+	}
+	header := http.Header{
+		"Authorization": {"token " + token},
+		"Accept":        {"application/vnd.pulumi+8"},
+	}
+
+	ids := map[string]bool{}
+	base := strings.TrimSuffix(config.CloudURL.Value(), "/")
+	for page := 1; ; page++ {
+		u := fmt.Sprintf("%s/api/orgs/%s/search/resources?page=%d&size=%d", base, url.PathEscape(org), page, pageSize)
+		b, err := fetch.GetWithHeader(ctx, u, header)
+		if err != nil {
+			return nil, fmt.Errorf("failed to search the resources of %s: %w", org, err)
+		}
+		var resp searchResponse
+		if err := json.Unmarshal(b, &resp); err != nil {
+			return nil, fmt.Errorf("failed to parse the resources of %s: %w", org, err)
+		}
+		for _, r := range resp.Resources {
+			if r.ID != "" {
+				ids[strings.ToLower(r.ID)] = true
+			}
+		}
+		if len(resp.Resources) < pageSize {
+			return ids, nil
+		}
+	}
+}
@@ -0,0 +1,151 @@
+// Package manifest writes run-manifest.json at the end of a scan, recording how the import file
+// was produced: the build, the schemas, the effective value of every setting and where it came
+// from, the types skipped without being listed and the identity the cloud was scanned with, like
+// the AWS account or the Kubernetes cluster. Credentials are never recorded.
+package manifest
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/pulumi/pulumi-cloud-import/pkg/config"
+	"github.com/pulumi/pulumi-cloud-import/pkg/redact"
+	"github.com/pulumi/pulumi-cloud-import/pkg/version"
+)
+
+// Path is where the manifest is written to.
+const Path = "run-manifest.json"
+
+// Setting is the effective value of a setting. Source is the flag or environment variable it was
+// given with, or "default".
+type Setting struct {
+	Flag   string `json:"flag"`
+	Env    string `json:"env"`
+	Value  string `json:"value"`
+	Source string `json:"source"`
+}
+
+// Manifest is the content of run-manifest.json.
+type Manifest struct {
+	Program  string            `json:"program"`
+	Version  string            `json:"version"`
+	Commit   string            `json:"commit"`
+	Date     string            `json:"date"`
+	Args     []string          `json:"args"`
+	Started  time.Time         `json:"started"`
+	Finished time.Time         `json:"finished"`
+	Schemas  []version.Schema  `json:"schemas"`
+	Settings []Setting         `json:"settings"`
+	Identity map[string]string `json:"identity"`
+	Skipped  map[string]string `json:"skipped"`
+}
+
+var (
+	started  = time.Now().UTC()
+	mu       sync.Mutex
+	identity = map[string]string{}
+	skipped  = map[string]string{}
+)
+
+// Identify records what the cloud was scanned as, e.g. Identify("account", "123456789012").
+func Identify(key, value string) {
+	if value == "" {
+		return
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	identity[key] = value
+}
+
+// Skip records that the resources of typ weren't listed, and why.
+func Skip(typ, reason string) {
+	mu.Lock()
+	defer mu.Unlock()
+	skipped[typ] = reason
+}
+
+// Header describes the scan an import file comes from, and is written at the top of the import
+// file so that the file tells which environment it was produced from. Filters are the settings
+// narrowing down the resources scanned, by flag.
+type Header struct {
+	Program  string            `json:"program"`
+	Version  string            `json:"version"`
+	Scanned  time.Time         `json:"scanned"`
+	Identity map[string]string `json:"identity"`
+	Filters  map[string]string `json:"filters,omitempty"`
+}
+
+// NewHeader returns the header of the import file of the run, from the identities recorded so far.
+// Identities are redacted like in the manifest.
+func NewHeader() *Header {
+	mu.Lock()
+	defer mu.Unlock()
+	v, _, _ := version.Build()
+	h := &Header{
+		Program:  version.Program(),
+		Version:  v,
+		Scanned:  started,
+		Identity: map[string]string{},
+		Filters:  map[string]string{},
+	}
+	for key, value := range identity {
+		h.Identity[key] = redact.String(value)
+	}
+	for _, s := range config.All {
+		if value, _, ok := s.Lookup(); ok && s.Filter && value != "" {
+			h.Filters[s.Flag()] = value
+		}
+	}
+	return h
+}
+
+// Write writes the manifest of the run to Path. Settings that aren't given and have no default
+// are left out. Identities are redacted like log output when --redact is passed.
+func Write() error {
+	mu.Lock()
+	defer mu.Unlock()
+	v, commit, date := version.Build()
+	m := Manifest{
+		Program:  version.Program(),
+		Version:  v,
+		Commit:   commit,
+		Date:     date,
+		Args:     os.Args[1:],
+		Started:  started,
+		Finished: time.Now().UTC(),
+		Schemas:  version.Schemas(),
+		Settings: []Setting{},
+		Identity: map[string]string{},
+		Skipped:  skipped,
+	}
+	if m.Schemas == nil {
+		m.Schemas = []version.Schema{}
+	}
+	for key, value := range identity {
+		m.Identity[key] = redact.String(value)
+	}
+
+	for _, s := range config.All {
+		value, source, ok := s.Lookup()
+		if !ok {
+			value, source = s.Default, "default"
+		}
+		if value == "" {
+			continue
+		}
+		m.Settings = append(m.Settings, Setting{Flag: s.Flag(), Env: s.Env(), Value: value, Source: source})
+	}
+
+	b, err := json.MarshalIndent(m, "", "    ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(Path, b, 0644); err != nil {
+		return err
+	}
+	fmt.Printf("Run manifest written to %s\n", Path)
+	return nil
+}
@@ -0,0 +1,124 @@
+// Package naming derives Pulumi resource names from cloud provider names and identifiers.
+//
+// Names are transliterated so that letters of other scripts are spelled out in ASCII (Москва
+// becomes Moskva), normalized so that accented characters keep their base letter (é becomes e),
+// stripped of the characters a provider's strategy doesn't allow, and capped in length so that URNs
+// stay manageable. Truncated names end in a short hash of the full name to keep them unique.
+//
+// When nothing is left of a name, e.g. one made of emoji, a name derived from a hash of the
+// original is used instead and the substitution is reported.
+//
+// --name-prefix and --name-suffix are added to every name as they are, e.g. to tell imported
+// resources apart from the ones written by hand in the same stack. The length cap leaves room for
+// them.
+package naming
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"unicode"
+
+	"github.com/pulumi/pulumi-cloud-import/pkg/config"
+	"github.com/pulumi/pulumi-cloud-import/pkg/redact"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Strategy describes how names are derived for a provider.
+type Strategy struct {
+	// Keep lists the characters kept in names in addition to ASCII letters, digits and spaces.
+	Keep string
+	// MaxLength is the length names are capped at, 0 means no cap.
+	MaxLength int
+}
+
+var (
+	// Default is used by importers built on pkg/importer.
+	Default = Strategy{MaxLength: 128}
+	// AWS names are built from the type and the Cloud Control identifier, which is often an ARN.
+	AWS = Strategy{MaxLength: 200}
+	// AWSTemplate names are written with --name-template, whose separators are kept.
+	AWSTemplate = Strategy{Keep: "-_.", MaxLength: 200}
+	// Azure names are the last segment of the resource ID.
+	Azure = Strategy{MaxLength: 128}
+	// Kubernetes names are namespace/name, which are already valid Pulumi names.
+	Kubernetes = Strategy{Keep: "/-.", MaxLength: 253}
+)
+
+// Name concatenates parts and turns the result into a Pulumi resource name, with --name-prefix and
+// --name-suffix. Empty parts make an empty name, without the prefix and suffix, for callers to fall
+// back on another name.
+func (s Strategy) Name(parts ...string) string {
+	name := s.clean(parts...)
+	if name == "" {
+		return ""
+	}
+	prefix, suffix := config.NamePrefix.Value(), config.NameSuffix.Value()
+	return prefix + s.truncate(name, len(prefix)+len(suffix)) + suffix
+}
+
+// Disambiguate returns name, as returned by Name, with a short hash of id appended, for the resources
+// whose names collide once stripped. The hash is the same from one scan to the next.
+func (s Strategy) Disambiguate(name, id string) string {
+	prefix, suffix := config.NamePrefix.Value(), config.NameSuffix.Value()
+	h := hash(id)
+	return prefix + s.truncate(Base(name), len(prefix)+len(suffix)+len(h)) + h + suffix
+}
+
+// Base returns name without --name-prefix and --name-suffix, for names derived from the name of
+// another resource.
+func Base(name string) string {
+	return strings.TrimSuffix(strings.TrimPrefix(name, config.NamePrefix.Value()), config.NameSuffix.Value())
+}
+
+// clean concatenates parts and strips the result of the characters s doesn't allow.
+func (s Strategy) clean(parts ...string) string {
+	original := strings.Join(parts, "")
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r) || r == ' '):
+			return r
+		case strings.ContainsRune(s.Keep, r):
+			return r
+		}
+		return -1
+	}, fold(transliterate(original)))
+	if strings.TrimSpace(name) == "" && original != "" {
+		name = "resource" + hash(original)
+		redact.Printf("name %q has no valid characters, using %s instead\n", original, name)
+	}
+	return name
+}
+
+// fold decomposes characters and drops combining marks, so that é becomes e rather than being removed.
+func fold(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.Is(unicode.Mn, r) {
+			return -1
+		}
+		return r
+	}, norm.NFKD.String(s))
+}
+
+// truncate caps name to the length of s, less reserved.
+func (s Strategy) truncate(name string, reserved int) string {
+	max := s.MaxLength - reserved
+	if s.MaxLength <= 0 || len(name) <= max {
+		return name
+	}
+	suffix := hash(name)
+	if max < len(suffix) {
+		return suffix
+	}
+	return name[:max-len(suffix)] + suffix
+}
+
+func hash(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])[:8]
+}
+
+// Clear strips everything but ASCII letters, digits and spaces from str, without a length cap.
+func Clear(str string) string {
+	return Strategy{}.clean(str)
+}
@@ -0,0 +1,158 @@
+package naming
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestName(t *testing.T) {
+	tests := []struct {
+		name     string
+		strategy Strategy
+		parts    []string
+		expected string
+	}{
+		{"empty", Default, []string{""}, ""},
+		{"no parts", Default, nil, ""},
+		{"symbols", Default, []string{"!!!"}, "resource" + hash("!!!")},
+		{"emoji", AWS, []string{"🎉🎉"}, "resource" + hash("🎉🎉")},
+		{"symbols only once joined", AWS, []string{"-", "/"}, "resource" + hash("-/")},
+		{"concatenated", AWS, []string{"S3", "Bucket", "my-bucket"}, "S3Bucketmybucket"},
+		{"spaces kept", Default, []string{"my bucket"}, "my bucket"},
+		{"accents folded", Default, []string{"café"}, "cafe"},
+		{"decomposed accents folded", Default, []string{"cafe\u0301"}, "cafe"},
+		{"compatibility folded", Default, []string{"ﬁle"}, "file"},
+		{"letters spelled out", Default, []string{"Ærøskøbing"}, "AEroskobing"},
+		{"sharp s", Default, []string{"straße"}, "strasse"},
+		{"cyrillic", Default, []string{"Москва"}, "Moskva"},
+		{"greek", Default, []string{"Αθήνα"}, "Athina"},
+		{"hiragana", Default, []string{"とうきょう"}, "toukyou"},
+		{"katakana", Default, []string{"トウキョウ"}, "toukyou"},
+		{"hangul", Default, []string{"서울"}, "seoul"},
+		{"han", Default, []string{"東京"}, "u6771u4eac"},
+		{"default keeps no separators", Default, []string{"my-bucket_1.x/y"}, "mybucket1xy"},
+		{"aws keeps no separators", AWS, []string{"my-bucket_1.x/y"}, "mybucket1xy"},
+		{"aws template keeps separators", AWSTemplate, []string{"my-bucket_1.x/y"}, "my-bucket_1.xy"},
+		{"kubernetes keeps separators", Kubernetes, []string{"my-ns/my_app.v1"}, "my-ns/myapp.v1"},
+		{"under the cap", Strategy{MaxLength: 10}, []string{"abcdefghij"}, "abcdefghij"},
+		{"truncated", Strategy{MaxLength: 10}, []string{"abcdefghijk"}, "ab" + hash("abcdefghijk")},
+		{"cap shorter than the hash", Strategy{MaxLength: 4}, []string{"abcdefghijk"}, hash("abcdefghijk")},
+		{"no cap", Strategy{}, []string{strings.Repeat("a", 1000)}, strings.Repeat("a", 1000)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.strategy.Name(tt.parts...); got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestNameLength(t *testing.T) {
+	for _, s := range []Strategy{Default, AWS, AWSTemplate, Azure, Kubernetes} {
+		long := strings.Repeat("a", s.MaxLength+1)
+		name := s.Name(long)
+		if len(name) != s.MaxLength {
+			t.Errorf("%+v: expected %d characters, got %d", s, s.MaxLength, len(name))
+		}
+		// truncated names of different resources stay distinct
+		if other := s.Name(long + "b"); other == name {
+			t.Errorf("%+v: truncated names collide: %s", s, name)
+		}
+	}
+}
+
+func TestNameLocale(t *testing.T) {
+	tests := []struct {
+		locale, name, expected string
+	}{
+		{"", "Müller", "Muller"},
+		{"de", "Müller", "Mueller"},
+		{"DE", "Größe", "Groesse"},
+		{"", "Гліб", "Glib"},
+		{"uk", "Гліб", "Hlib"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.locale+" "+tt.name, func(t *testing.T) {
+			t.Setenv("PULUMI_CLOUD_IMPORT_NAME_LOCALE", tt.locale)
+			if got := Default.Name(tt.name); got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestNamePrefixSuffix(t *testing.T) {
+	tests := []struct {
+		name           string
+		prefix, suffix string
+		strategy       Strategy
+		parts          []string
+		expected       string
+	}{
+		{"added as given", "imported-", "-v1", AWS, []string{"S3Bucket", "my-bucket"}, "imported-S3Bucketmybucket-v1"},
+		{"room left for them", "pre", "suf", Strategy{MaxLength: 16}, []string{"abcdefghijk"}, "pre" + "ab" + hash("abcdefghijk") + "suf"},
+		{"longer than the cap", strings.Repeat("p", 12), strings.Repeat("s", 12), Strategy{MaxLength: 20}, []string{"bucket"}, strings.Repeat("p", 12) + hash("bucket") + strings.Repeat("s", 12)},
+		{"not around an empty name", "imported-", "-v1", Default, []string{""}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("PULUMI_CLOUD_IMPORT_NAME_PREFIX", tt.prefix)
+			t.Setenv("PULUMI_CLOUD_IMPORT_NAME_SUFFIX", tt.suffix)
+			got := tt.strategy.Name(tt.parts...)
+			if got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+			if base := Base(got); got != "" && (!strings.HasPrefix(got, tt.prefix+base) || !strings.HasSuffix(got, base+tt.suffix)) {
+				t.Errorf("Base(%q) = %q", got, base)
+			}
+		})
+	}
+}
+
+func TestDisambiguate(t *testing.T) {
+	tests := []struct {
+		name           string
+		prefix, suffix string
+		strategy       Strategy
+		base, id       string
+		expected       string
+	}{
+		{"hash appended", "", "", AWS, "S3Bucketmybucket", "mybucket", "S3Bucketmybucket" + hash("mybucket")},
+		{"inside prefix and suffix", "imp-", "-x", AWS, "S3Bucketmybucket", "mybucket", "imp-S3Bucketmybucket" + hash("mybucket") + "-x"},
+		{"within the cap", "", "", Strategy{MaxLength: 20}, "abcdefghijklmnop", "id", "abcd" + hash("abcdefghijklmnop") + hash("id")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("PULUMI_CLOUD_IMPORT_NAME_PREFIX", tt.prefix)
+			t.Setenv("PULUMI_CLOUD_IMPORT_NAME_SUFFIX", tt.suffix)
+			name := tt.strategy.Name(tt.base)
+			got := tt.strategy.Disambiguate(name, tt.id)
+			if got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+			if again := tt.strategy.Disambiguate(name, tt.id); again != got {
+				t.Errorf("not stable: %q then %q", got, again)
+			}
+			if other := tt.strategy.Disambiguate(name, tt.id+"2"); other == got {
+				t.Errorf("identifiers %q and %q disambiguate to %q", tt.id, tt.id+"2", got)
+			}
+		})
+	}
+}
+
+func TestClear(t *testing.T) {
+	tests := []struct {
+		in, expected string
+	}{
+		{"", ""},
+		{"my-bucket.v1", "mybucketv1"},
+		{"Crème brûlée", "Creme brulee"},
+		{"***", "resource" + hash("***")},
+	}
+	for _, tt := range tests {
+		if got := Clear(tt.in); got != tt.expected {
+			t.Errorf("Clear(%q): expected %q, got %q", tt.in, tt.expected, got)
+		}
+	}
+}
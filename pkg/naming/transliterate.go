@@ -0,0 +1,163 @@
+package naming
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/pulumi/pulumi-cloud-import/pkg/config"
+	"golang.org/x/text/unicode/norm"
+)
+
+// latin spells out the Latin letters that don't decompose into a base letter and a mark.
+var latin = map[rune]string{
+	'ß': "ss", 'æ': "ae", 'Æ': "AE", 'œ': "oe", 'Œ': "OE", 'ø': "o", 'Ø': "O", 'đ': "d", 'Đ': "D",
+	'ł': "l", 'Ł': "L", 'þ': "th", 'Þ': "TH", 'ð': "d", 'Ð': "D", 'ı': "i",
+}
+
+// cyrillic is the romanization of Russian, used for other languages written in Cyrillic unless
+// their locale overrides some letters.
+var cyrillic = map[rune]string{
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "e", 'ж': "zh", 'з': "z",
+	'и': "i", 'й': "y", 'к': "k", 'л': "l", 'м': "m", 'н': "n", 'о': "o", 'п': "p", 'р': "r",
+	'с': "s", 'т': "t", 'у': "u", 'ф': "f", 'х': "kh", 'ц': "ts", 'ч': "ch", 'ш': "sh", 'щ': "shch",
+	'ъ': "", 'ы': "y", 'ь': "", 'э': "e", 'ю': "yu", 'я': "ya",
+	'є': "ye", 'і': "i", 'ї': "yi", 'ґ': "g", 'ў': "w", 'ј': "j", 'љ': "lj", 'њ': "nj", 'џ': "dz",
+	'ђ': "dj", 'ћ': "c", 'ѕ': "dz", 'ќ': "kj", 'ѓ': "gj",
+}
+
+var greek = map[rune]string{
+	'α': "a", 'β': "v", 'γ': "g", 'δ': "d", 'ε': "e", 'ζ': "z", 'η': "i", 'θ': "th", 'ι': "i",
+	'κ': "k", 'λ': "l", 'μ': "m", 'ν': "n", 'ξ': "x", 'ο': "o", 'π': "p", 'ρ': "r", 'σ': "s",
+	'ς': "s", 'τ': "t", 'υ': "y", 'φ': "f", 'χ': "ch", 'ψ': "ps", 'ω': "o",
+}
+
+// locales override the spelling of letters for a language, e.g. German spells out ä as ae rather
+// than dropping the umlaut, and Ukrainian romanizes г as h.
+var locales = map[string]map[rune]string{
+	"de": {'ä': "ae", 'ö': "oe", 'ü': "ue", 'Ä': "Ae", 'Ö': "Oe", 'Ü': "Ue"},
+	"uk": {'г': "h", 'и': "y", 'й': "i", 'є': "ie", 'ї': "i"},
+	"bg": {'щ': "sht", 'ъ': "a", 'ю': "yu", 'я': "ya"},
+	"sr": {'ж': "z", 'ц': "c", 'ч': "c", 'ш': "s", 'х': "h"},
+}
+
+// kana romanizes hiragana, katakana being mapped to hiragana first. Small ya, yu and yo combine
+// with the syllable before them, and the small tsu doubles the consonant after it.
+var kana = map[rune]string{
+	'あ': "a", 'い': "i", 'う': "u", 'え': "e", 'お': "o",
+	'か': "ka", 'き': "ki", 'く': "ku", 'け': "ke", 'こ': "ko", 'が': "ga", 'ぎ': "gi", 'ぐ': "gu", 'げ': "ge", 'ご': "go",
+	'さ': "sa", 'し': "shi", 'す': "su", 'せ': "se", 'そ': "so", 'ざ': "za", 'じ': "ji", 'ず': "zu", 'ぜ': "ze", 'ぞ': "zo",
+	'た': "ta", 'ち': "chi", 'つ': "tsu", 'て': "te", 'と': "to", 'だ': "da", 'ぢ': "ji", 'づ': "zu", 'で': "de", 'ど': "do",
+	'な': "na", 'に': "ni", 'ぬ': "nu", 'ね': "ne", 'の': "no",
+	'は': "ha", 'ひ': "hi", 'ふ': "fu", 'へ': "he", 'ほ': "ho", 'ば': "ba", 'び': "bi", 'ぶ': "bu", 'べ': "be", 'ぼ': "bo",
+	'ぱ': "pa", 'ぴ': "pi", 'ぷ': "pu", 'ぺ': "pe", 'ぽ': "po",
+	'ま': "ma", 'み': "mi", 'む': "mu", 'め': "me", 'も': "mo",
+	'や': "ya", 'ゆ': "yu", 'よ': "yo",
+	'ら': "ra", 'り': "ri", 'る': "ru", 'れ': "re", 'ろ': "ro",
+	'わ': "wa", 'ゐ': "i", 'ゑ': "e", 'を': "o", 'ん': "n", 'ゔ': "vu",
+	'ぁ': "a", 'ぃ': "i", 'ぅ': "u", 'ぇ': "e", 'ぉ': "o",
+}
+
+// smallKana are the small ya, yu and yo, which replace the vowel of the syllable before them.
+var smallKana = map[rune]string{'ゃ': "a", 'ゅ': "u", 'ょ': "o"}
+
+// hangul romanizes the initial consonants, vowels and final consonants Hangul syllables are
+// composed of, following the Revised Romanization of Korean.
+var hangul = struct{ initials, vowels, finals []string }{
+	initials: []string{"g", "kk", "n", "d", "tt", "r", "m", "b", "pp", "s", "ss", "", "j", "jj", "ch", "k", "t", "p", "h"},
+	vowels:   []string{"a", "ae", "ya", "yae", "eo", "e", "yeo", "ye", "o", "wa", "wae", "oe", "yo", "u", "wo", "we", "wi", "yu", "eu", "ui", "i"},
+	finals:   []string{"", "k", "k", "k", "n", "n", "n", "t", "l", "k", "m", "l", "l", "l", "p", "l", "m", "p", "p", "t", "t", "ng", "t", "t", "k", "t", "p", "t"},
+}
+
+const (
+	hangulFirst = 0xAC00
+	hangulLast  = 0xD7A3
+)
+
+// transliterate spells out the letters of other scripts than Latin with ASCII letters, so that
+// names written in Cyrillic, Greek, Japanese kana or Korean keep a readable name. Han characters
+// have no single reading and are replaced with their code point, e.g. 中 with u4e2d, which keeps
+// names distinct. The spelling of some letters depends on the language given with --name-locale.
+func transliterate(s string) string {
+	overrides := locales[strings.ToLower(config.NameLocale.Value())]
+	var b strings.Builder
+	double := false
+	for _, r := range s {
+		out, ok := spell(r, overrides)
+		switch {
+		case r == 'っ' || r == 'ッ':
+			double = true
+			continue
+		case smallKana[toHiragana(r)] != "":
+			// きゃ is kya, しゃ is sha
+			prev := strings.TrimSuffix(b.String(), "i")
+			if !strings.HasSuffix(prev, "sh") && !strings.HasSuffix(prev, "ch") && !strings.HasSuffix(prev, "j") {
+				prev += "y"
+			}
+			b.Reset()
+			b.WriteString(prev + smallKana[toHiragana(r)])
+			continue
+		case !ok:
+			out = string(r)
+		}
+		if double && out != "" && !strings.ContainsRune("aeiou", rune(out[0])) {
+			out = out[:1] + out
+		}
+		double = false
+		b.WriteString(out)
+	}
+	return b.String()
+}
+
+// spell returns the ASCII spelling of r, and false when r is left as is.
+func spell(r rune, overrides map[rune]string) (string, bool) {
+	lower := unicode.ToLower(r)
+	upper := r != lower
+	out, ok := overrides[r]
+	if !ok {
+		out, ok = overrides[lower]
+	}
+	if !ok {
+		out, ok = latin[r]
+	}
+	if !ok {
+		out, ok = cyrillic[lower]
+	}
+	if !ok {
+		out, ok = greek[base(lower)]
+	}
+	if ok {
+		if upper && out != "" && unicode.IsLower(rune(out[0])) {
+			out = strings.ToUpper(out[:1]) + out[1:]
+		}
+		return out, true
+	}
+	if out, ok := kana[toHiragana(r)]; ok {
+		return out, true
+	}
+	if r >= hangulFirst && r <= hangulLast {
+		i := int(r - hangulFirst)
+		initial, vowel, final := i/(21*28), i%(21*28)/28, i%28
+		return hangul.initials[initial] + hangul.vowels[vowel] + hangul.finals[final], true
+	}
+	if unicode.Is(unicode.Han, r) {
+		return fmt.Sprintf("u%x", r), true
+	}
+	return "", false
+}
+
+// base returns the letter r decomposes into without its marks, e.g. α for ά.
+func base(r rune) rune {
+	for _, d := range norm.NFD.String(string(r)) {
+		return d
+	}
+	return r
+}
+
+// toHiragana maps katakana to the hiragana of the same syllable.
+func toHiragana(r rune) rune {
+	if r >= 'ァ' && r <= 'ヶ' {
+		return r - 0x60
+	}
+	return r
+}
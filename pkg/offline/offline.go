@@ -0,0 +1,214 @@
+// Package offline reads the inventory exports import files are built from with --from-inventory,
+// for environments where the programs aren't allowed to call the cloud: an AWS Config snapshot, an
+// Azure Resource Graph export in JSON or CSV, or a dump of kubectl get -o json. Exports are read
+// gzipped or not, like AWS Config delivers its snapshots.
+//
+//	go run . --import --from-inventory config-snapshot.json.gz
+//
+// Only import files are built from exports: reading resources into a stack calls the cloud
+// through the provider anyway.
+package offline
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/pulumi/pulumi-cloud-import/pkg/compress"
+	"github.com/pulumi/pulumi-cloud-import/pkg/config"
+)
+
+// Path returns the export passed with --from-inventory, "" when resources are discovered in the
+// cloud.
+func Path() string {
+	return config.FromInventory.Value()
+}
+
+// Check panics when --from-inventory is passed without --import.
+func Check(importMode bool) {
+	if Path() != "" && !importMode {
+		panic("--from-inventory builds an import file, pass --import")
+	}
+}
+
+// ConfigItem is a resource of an AWS Config snapshot.
+type ConfigItem struct {
+	ResourceType string            `json:"resourceType"`
+	ResourceID   string            `json:"resourceId"`
+	ResourceName string            `json:"resourceName"`
+	ARN          string            `json:"ARN"`
+	Region       string            `json:"awsRegion"`
+	AccountID    string            `json:"awsAccountId"`
+	Status       string            `json:"configurationItemStatus"`
+	CreationTime string            `json:"resourceCreationTime"`
+	Tags         map[string]string `json:"tags"`
+}
+
+// Created returns the creation time of the resource, zero when AWS Config didn't record it.
+func (item ConfigItem) Created() time.Time {
+	t, _ := time.Parse(time.RFC3339, item.CreationTime)
+	return t
+}
+
+// deletedStatuses are the statuses of the configuration items of resources that are gone.
+var deletedStatuses = map[string]bool{
+	"ResourceDeleted":            true,
+	"ResourceDeletedNotRecorded": true,
+}
+
+// ReadAWSConfig returns the resources of the AWS Config snapshot at path, as delivered to S3 or
+// downloaded with aws configservice deliver-config-snapshot. Deleted resources are left out.
+func ReadAWSConfig(path string) ([]ConfigItem, error) {
+	b, err := compress.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var snapshot struct {
+		ConfigurationItems []ConfigItem `json:"configurationItems"`
+	}
+	if err := json.Unmarshal(b, &snapshot); err != nil {
+		return nil, fmt.Errorf("%s isn't an AWS Config snapshot: %w", path, err)
+	}
+	if snapshot.ConfigurationItems == nil {
+		return nil, fmt.Errorf("%s isn't an AWS Config snapshot: no configurationItems", path)
+	}
+	items := []ConfigItem{}
+	for _, item := range snapshot.ConfigurationItems {
+		if !deletedStatuses[item.Status] {
+			items = append(items, item)
+		}
+	}
+	return items, nil
+}
+
+// GraphResource is a resource of an Azure Resource Graph export. Type is the ARM type, which Resource
+// Graph returns lower-cased.
+type GraphResource struct {
+	ID         string            `json:"id"`
+	Type       string            `json:"type"`
+	Name       string            `json:"name"`
+	Location   string            `json:"location"`
+	Tags       map[string]string `json:"tags"`
+	Properties interface{}       `json:"properties"`
+}
+
+// ReadResourceGraph returns the resources of the Azure Resource Graph export at path: the JSON of az
+// graph query, the rows alone, or the CSV the portal downloads. Queries should project id and type,
+// and name, location, tags and properties for what's derived from them.
+func ReadResourceGraph(path string) ([]GraphResource, error) {
+	b, err := compress.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	resources := []GraphResource{}
+	switch trimmed := bytes.TrimSpace(b); {
+	case bytes.HasPrefix(trimmed, []byte("[")):
+		err = json.Unmarshal(trimmed, &resources)
+	case bytes.HasPrefix(trimmed, []byte("{")):
+		// az graph query wraps the rows
+		var result struct {
+			Data []GraphResource `json:"data"`
+		}
+		err = json.Unmarshal(trimmed, &result)
+		resources = result.Data
+	default:
+		resources, err = readGraphCSV(b)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s isn't an Azure Resource Graph export: %w", path, err)
+	}
+	for _, r := range resources {
+		if r.ID == "" || r.Type == "" {
+			return nil, fmt.Errorf("%s isn't an Azure Resource Graph export: resources have no id or type", path)
+		}
+	}
+	return resources, nil
+}
+
+// readGraphCSV reads the rows of a CSV export, whose columns are named after the projected fields in
+// any case. Tags and properties are JSON.
+func readGraphCSV(b []byte) ([]GraphResource, error) {
+	rows, err := csv.NewReader(bytes.NewReader(b)).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, errors.New("empty CSV")
+	}
+	columns := map[string]int{}
+	for i, name := range rows[0] {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if _, ok := columns["id"]; !ok {
+		return nil, errors.New("no id column")
+	}
+	field := func(row []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(row) {
+			return row[i]
+		}
+		return ""
+	}
+	resources := []GraphResource{}
+	for _, row := range rows[1:] {
+		r := GraphResource{
+			ID:       field(row, "id"),
+			Type:     field(row, "type"),
+			Name:     field(row, "name"),
+			Location: field(row, "location"),
+		}
+		if tags := field(row, "tags"); tags != "" {
+			_ = json.Unmarshal([]byte(tags), &r.Tags)
+		}
+		if properties := field(row, "properties"); properties != "" {
+			_ = json.Unmarshal([]byte(properties), &r.Properties)
+		}
+		resources = append(resources, r)
+	}
+	return resources, nil
+}
+
+// ReadKubectl returns the objects of the dump of kubectl get -o json at path: a list, a single
+// object, or several of them one after the other, e.g. the output of several kubectl invocations.
+func ReadKubectl(path string) ([]map[string]interface{}, error) {
+	b, err := compress.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	objects := []map[string]interface{}{}
+	decoder := json.NewDecoder(bytes.NewReader(b))
+	for {
+		var object map[string]interface{}
+		if err := decoder.Decode(&object); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("%s isn't a kubectl get -o json dump: %w", path, err)
+		}
+		objects = append(objects, listItems(object)...)
+	}
+	for _, object := range objects {
+		if object["apiVersion"] == nil || object["kind"] == nil {
+			return nil, fmt.Errorf("%s isn't a kubectl get -o json dump: objects have no apiVersion or kind", path)
+		}
+	}
+	return objects, nil
+}
+
+// listItems returns the items of a list, nested lists included, and object itself otherwise.
+func listItems(object map[string]interface{}) []map[string]interface{} {
+	items, ok := object["items"].([]interface{})
+	if !ok || !strings.HasSuffix(fmt.Sprint(object["kind"]), "List") {
+		return []map[string]interface{}{object}
+	}
+	objects := []map[string]interface{}{}
+	for _, item := range items {
+		if item, ok := item.(map[string]interface{}); ok {
+			objects = append(objects, listItems(item)...)
+		}
+	}
+	return objects
+}
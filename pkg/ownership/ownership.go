@@ -0,0 +1,155 @@
+// Package ownership classifies discovered resources as likely managed by an infrastructure as code
+// tool or as likely unmanaged, from the tags, labels and annotations the tools leave behind, like
+// the aws:cloudformation:stack-name tag or the app.kubernetes.io/managed-by label.
+//
+// Pass --ownership to write the classification of every resource to ownership.json and print the
+// split. Most of the time only the unmanaged resources are worth importing.
+package ownership
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pulumi/pulumi-cloud-import/pkg/config"
+)
+
+// Path is where the classification is written to.
+const Path = "ownership.json"
+
+// Unmanaged is the owner of resources nothing points to a tool for.
+const Unmanaged = "unmanaged"
+
+// Entry is the classification of a discovered resource. Evidence is the tag, label or annotation
+// the owner was derived from.
+type Entry struct {
+	Type     string `json:"type"`
+	Name     string `json:"name"`
+	ID       string `json:"id"`
+	Owner    string `json:"owner"`
+	Evidence string `json:"evidence,omitempty"`
+}
+
+// keyPrefixes maps the prefixes of tags, labels and annotations set by tools to the tool.
+var keyPrefixes = []struct {
+	prefix string
+	owner  string
+}{
+	{"aws:cloudformation:", "cloudformation"},
+	{"pulumi:", "pulumi"},
+	{"pulumi.com/", "pulumi"},
+	{"meta.helm.sh/", "helm"},
+	{"helm.sh/chart", "helm"},
+	{"argocd.argoproj.io/", "argocd"},
+	{"kustomize.toolkit.fluxcd.io/", "flux"},
+	{"helm.toolkit.fluxcd.io/", "flux"},
+	{"kubectl.kubernetes.io/last-applied-configuration", "kubectl"},
+	{"terraform", "terraform"},
+	{"tf-workspace", "terraform"},
+	{"tf_workspace", "terraform"},
+}
+
+// managedByKeys are keys whose value names the tool managing the resource.
+var managedByKeys = map[string]bool{
+	"app.kubernetes.io/managed-by": true,
+	"managed-by":                   true,
+	"managed_by":                   true,
+	"managedby":                    true,
+	"provisioner":                  true,
+	"iac":                          true,
+}
+
+// tools are the values of managedByKeys that name a known tool.
+var tools = []string{"cloudformation", "terraform", "pulumi", "helm", "bicep", "cdk", "crossplane"}
+
+// Classify returns the tool that likely manages a resource with the given tags, labels or
+// annotations, and the key it was derived from. The owner is Unmanaged when no key points to a tool.
+func Classify(tags map[string]string) (owner string, evidence string) {
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	// the first matching key wins, sorting keeps the result stable
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		key := strings.ToLower(k)
+		if managedByKeys[key] && tags[k] != "" {
+			value := strings.ToLower(tags[k])
+			for _, t := range tools {
+				if strings.Contains(value, t) {
+					return t, k
+				}
+			}
+			return value, k
+		}
+	}
+	for _, k := range keys {
+		key := strings.ToLower(k)
+		for _, p := range keyPrefixes {
+			if strings.HasPrefix(key, p.prefix) {
+				return p.owner, k
+			}
+		}
+	}
+	return Unmanaged, ""
+}
+
+var (
+	mu      sync.Mutex
+	entries []Entry
+)
+
+// Enabled reports whether --ownership was passed.
+func Enabled() bool {
+	return config.Ownership.Bool()
+}
+
+// Add records the classification of a discovered resource when --ownership is passed.
+func Add(typ, name, id, owner, evidence string) {
+	if !Enabled() {
+		return
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	entries = append(entries, Entry{Type: typ, Name: name, ID: id, Owner: owner, Evidence: evidence})
+}
+
+// Report writes the resources recorded with Add to Path and prints the number of resources per owner.
+func Report() error {
+	if !Enabled() {
+		return nil
+	}
+	mu.Lock()
+	defer mu.Unlock()
+
+	b, err := json.MarshalIndent(entries, "", "    ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(Path, b, 0644); err != nil {
+		return err
+	}
+
+	counts := map[string]int{}
+	for _, e := range entries {
+		counts[e.Owner]++
+	}
+	owners := make([]string, 0, len(counts))
+	for o := range counts {
+		if o != Unmanaged {
+			owners = append(owners, o)
+		}
+	}
+	sort.Slice(owners, func(i, j int) bool { return counts[owners[i]] > counts[owners[j]] })
+
+	fmt.Printf("%d of %d resources are likely unmanaged\n", counts[Unmanaged], len(entries))
+	for _, o := range owners {
+		fmt.Printf("  %d likely managed by %s\n", counts[o], o)
+	}
+	fmt.Printf("Ownership of every resource written to %s\n", Path)
+	return nil
+}
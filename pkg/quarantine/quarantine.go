@@ -0,0 +1,139 @@
+// Package quarantine reads and writes quarantine.json, the file resources that failed to be read or
+// imported are set aside in along with the errors reported for them. Quarantined resources can be
+// retried later, e.g. after upgrading a provider, with `pulumi-cloud-import-bulk retry-quarantine`.
+package quarantine
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi/internals"
+)
+
+// DefaultPath is where programs write quarantined resources unless told otherwise.
+const DefaultPath = "quarantine.json"
+
+const (
+	// StageRead marks resources that failed to be read with ReadResource.
+	StageRead = "read"
+	// StageImport marks resources that failed to be imported with `pulumi import`.
+	StageImport = "import"
+)
+
+// Entry is a quarantined resource. Resource is the spec as written to the import file.
+type Entry struct {
+	Resource map[string]interface{} `json:"resource"`
+	Stage    string                 `json:"stage"`
+	Error    string                 `json:"error"`
+	Attempts int                    `json:"attempts"`
+	Time     time.Time              `json:"time"`
+}
+
+// File is the content of quarantine.json. The name table lists the parents of quarantined
+// resources that were imported.
+type File struct {
+	NameTable map[string]resource.URN `json:"nameTable,omitempty"`
+	Resources []Entry                 `json:"resources"`
+}
+
+// NewEntry quarantines spec, any value marshalling to an import file resource.
+func NewEntry(spec interface{}, stage string, err string) Entry {
+	var r map[string]interface{}
+	if b, e := json.Marshal(spec); e == nil {
+		_ = json.Unmarshal(b, &r)
+	}
+	return Entry{Resource: r, Stage: stage, Error: err, Attempts: 1, Time: time.Now().UTC()}
+}
+
+// Read reads the quarantine file at path.
+func Read(path string) (*File, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var f File
+	if err := json.Unmarshal(b, &f); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return &f, nil
+}
+
+// Write writes f to path, or removes path when nothing is quarantined anymore.
+func Write(path string, f *File) error {
+	if len(f.Resources) == 0 {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	b, err := json.MarshalIndent(f, "", "    ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, b, 0644)
+}
+
+var (
+	mu      sync.Mutex
+	pending []Entry
+	// reads are the reads being watched
+	reads sync.WaitGroup
+)
+
+// Add quarantines spec for programs collecting failures while they run, see Save.
+func Add(spec interface{}, stage string, err error) {
+	mu.Lock()
+	defer mu.Unlock()
+	pending = append(pending, NewEntry(spec, stage, err.Error()))
+}
+
+// Watch quarantines spec if reading res fails. ReadResource returns before the provider reads the
+// resource, a failing read only rejecting the outputs of res, so the read is waited on in the
+// background. failed, if not nil, is called with the error of a failing read too.
+func Watch(spec interface{}, res pulumi.Resource, failed func(err error)) {
+	reads.Add(1)
+	go func() {
+		defer reads.Done()
+		if _, err := internals.UnsafeAwaitOutput(context.Background(), res.URN()); err != nil {
+			Add(spec, StageRead, err)
+			if failed != nil {
+				failed(err)
+			}
+		}
+	}()
+}
+
+// Wait waits for the reads watched with Watch to complete.
+func Wait() {
+	reads.Wait()
+}
+
+// Save appends the resources quarantined with Add to DefaultPath, once the reads watched completed.
+func Save() error {
+	Wait()
+	mu.Lock()
+	defer mu.Unlock()
+	if len(pending) == 0 {
+		return nil
+	}
+	f, err := Read(DefaultPath)
+	if os.IsNotExist(err) {
+		f, err = &File{}, nil
+	}
+	if err != nil {
+		return err
+	}
+	f.Resources = append(f.Resources, pending...)
+	if err := Write(DefaultPath, f); err != nil {
+		return err
+	}
+	fmt.Printf("%d resources failed and were written to %s\n", len(pending), DefaultPath)
+	pending = nil
+	return nil
+}
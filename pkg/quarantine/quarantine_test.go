@@ -0,0 +1,70 @@
+package quarantine
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+// failingReads fails the reads of the resources named "bad", like a provider failing to read them.
+type failingReads struct{}
+
+func (failingReads) NewResource(args pulumi.MockResourceArgs) (string, resource.PropertyMap, error) {
+	if args.Name == "bad" {
+		return "", nil, errors.New("HandlerInternalFailureException: the handler failed")
+	}
+	return args.ID, resource.PropertyMap{}, nil
+}
+
+func (failingReads) Call(args pulumi.MockCallArgs) (resource.PropertyMap, error) {
+	return resource.PropertyMap{}, nil
+}
+
+func TestWatch(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	type spec struct {
+		Type string `json:"type"`
+		Name string `json:"name"`
+		ID   string `json:"id"`
+	}
+	failed := []error{}
+	// the failing read fails the program, like it fails pulumi up
+	_ = pulumi.RunErr(func(ctx *pulumi.Context) error {
+		for _, name := range []string{"good", "bad"} {
+			r := spec{Type: "test:index:Resource", Name: name, ID: name + "-id"}
+			var res pulumi.CustomResourceState
+			if err := ctx.ReadResource(r.Type, r.Name, pulumi.ID(r.ID), nil, &res); err != nil {
+				t.Fatal(err)
+			}
+			Watch(r, &res, func(err error) { failed = append(failed, err) })
+		}
+		return Save()
+	}, pulumi.WithMocks("project", "stack", failingReads{}))
+
+	f, err := Read(DefaultPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(f.Resources) != 1 {
+		t.Fatalf("got %d quarantined resources, want 1: %+v", len(f.Resources), f.Resources)
+	}
+	e := f.Resources[0]
+	if e.Stage != StageRead || e.Resource["name"] != "bad" || !strings.Contains(e.Error, "HandlerInternalFailureException") {
+		t.Errorf("got %+v, want the read of bad", e)
+	}
+	if len(failed) != 1 {
+		t.Errorf("failed was called %d times, want 1", len(failed))
+	}
+}
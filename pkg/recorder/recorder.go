@@ -0,0 +1,195 @@
+// Package recorder saves the responses of cloud API calls to disk and replays them later, so that
+// naming and mapping bugs can be reproduced without access to the account they were reported against.
+//
+// Set PULUMI_CLOUD_IMPORT_RECORD=<dir> to record a run and PULUMI_CLOUD_IMPORT_REPLAY=<dir> to replay it.
+package recorder
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/pulumi/pulumi-cloud-import/pkg/config"
+)
+
+// entry is a single recorded response
+type entry struct {
+	Method     string      `json:"method"`
+	URL        string      `json:"url"`
+	StatusCode int         `json:"statusCode"`
+	Header     http.Header `json:"header"`
+	Body       string      `json:"body"`
+}
+
+// only headers SDKs need to decode a response are kept, everything else may carry request specific data
+var recordedHeaders = []string{"Content-Type", "Link", "X-Amzn-Query-Error"}
+
+func recordDir() string {
+	return config.Record.Value()
+}
+
+func replayDir() string {
+	return config.Replay.Value()
+}
+
+// Enabled reports whether responses are being recorded or replayed.
+func Enabled() bool {
+	return recordDir() != "" || replayDir() != ""
+}
+
+// Replaying reports whether responses are served from a recording, in which case importers should
+// not require real credentials.
+func Replaying() bool {
+	return replayDir() != ""
+}
+
+// Wrap returns a RoundTripper recording or replaying responses as configured in the environment, or
+// rt itself when neither is. A nil rt wraps http.DefaultTransport.
+func Wrap(rt http.RoundTripper) http.RoundTripper {
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+	if dir := replayDir(); dir != "" {
+		return &replayer{dir: dir}
+	}
+	if dir := recordDir(); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			panic(fmt.Sprintf("failed to create recording directory: %v", err))
+		}
+		return &recorder{dir: dir, next: rt}
+	}
+	return rt
+}
+
+// scopeKey is the context key of the scope of a request, see Scope.
+type scopeKey struct{}
+
+// HTTPClient is the client SDKs send their requests with, like aws.HTTPClient.
+type HTTPClient interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+type scopedClient struct {
+	next  HTTPClient
+	scope string
+}
+
+func (c scopedClient) Do(req *http.Request) (*http.Response, error) {
+	return c.next.Do(req.WithContext(context.WithValue(req.Context(), scopeKey{}, c.scope)))
+}
+
+// Scope returns client sending its requests in scope, e.g. one of the accounts of an organization,
+// whose requests are the same as those of the other accounts but for their credentials. Requests
+// are recorded and replayed per scope.
+func Scope(client HTTPClient, scope string) HTTPClient {
+	return scopedClient{next: client, scope: scope}
+}
+
+// key identifies a request independently of its host and headers, so that recordings can be replayed
+// against another region or cluster endpoint and without credentials, within its scope if it has
+// one.
+func key(req *http.Request) (string, error) {
+	h := sha256.New()
+	if scope, ok := req.Context().Value(scopeKey{}).(string); ok {
+		fmt.Fprintf(h, "%s\n", scope)
+	}
+	fmt.Fprintf(h, "%s %s?%s\n", req.Method, req.URL.Path, req.URL.RawQuery)
+	if req.Body != nil && req.Body != http.NoBody {
+		body, err := io.ReadAll(req.Body)
+		if err != nil {
+			return "", err
+		}
+		req.Body.Close()
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		h.Write(body)
+	}
+	return hex.EncodeToString(h.Sum(nil))[:32], nil
+}
+
+type recorder struct {
+	dir  string
+	next http.RoundTripper
+}
+
+func (r *recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	k, err := key(req)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := r.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	// throttling and server errors are transient and would make replays fail where the run succeeded
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+		return resp, nil
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	e := entry{
+		Method:     req.Method,
+		URL:        req.URL.String(),
+		StatusCode: resp.StatusCode,
+		Header:     http.Header{},
+		Body:       string(body),
+	}
+	for _, h := range recordedHeaders {
+		if v := resp.Header.Values(h); len(v) > 0 {
+			e.Header[h] = v
+		}
+	}
+	b, err := json.MarshalIndent(e, "", "    ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(r.dir, k+".json"), b, 0600); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+type replayer struct {
+	dir string
+}
+
+func (r *replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	k, err := key(req)
+	if err != nil {
+		return nil, err
+	}
+	b, err := os.ReadFile(filepath.Join(r.dir, k+".json"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("no recorded response for %s %s", req.Method, req.URL)
+		}
+		return nil, err
+	}
+	var e entry
+	if err := json.Unmarshal(b, &e); err != nil {
+		return nil, err
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", e.StatusCode, http.StatusText(e.StatusCode)),
+		StatusCode:    e.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        e.Header,
+		Body:          io.NopCloser(bytes.NewBufferString(e.Body)),
+		ContentLength: int64(len(e.Body)),
+		Request:       req,
+	}, nil
+}
@@ -0,0 +1,47 @@
+package recorder
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestScope records the same request in two accounts and replays the answer of each account.
+func TestScope(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the credentials of each account make the server answer differently
+		io.WriteString(w, r.Header.Get("Authorization"))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	send := func(rt http.RoundTripper, account string) string {
+		client := Scope(&http.Client{Transport: rt}, account)
+		req, err := http.NewRequest(http.MethodPost, server.URL+"/", strings.NewReader(`{"TypeName":"AWS::S3::Bucket"}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", "account "+account)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		b, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(b)
+	}
+
+	for _, account := range []string{"111111111111", "222222222222"} {
+		send(&recorder{dir: dir, next: http.DefaultTransport}, account)
+	}
+	for _, account := range []string{"111111111111", "222222222222"} {
+		if got, want := send(&replayer{dir: dir}, account), "account "+account; got != want {
+			t.Errorf("replayed %q for account %s, want %q", got, account, want)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+// Package redact hides sensitive identifiers in log output and reports when --redact is passed, so
+// that diagnostics can be shared with support. The import file always keeps the real IDs.
+//
+// Email addresses are always redacted. Additional regular expressions, one per line, can be listed
+// in the file named by PULUMI_CLOUD_IMPORT_REDACT_PATTERNS.
+package redact
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/pulumi/pulumi-cloud-import/pkg/config"
+)
+
+var emailRegex = regexp.MustCompile(`[a-zA-Z0-9._%+\-]+@[a-zA-Z0-9.\-]+\.[a-zA-Z]{2,}`)
+
+var (
+	once     sync.Once
+	enabled  bool
+	patterns []*regexp.Regexp
+)
+
+func load() {
+	once.Do(func() {
+		// support bundles are meant to be shared
+		enabled = config.Redact.Bool() || config.SupportBundle.Bool()
+		if !enabled {
+			return
+		}
+
+		patterns = []*regexp.Regexp{emailRegex}
+		path := config.RedactPatterns.Value()
+		if path == "" {
+			return
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			panic(fmt.Sprintf("failed to read redaction patterns: %v", err))
+		}
+		defer f.Close()
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			re, err := regexp.Compile(line)
+			if err != nil {
+				panic(fmt.Sprintf("invalid redaction pattern %q: %v", line, err))
+			}
+			patterns = append(patterns, re)
+		}
+		if err := scanner.Err(); err != nil {
+			panic(fmt.Sprintf("failed to read redaction patterns: %v", err))
+		}
+	})
+}
+
+// Enabled reports whether --redact, or --support-bundle, was passed.
+func Enabled() bool {
+	load()
+	return enabled
+}
+
+// String replaces every match of the redaction patterns with a short hash of the match. The same
+// value always hashes the same way, so redacted output can still be correlated.
+func String(s string) string {
+	load()
+	if !enabled {
+		return s
+	}
+	for _, re := range patterns {
+		s = re.ReplaceAllStringFunc(s, hash)
+	}
+	return s
+}
+
+func hash(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return "redacted-" + hex.EncodeToString(sum[:])[:8]
+}
+
+// Println is fmt.Println with the output redacted.
+func Println(a ...any) {
+	fmt.Print(String(fmt.Sprintln(a...)))
+}
+
+// Printf is fmt.Printf with the output redacted.
+func Printf(format string, a ...any) {
+	fmt.Print(String(fmt.Sprintf(format, a...)))
+}
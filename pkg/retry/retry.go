@@ -0,0 +1,189 @@
+// Package retry retries the requests of importers and of the schema and metadata downloads the same
+// way: failed attempts are retried after an exponentially growing delay, capped and jittered so that
+// workers throttled together don't retry together, and servers asking for a delay with Retry-After
+// are waited on that long.
+//
+// Errors fall into classes with a policy each. Throttled requests are retried the most and the most
+// patiently, as waiting always gets them through eventually, transient failures like timeouts and
+// 5xx answers a few times, and other errors, like denied or malformed requests, not at all.
+package retry
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Class is how an error is retried.
+type Class int
+
+const (
+	// Permanent errors aren't retried.
+	Permanent Class = iota
+	// Transient errors, like connection failures and 5xx answers, are retried a few times.
+	Transient
+	// Throttled errors are retried until the cloud API lets requests through.
+	Throttled
+)
+
+// Policy is how the failed attempts of an operation are retried.
+type Policy struct {
+	// Attempts is how many times an operation is attempted in all.
+	Attempts int
+	// Base is the delay before the first retry, doubled for every retry after it.
+	Base time.Duration
+	// Max caps the delay between attempts.
+	Max time.Duration
+}
+
+var (
+	// Transients is the policy of transient errors.
+	Transients = Policy{Attempts: 3, Base: 2 * time.Second, Max: 30 * time.Second}
+	// Throttles is the policy of throttled errors.
+	Throttles = Policy{Attempts: 10, Base: time.Second, Max: 5 * time.Minute}
+)
+
+// For returns the policy of class, a single attempt for permanent errors.
+func For(class Class) Policy {
+	switch class {
+	case Transient:
+		return Transients
+	case Throttled:
+		return Throttles
+	}
+	return Policy{Attempts: 1}
+}
+
+// Delay returns the delay before retrying the attempt-th failed attempt: Base doubled for every
+// retry before it, capped to Max, with full jitter on its upper half.
+func (p Policy) Delay(attempt int) time.Duration {
+	d := p.Max
+	if attempt < 1 {
+		attempt = 1
+	}
+	// shifting past 32 overflows long before any delay gets there
+	if attempt <= 32 {
+		if e := p.Base << (attempt - 1); e > 0 && e < p.Max {
+			d = e
+		}
+	}
+	if d <= 0 {
+		return 0
+	}
+	half := d / 2
+	return half + time.Duration(rand.Int63n(int64(d-half)+1))
+}
+
+// Error is an error classified for retries, with the delay the server asked for, if any.
+type Error struct {
+	Class Class
+	// After is the delay the server asked for with Retry-After, zero if it didn't.
+	After time.Duration
+	Err   error
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Classify wraps err in the class it's retried with. Errors not classified are transient.
+func Classify(err error, class Class, after time.Duration) error {
+	if err == nil {
+		return nil
+	}
+	return &Error{Class: class, After: after, Err: err}
+}
+
+// Stop marks err as not worth retrying.
+func Stop(err error) error {
+	return Classify(err, Permanent, 0)
+}
+
+// ClassOf returns the class of err and the delay the server asked for.
+func ClassOf(err error) (Class, time.Duration) {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Class, e.After
+	}
+	return Transient, 0
+}
+
+// Status classifies an HTTP answer by its status code and Retry-After header: 429 as throttled, 5xx
+// and 408 as transient, anything else as permanent.
+func Status(code int, header http.Header) (Class, time.Duration) {
+	after := RetryAfter(header)
+	switch {
+	case code == http.StatusTooManyRequests:
+		return Throttled, after
+	case code >= 500 || code == http.StatusRequestTimeout:
+		return Transient, after
+	}
+	return Permanent, 0
+}
+
+// RetryAfter returns the delay the Retry-After header of an answer asks for, given as seconds or as
+// an HTTP date, and zero if there's none.
+func RetryAfter(header http.Header) time.Duration {
+	value := header.Get("Retry-After")
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
+// Do calls op until it succeeds, retrying its errors with the policy of their class. notify, if not
+// nil, is called before waiting for each retry. The error of the last attempt is returned, unwrapped
+// of its class.
+func Do(ctx context.Context, op func() error, notify func(err error, class Class, delay time.Duration)) error {
+	attempts := map[Class]int{}
+	for {
+		err := op()
+		if err == nil {
+			return nil
+		}
+		class, after := ClassOf(err)
+		attempts[class]++
+		policy := For(class)
+		if attempts[class] >= policy.Attempts {
+			return unwrap(err)
+		}
+		delay := policy.Delay(attempts[class])
+		if after > delay {
+			delay = after
+		}
+		if notify != nil {
+			notify(unwrap(err), class, delay)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+func unwrap(err error) error {
+	var e *Error
+	if errors.As(err, &e) && e == err {
+		return e.Err
+	}
+	return err
+}
@@ -0,0 +1,77 @@
+package shard
+
+import (
+	"sort"
+
+	"github.com/pulumi/pulumi-cloud-import/pkg/stackstate"
+)
+
+// Order sorts resources so that every resource comes after its parent and the resources it depends
+// on, which `pulumi import` would otherwise wait for or fail on when it imports several resources
+// at once. Resources are grouped by depth, those without a parent or dependency in the file coming
+// first, and by type, name and ID within a depth, so that the file is the same from one scan to the
+// next however concurrently resources were listed. References forming a cycle are ignored where the
+// cycle closes.
+func Order[T any](resources []T, ref func(T) stackstate.Ref) []T {
+	specs := make([]stackstate.Ref, len(resources))
+	index := map[string]int{}
+	for i, r := range resources {
+		specs[i] = ref(r)
+		index[specs[i].Name] = i
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	states := make([]int, len(resources))
+	depths := make([]int, len(resources))
+	var depth func(i int) int
+	depth = func(i int) int {
+		switch states[i] {
+		case visiting:
+			return -1
+		case visited:
+			return depths[i]
+		}
+		states[i] = visiting
+		d := 0
+		for _, name := range append([]string{specs[i].Parent}, specs[i].Dependencies...) {
+			// references to resources missing from the file, e.g. in the name table, don't count
+			if j, ok := index[name]; ok && j != i {
+				if dj := depth(j) + 1; dj > d {
+					d = dj
+				}
+			}
+		}
+		states[i], depths[i] = visited, d
+		return d
+	}
+	for i := range resources {
+		depth(i)
+	}
+
+	order := make([]int, len(resources))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		i, j := order[a], order[b]
+		if depths[i] != depths[j] {
+			return depths[i] < depths[j]
+		}
+		if specs[i].Type != specs[j].Type {
+			return specs[i].Type < specs[j].Type
+		}
+		if specs[i].Name != specs[j].Name {
+			return specs[i].Name < specs[j].Name
+		}
+		return specs[i].ID < specs[j].ID
+	})
+	ordered := make([]T, len(resources))
+	for k, i := range order {
+		ordered[k] = resources[i]
+	}
+	return ordered
+}
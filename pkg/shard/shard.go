@@ -0,0 +1,139 @@
+// Package shard splits an import file into several, so that they can be imported by parallel
+// `pulumi import` runs. Pass --shards to write import-1.json, import-2.json and so on instead of
+// import.json:
+//
+//	go run . --import --shards 4
+//
+// Shards hold roughly the same estimated import time rather than the same number of resources.
+// The time a resource takes to import is estimated from the time it took to list the resources of
+// its type during discovery, so that a shard of slow types holds fewer resources. A resource is
+// always in the same shard as its parent.
+//
+// Whether sharded or not, resources are ordered so that parents and dependencies come before the
+// resources that need them, see Order.
+package shard
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pulumi/pulumi-cloud-import/pkg/config"
+	"github.com/pulumi/pulumi-cloud-import/pkg/events"
+	"github.com/pulumi/pulumi-cloud-import/pkg/stackstate"
+)
+
+// Split orders resources and splits them into the number of shards given with --shards, or
+// returns them as a single shard when it isn't passed. Resources keep their order within a shard.
+func Split[T any](resources []T, ref func(T) stackstate.Ref) [][]T {
+	resources = Order(resources, ref)
+	n := config.Shards.Int()
+	if n <= 1 || len(resources) == 0 {
+		return [][]T{resources}
+	}
+
+	// resources are grouped with their parent, transitively
+	parents := map[string]string{}
+	for _, r := range resources {
+		spec := ref(r)
+		parents[spec.Name] = spec.Parent
+	}
+	root := func(name string) string {
+		seen := map[string]bool{}
+		for !seen[name] {
+			seen[name] = true
+			parent, ok := parents[name]
+			if !ok || parent == "" {
+				break
+			}
+			if _, ok := parents[parent]; !ok {
+				// the parent is in the name table, not in the file
+				break
+			}
+			name = parent
+		}
+		return name
+	}
+
+	costs := estimate(resources, ref)
+	groups := map[string]time.Duration{}
+	roots := make([]string, len(resources))
+	for i, r := range resources {
+		roots[i] = root(ref(r).Name)
+		groups[roots[i]] += costs[i]
+	}
+
+	// the most expensive groups go first to the shard with the least work, which keeps shards even
+	names := make([]string, 0, len(groups))
+	for name := range groups {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if groups[names[i]] != groups[names[j]] {
+			return groups[names[i]] > groups[names[j]]
+		}
+		return names[i] < names[j]
+	})
+	if n > len(names) {
+		n = len(names)
+	}
+	loads := make([]time.Duration, n)
+	assigned := map[string]int{}
+	for _, name := range names {
+		least := 0
+		for i := range loads {
+			if loads[i] < loads[least] {
+				least = i
+			}
+		}
+		assigned[name] = least
+		loads[least] += groups[name]
+	}
+
+	shards := make([][]T, n)
+	for i, r := range resources {
+		s := assigned[roots[i]]
+		shards[s] = append(shards[s], r)
+	}
+	for i, load := range loads {
+		fmt.Printf("shard %d: %d resources, estimated at %s\n", i+1, len(shards[i]), load.Round(time.Millisecond))
+	}
+	return shards
+}
+
+// estimate returns the estimated import time of each resource. Types whose latency wasn't measured
+// are estimated at the average of the measured ones, and every resource costs the same when none
+// was measured.
+func estimate[T any](resources []T, ref func(T) stackstate.Ref) []time.Duration {
+	latencies := events.Latencies()
+	var fallback time.Duration = time.Millisecond
+	if len(latencies) > 0 {
+		var total time.Duration
+		for _, l := range latencies {
+			total += l
+		}
+		fallback = total / time.Duration(len(latencies))
+	}
+
+	costs := make([]time.Duration, len(resources))
+	for i, r := range resources {
+		if l, ok := latencies[ref(r).Type]; ok && l > 0 {
+			costs[i] = l
+		} else {
+			costs[i] = fallback
+		}
+	}
+	return costs
+}
+
+// Path returns the path shard i of n is written to, e.g. import-2.json for the second shard of
+// import.json. A single shard is written to path itself.
+func Path(path string, i, n int) string {
+	if n <= 1 {
+		return path
+	}
+	ext := filepath.Ext(path)
+	return fmt.Sprintf("%s-%d%s", strings.TrimSuffix(path, ext), i+1, ext)
+}
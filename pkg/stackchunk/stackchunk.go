@@ -0,0 +1,160 @@
+// Package stackchunk splits the resources read in ReadMode into several stacks, as stacks of tens of
+// thousands of resources are slow to refresh, preview and update. Pass --max-stack-resources to read
+// at most that many resources into the stack, and the others into additional stacks named after it,
+// dev-2, dev-3 and so on, which are created with the Automation API and updated by the run of the
+// stack itself:
+//
+//	PULUMI_CLOUD_IMPORT_MAX_STACK_RESOURCES=5000 pulumi up -s dev
+//
+// The additional stacks run the same program with --stack-chunk set to the chunk they read. Rather
+// than scan again, they replay the responses recorded by the scan of the first stack, see
+// pkg/recorder, so that every stack splits the same resources the same way. How resources are split
+// is up to hierarchy.Reader. Additional stacks no longer needed, as resources went away, are updated
+// to read nothing.
+package stackchunk
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/pulumi/pulumi-cloud-import/pkg/config"
+	"github.com/pulumi/pulumi-cloud-import/pkg/recorder"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+// recording is the directory the scan is recorded to when it was recorded for the additional stacks
+// only, and is removed once they were updated.
+var recording string
+
+// Enabled reports whether the resources read are split into several stacks.
+func Enabled() bool {
+	return config.MaxStackResources.Int() > 0
+}
+
+// Additional reports whether the run reads an additional stack, rather than the stack the resources
+// are split from.
+func Additional() bool {
+	return config.StackChunk.Int() > 1
+}
+
+// Record records the responses of the scan for the additional stacks to replay, unless they are
+// already recorded or replayed. Call it in ReadMode before wrapping the transport with recorder.Wrap.
+func Record() {
+	if !Enabled() || Additional() || recorder.Enabled() {
+		return
+	}
+	dir, err := os.MkdirTemp("", "pulumi-cloud-import-")
+	if err != nil {
+		panic(fmt.Sprintf("failed to create recording directory: %v", err))
+	}
+	if err := os.Setenv(config.Record.Env(), dir); err != nil {
+		panic(fmt.Sprintf("failed to record the scan: %v", err))
+	}
+	recording = dir
+}
+
+// Name returns the name of the stack chunk is read into, counting from 1 for stack itself.
+func Name(stack string, chunk int) string {
+	if chunk <= 1 {
+		return stack
+	}
+	return fmt.Sprintf("%s-%d", stack, chunk)
+}
+
+// Rotate updates the additional stacks with the chunks, as returned by hierarchy.Reader.Chunks, the
+// stack of ctx doesn't read, creating the stacks that don't exist yet with the configuration of the
+// stack of ctx. Additional stacks left over from earlier scans are updated to read nothing. Nothing
+// is updated in previews or in the runs of additional stacks.
+func Rotate(ctx *pulumi.Context, chunks []int) error {
+	if !Enabled() || Additional() {
+		return nil
+	}
+	if recording != "" {
+		defer os.RemoveAll(recording)
+	}
+	if ctx.DryRun() {
+		if len(chunks) > 1 {
+			fmt.Printf("%d more resources will be read into %d additional stacks, %s to %s\n",
+				sum(chunks[1:]), len(chunks)-1, Name(ctx.Stack(), 2), Name(ctx.Stack(), len(chunks)))
+		}
+		return nil
+	}
+
+	dir, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	ws, err := auto.NewLocalWorkspace(ctx.Context(), auto.WorkDir(dir))
+	if err != nil {
+		return err
+	}
+	values, err := ws.GetAllConfig(ctx.Context(), ctx.Stack())
+	if err != nil {
+		return err
+	}
+	last := len(chunks)
+	stacks, err := ws.ListStacks(ctx.Context())
+	if err != nil {
+		return err
+	}
+	for _, s := range stacks {
+		if n := chunkOf(ctx.Stack(), s.Name); n > last {
+			last = n
+		}
+	}
+
+	replay := config.Replay.Value()
+	if replay == "" {
+		replay = config.Record.Value()
+	}
+	for chunk := 2; chunk <= last; chunk++ {
+		name := Name(ctx.Stack(), chunk)
+		env := map[string]string{
+			config.StackChunk.Env(): strconv.Itoa(chunk),
+			config.Replay.Env():     replay,
+			config.Record.Env():     "",
+		}
+		stack, err := auto.UpsertStackLocalSource(ctx.Context(), name, dir, auto.EnvVars(env))
+		if err != nil {
+			return fmt.Errorf("failed to create stack %s: %w", name, err)
+		}
+		if err := stack.SetAllConfig(ctx.Context(), values); err != nil {
+			return fmt.Errorf("failed to configure stack %s: %w", name, err)
+		}
+		if _, err := stack.Up(ctx.Context()); err != nil {
+			return fmt.Errorf("failed to update stack %s: %w", name, err)
+		}
+		if chunk > len(chunks) {
+			fmt.Printf("stack %s is no longer needed and reads nothing, it can be removed\n", name)
+			continue
+		}
+		fmt.Printf("read %d resources into stack %s\n", chunks[chunk-1], name)
+	}
+	return nil
+}
+
+// chunkOf returns the chunk the stack named name reads when it's an additional stack of stack, as
+// listed by `pulumi stack ls` with or without its organization and project, and 0 otherwise.
+func chunkOf(stack, name string) int {
+	name = name[strings.LastIndex(name, "/")+1:]
+	suffix := strings.TrimPrefix(name, stack+"-")
+	if suffix == name {
+		return 0
+	}
+	n, err := strconv.Atoi(suffix)
+	if err != nil || n < 2 {
+		return 0
+	}
+	return n
+}
+
+func sum(chunks []int) int {
+	n := 0
+	for _, c := range chunks {
+		n += c
+	}
+	return n
+}
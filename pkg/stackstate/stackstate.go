@@ -0,0 +1,130 @@
+// Package stackstate reads the resources already present in a stack, so that re-running an import
+// doesn't emit resources the stack already manages and `pulumi import` fails with "resource
+// already exists".
+package stackstate
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+
+	"github.com/pulumi/pulumi-cloud-import/pkg/config"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+)
+
+// State is the set of resources in a stack, their URNs by type and ID.
+type State struct {
+	resources map[string]resource.URN
+	urns      []resource.URN
+}
+
+type export struct {
+	Deployment struct {
+		Resources []struct {
+			URN  resource.URN `json:"urn"`
+			Type string       `json:"type"`
+			ID   string       `json:"id"`
+		} `json:"resources"`
+	} `json:"deployment"`
+}
+
+// FromFlags loads the state of the stack given with --stack, or returns nil when it wasn't passed.
+func FromFlags() (*State, error) {
+	stack := config.Stack.Value()
+	if stack == "" {
+		return nil, nil
+	}
+	return Load(stack)
+}
+
+// Load exports the state of stack with the Pulumi CLI, using whatever backend it is logged in to.
+// An empty stack loads the currently selected stack.
+func Load(stack string) (*State, error) {
+	var stdout, stderr bytes.Buffer
+	args := []string{"stack", "export"}
+	if stack != "" {
+		args = append(args, "--stack", stack)
+	}
+	cmd := exec.Command("pulumi", args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	cmd.Env = os.Environ()
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("failed to export stack %s: %w: %s", stack, err, stderr.String())
+	}
+
+	var e export
+	if err := json.Unmarshal(stdout.Bytes(), &e); err != nil {
+		return nil, fmt.Errorf("failed to parse state of stack %s: %w", stack, err)
+	}
+	s := &State{resources: map[string]resource.URN{}}
+	for _, r := range e.Deployment.Resources {
+		s.urns = append(s.urns, r.URN)
+		if r.ID != "" {
+			s.resources[r.Type+"/"+r.ID] = r.URN
+		}
+	}
+	return s, nil
+}
+
+// URN returns the URN of the resource of type typ with the given ID, and false if it isn't in the
+// stack. A nil State has no resources.
+func (s *State) URN(typ, id string) (resource.URN, bool) {
+	if s == nil {
+		return "", false
+	}
+	urn, ok := s.resources[typ+"/"+id]
+	return urn, ok
+}
+
+// Named returns the URN of the resource of type typ with the given name, and false if it isn't in
+// the stack. It finds resources whose ID isn't known up front, like providers.
+func (s *State) Named(typ, name string) (resource.URN, bool) {
+	if s == nil {
+		return "", false
+	}
+	for _, urn := range s.urns {
+		if string(urn.Type()) == typ && string(urn.Name()) == name {
+			return urn, true
+		}
+	}
+	return "", false
+}
+
+// Ref identifies a resource in an import file. Dependencies are the names of the resources it
+// references, for the programs that record them.
+type Ref struct {
+	Type         string
+	ID           string
+	Name         string
+	Parent       string
+	Dependencies []string
+}
+
+// Filter drops the resources already in the stack from an import file. Resources that are kept
+// but whose parent was dropped refer to it through the returned name table instead.
+func Filter[T any](s *State, resources []T, ref func(T) Ref) ([]T, map[string]resource.URN) {
+	kept := []T{}
+	dropped := map[string]resource.URN{}
+	for _, r := range resources {
+		spec := ref(r)
+		if urn, ok := s.URN(spec.Type, spec.ID); ok {
+			dropped[spec.Name] = urn
+			continue
+		}
+		kept = append(kept, r)
+	}
+
+	nameTable := map[string]resource.URN{}
+	for _, r := range kept {
+		if urn, ok := dropped[ref(r).Parent]; ok {
+			nameTable[ref(r).Parent] = urn
+		}
+	}
+	if len(dropped) > 0 {
+		fmt.Printf("Skipped %d resources already in the stack\n", len(dropped))
+	}
+	return kept, nameTable
+}
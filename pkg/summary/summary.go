@@ -0,0 +1,88 @@
+// Package summary reports how a scan went with the cloud API at its end: how many requests were
+// throttled, how long was spent backing off and which scopes took longest to list, so that workers
+// and filters of the next run can be tuned from data. The summary is printed and written to
+// summary.json.
+package summary
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/pulumi/pulumi-cloud-import/pkg/events"
+	"github.com/pulumi/pulumi-cloud-import/pkg/workers"
+)
+
+// Path is where the summary is written to.
+const Path = "summary.json"
+
+// slowest is the number of scopes listed in the summary.
+const slowest = 10
+
+// Scope is the time spent listing a scope: a type token for most programs, a resource group for
+// Azure and a group/version/resource for Kubernetes.
+type Scope struct {
+	Scope    string  `json:"scope"`
+	Duration string  `json:"duration"`
+	Seconds  float64 `json:"seconds"`
+}
+
+// Summary is the content of summary.json.
+type Summary struct {
+	Requests       int64   `json:"requests"`
+	Throttled      int64   `json:"throttled"`
+	Backoff        string  `json:"backoff"`
+	BackoffSeconds float64 `json:"backoffSeconds"`
+	Slowest        []Scope `json:"slowest"`
+}
+
+// Report prints the summary of the run and writes it to Path.
+func Report() error {
+	requests, throttled, backoff := workers.Stats()
+	s := Summary{
+		Requests:       requests,
+		Throttled:      throttled,
+		Backoff:        backoff.Round(time.Millisecond).String(),
+		BackoffSeconds: backoff.Seconds(),
+		Slowest:        []Scope{},
+	}
+
+	durations := events.Durations()
+	scopes := make([]string, 0, len(durations))
+	for scope := range durations {
+		scopes = append(scopes, scope)
+	}
+	sort.Slice(scopes, func(i, j int) bool {
+		if durations[scopes[i]] != durations[scopes[j]] {
+			return durations[scopes[i]] > durations[scopes[j]]
+		}
+		return scopes[i] < scopes[j]
+	})
+	if len(scopes) > slowest {
+		scopes = scopes[:slowest]
+	}
+	for _, scope := range scopes {
+		d := durations[scope]
+		s.Slowest = append(s.Slowest, Scope{Scope: scope, Duration: d.Round(time.Millisecond).String(), Seconds: d.Seconds()})
+	}
+
+	b, err := json.MarshalIndent(s, "", "    ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(Path, b, 0644); err != nil {
+		return err
+	}
+
+	fmt.Printf("%d of %d requests throttled, %s spent backing off\n", s.Throttled, s.Requests, s.Backoff)
+	if len(s.Slowest) > 0 {
+		fmt.Println("Slowest to list:")
+		for _, scope := range s.Slowest {
+			fmt.Printf("  %s %s\n", scope.Duration, scope.Scope)
+		}
+	}
+	fmt.Printf("Summary written to %s\n", Path)
+	return nil
+}
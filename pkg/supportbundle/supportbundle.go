@@ -0,0 +1,272 @@
+// Package supportbundle gathers what a run left behind into support-bundle.tar.gz with
+// --support-bundle, for attaching to GitHub issues: the manifest, the summary, the denied operations,
+// the quarantined resources and the coverage of the run, its output and the environment it ran in.
+// The bundle turns --redact on, so that the output and the reports, like the resources of the
+// quarantine, are redacted; the names of the environment variables are recorded, never their values.
+package supportbundle
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pulumi/pulumi-cloud-import/pkg/config"
+	"github.com/pulumi/pulumi-cloud-import/pkg/coverage"
+	"github.com/pulumi/pulumi-cloud-import/pkg/denied"
+	"github.com/pulumi/pulumi-cloud-import/pkg/manifest"
+	"github.com/pulumi/pulumi-cloud-import/pkg/quarantine"
+	"github.com/pulumi/pulumi-cloud-import/pkg/redact"
+	"github.com/pulumi/pulumi-cloud-import/pkg/summary"
+	"github.com/pulumi/pulumi-cloud-import/pkg/version"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+// Path is where the bundle is written to.
+const Path = "support-bundle.tar.gz"
+
+// reports are the files the run writes that go into the bundle, when they were written.
+var reports = []string{manifest.Path, summary.Path, denied.Path, quarantine.DefaultPath, coverage.Path}
+
+// envPrefixes are the prefixes of the environment variables whose names are recorded.
+var envPrefixes = []string{config.EnvPrefix, "PULUMI_", "AWS_", "ARM_", "AZURE_", "KUBE"}
+
+// Environment is the content of environment.json.
+type Environment struct {
+	Program   string   `json:"program"`
+	Version   string   `json:"version"`
+	Commit    string   `json:"commit"`
+	Date      string   `json:"date"`
+	Go        string   `json:"go"`
+	OS        string   `json:"os"`
+	Arch      string   `json:"arch"`
+	CPUs      int      `json:"cpus"`
+	Pulumi    string   `json:"pulumi"`
+	Variables []string `json:"variables"`
+}
+
+var (
+	mu      sync.Mutex
+	output  *os.File
+	stdout  *os.File
+	stderr  *os.File
+	pipes   []*os.File
+	copying sync.WaitGroup
+	written sync.Once
+)
+
+// Enabled reports whether --support-bundle was passed.
+func Enabled() bool {
+	return config.SupportBundle.Bool()
+}
+
+// Start copies the output of the program to the bundle from now on. Programs call it first thing,
+// defer Write, and exit with Exit or run their Pulumi program with Run rather than with os.Exit and
+// pulumi.Run, which skip deferred functions.
+func Start() {
+	if !Enabled() {
+		return
+	}
+	f, err := os.CreateTemp("", "pulumi-cloud-import-output")
+	if err != nil {
+		panic(fmt.Sprintf("failed to create the output of the support bundle: %v", err))
+	}
+	output = f
+	stdout, stderr = os.Stdout, os.Stderr
+	os.Stdout = capture(stdout)
+	os.Stderr = capture(stderr)
+}
+
+// capture returns a pipe whose writes go to out as they are, and to the bundle redacted line by line.
+func capture(out *os.File) *os.File {
+	r, w, err := os.Pipe()
+	if err != nil {
+		panic(fmt.Sprintf("failed to capture the output of the support bundle: %v", err))
+	}
+	pipes = append(pipes, w)
+	copying.Add(1)
+	go func() {
+		defer copying.Done()
+		buf := make([]byte, 32*1024)
+		line := ""
+		for {
+			n, err := r.Read(buf)
+			if n > 0 {
+				out.Write(buf[:n])
+				line += string(buf[:n])
+				if i := strings.LastIndex(line, "\n"); i >= 0 {
+					record(line[:i+1])
+					line = line[i+1:]
+				}
+			}
+			if err != nil {
+				record(line)
+				return
+			}
+		}
+	}()
+	return w
+}
+
+// record writes lines of output to the bundle, redacted.
+func record(lines string) {
+	if lines == "" {
+		return
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	output.WriteString(redact.String(lines))
+}
+
+// stop restores the output of the program and waits for what was written so far to be recorded.
+func stop() {
+	os.Stdout, os.Stderr = stdout, stderr
+	for _, w := range pipes {
+		w.Close()
+	}
+	copying.Wait()
+}
+
+// Write writes the bundle. Deferred by programs, it also writes the bundle of a run that panicked,
+// with the panic in its output.
+func Write() {
+	if !Enabled() {
+		return
+	}
+	r := recover()
+	if r != nil {
+		fmt.Fprintf(os.Stderr, "panic: %v\n\n%s", r, debug.Stack())
+	}
+	flush()
+	if r != nil {
+		os.Exit(2)
+	}
+}
+
+// Exit writes the bundle of a failing run and exits with code.
+func Exit(code int) {
+	if Enabled() {
+		flush()
+	}
+	os.Exit(code)
+}
+
+// Run runs body as a Pulumi program like pulumi.Run does, writing the bundle of a failing program
+// before exiting.
+func Run(body pulumi.RunFunc) {
+	if !Enabled() {
+		pulumi.Run(body)
+		return
+	}
+	if err := pulumi.RunErr(body); err != nil {
+		fmt.Fprintf(os.Stderr, "program failed: %v\n", err)
+		Exit(1)
+	}
+}
+
+// flush writes the bundle once, however the run ends.
+func flush() {
+	written.Do(func() {
+		stop()
+		if err := write(); err != nil {
+			fmt.Printf("failed to write the support bundle: %v\n", err)
+		} else {
+			fmt.Printf("support bundle written to %s, attach it to your issue\n", Path)
+		}
+		os.Remove(output.Name())
+	})
+}
+
+func write() error {
+	f, err := os.Create(Path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+
+	env, err := json.MarshalIndent(environment(), "", "    ")
+	if err != nil {
+		return err
+	}
+	if err := add(tw, "environment.json", env); err != nil {
+		return err
+	}
+	if _, err := output.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	out, err := io.ReadAll(output)
+	if err != nil {
+		return err
+	}
+	if err := add(tw, "output.log", out); err != nil {
+		return err
+	}
+	for _, path := range reports {
+		b, err := os.ReadFile(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		// reports hold the identifiers of resources and the errors reported for them
+		if err := add(tw, path, []byte(redact.String(string(b)))); err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// add writes a file to the bundle.
+func add(tw *tar.Writer, name string, b []byte) error {
+	if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(b)), ModTime: time.Now()}); err != nil {
+		return err
+	}
+	_, err := tw.Write(b)
+	return err
+}
+
+// environment describes the build, the machine and the Pulumi CLI of the run.
+func environment() Environment {
+	v, commit, date := version.Build()
+	e := Environment{
+		Program:   version.Program(),
+		Version:   v,
+		Commit:    commit,
+		Date:      date,
+		Go:        runtime.Version(),
+		OS:        runtime.GOOS,
+		Arch:      runtime.GOARCH,
+		CPUs:      runtime.NumCPU(),
+		Variables: []string{},
+	}
+	if out, err := exec.Command("pulumi", "version").Output(); err == nil {
+		e.Pulumi = strings.TrimSpace(string(out))
+	}
+	for _, kv := range os.Environ() {
+		name := strings.SplitN(kv, "=", 2)[0]
+		for _, prefix := range envPrefixes {
+			if strings.HasPrefix(name, prefix) {
+				e.Variables = append(e.Variables, name)
+				break
+			}
+		}
+	}
+	sort.Strings(e.Variables)
+	return e
+}
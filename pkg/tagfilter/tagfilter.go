@@ -0,0 +1,95 @@
+// Package tagfilter matches the tags of resources against --include-tag and --exclude-tag. Tags are
+// given as key=value, or as a key alone to match any value. A resource matches when it has every key
+// of --include-tag with one of the values given for it, like the tag filters of the AWS Resource
+// Groups Tagging API, and none of the tags of --exclude-tag.
+package tagfilter
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/pulumi/pulumi-cloud-import/pkg/config"
+)
+
+// Filter is the tags resources must and must not have, by key, a key without values matching any
+// value.
+type Filter struct {
+	Include map[string][]string
+	Exclude map[string][]string
+}
+
+// FromFlags returns the filter of --include-tag and --exclude-tag, nil when neither is passed.
+func FromFlags() *Filter {
+	include := Parse(config.IncludeTag.List())
+	exclude := Parse(config.ExcludeTag.List())
+	if len(include) == 0 && len(exclude) == 0 {
+		return nil
+	}
+	return &Filter{Include: include, Exclude: exclude}
+}
+
+// Parse parses key=value tags into the values of each key, a key without values matching any value.
+func Parse(list []string) map[string][]string {
+	tags := map[string][]string{}
+	for _, t := range list {
+		key, value, ok := strings.Cut(t, "=")
+		if _, seen := tags[key]; !seen {
+			tags[key] = []string{}
+		}
+		if ok {
+			tags[key] = append(tags[key], value)
+		}
+	}
+	return tags
+}
+
+// Matches reports whether a resource tagged with tags matches. Resources listed for a parent, which
+// matched, are only checked against --exclude-tag as most of them have no tags of their own.
+func (f *Filter) Matches(tags map[string]string, child bool) bool {
+	for key, values := range f.Exclude {
+		if matchesTag(tags, key, values) {
+			return false
+		}
+	}
+	if child {
+		return true
+	}
+	for key, values := range f.Include {
+		if !matchesTag(tags, key, values) {
+			return false
+		}
+	}
+	return true
+}
+
+// Keys returns the keys of both --include-tag and --exclude-tag, sorted.
+func (f *Filter) Keys() []string {
+	keys := []string{}
+	for key := range f.Include {
+		keys = append(keys, key)
+	}
+	for key := range f.Exclude {
+		if _, ok := f.Include[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// matchesTag reports whether tags has key with one of values, or with any value when values is empty.
+func matchesTag(tags map[string]string, key string, values []string) bool {
+	value, ok := tags[key]
+	if !ok {
+		return false
+	}
+	if len(values) == 0 {
+		return true
+	}
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,55 @@
+// Package terminal leaves out the resources that are being deleted, failed or are gone but still
+// listed, like terminated EC2 instances, Azure resources whose provisioning state is Deleting or
+// Kubernetes objects with a deletion timestamp. Such resources fail to import, or are gone by the
+// time the import file is used. Skipped resources are counted and reported at the end of a scan.
+package terminal
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+
+	"github.com/pulumi/pulumi-cloud-import/pkg/debuglog"
+)
+
+// states are the terminal states of the cloud APIs, lower cased without separators so that
+// DELETE_IN_PROGRESS, Deleting and shutting-down all match.
+var states = map[string]bool{
+	"terminated":       true,
+	"terminating":      true,
+	"shuttingdown":     true,
+	"deleting":         true,
+	"deleted":          true,
+	"deleteinprogress": true,
+	"deletecomplete":   true,
+	"deletefailed":     true,
+	"deprovisioning":   true,
+	"createfailed":     true,
+	"failed":           true,
+}
+
+var skipped int64
+
+// IsTerminal reports whether state is a terminal state, whatever its case and separators.
+func IsTerminal(state string) bool {
+	normalized := strings.Map(func(r rune) rune {
+		if r == '-' || r == '_' || r == ' ' {
+			return -1
+		}
+		return r
+	}, strings.ToLower(state))
+	return states[normalized]
+}
+
+// Skip records that the resource with the given type and ID was left out because of its state.
+func Skip(typ, id, state string) {
+	atomic.AddInt64(&skipped, 1)
+	debuglog.For(0, typ).Println("skipping", id, "in state", state)
+}
+
+// Report prints how many resources were left out.
+func Report() {
+	if n := atomic.LoadInt64(&skipped); n > 0 {
+		fmt.Printf("Skipped %d resources being deleted, terminated or in a failed state\n", n)
+	}
+}
@@ -0,0 +1,125 @@
+// Package version reports the build a program was built from, so that an import file can be traced
+// back to the mapping logic and provider schemas that produced it. The values are embedded at build
+// time with -ldflags:
+//
+//	go build -ldflags "-X github.com/pulumi/pulumi-cloud-import/pkg/version.Version=v0.1.0 \
+//	    -X github.com/pulumi/pulumi-cloud-import/pkg/version.Date=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+//
+// Values that aren't set fall back to the VCS information recorded by the go command.
+package version
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"runtime/debug"
+
+	"github.com/pulumi/pulumi-cloud-import/pkg/config"
+)
+
+var (
+	// Version is the release the program was built from.
+	Version string
+	// Commit is the git SHA the program was built from.
+	Commit string
+	// Date is the time the program was built at.
+	Date string
+)
+
+// Schema is a provider schema a program maps resources with, along with the version it is pinned to.
+type Schema struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// schemas are the schemas the program declared with HandleFlag.
+var schemas []Schema
+
+// HandleFlag prints the build and the schemas in use and exits when --version is passed.
+func HandleFlag(declared ...Schema) {
+	schemas = declared
+	if !config.Version.Bool() {
+		return
+	}
+	Print(os.Stdout, schemas...)
+	os.Exit(0)
+}
+
+// Schemas returns the schemas in use, as given to HandleFlag.
+func Schemas() []Schema {
+	return schemas
+}
+
+// Print writes the build and the schemas in use to w.
+func Print(w io.Writer, schemas ...Schema) {
+	version, commit, date := Build()
+	fmt.Fprintf(w, "version: %s\n", orUnknown(version))
+	fmt.Fprintf(w, "commit: %s\n", orUnknown(commit))
+	fmt.Fprintf(w, "date: %s\n", orUnknown(date))
+	for _, s := range schemas {
+		fmt.Fprintf(w, "%s: %s\n", s.Name, orUnknown(s.Version))
+	}
+}
+
+// Build returns the release, commit and build time of the program, from the values embedded at
+// build time or else from the VCS information recorded by the go command.
+func Build() (version string, commit string, date string) {
+	version, commit, date = Version, Commit, Date
+	modified := false
+	if info, ok := debug.ReadBuildInfo(); ok {
+		if version == "" {
+			version = info.Main.Version
+		}
+		for _, s := range info.Settings {
+			switch s.Key {
+			case "vcs.revision":
+				if commit == "" {
+					commit = s.Value
+				}
+			case "vcs.time":
+				if date == "" {
+					date = s.Value
+				}
+			case "vcs.modified":
+				modified = s.Value == "true"
+			}
+		}
+	}
+	if commit != "" && modified && Commit == "" {
+		commit += " (modified)"
+	}
+	return version, commit, date
+}
+
+// Program returns the module path of the program, e.g. pulumi-cloud-import-aws.
+func Program() string {
+	if info, ok := debug.ReadBuildInfo(); ok {
+		return info.Main.Path
+	}
+	return ""
+}
+
+// Dependency returns the version of the module at path the program was built with.
+func Dependency(path string) string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return ""
+	}
+	for _, d := range info.Deps {
+		if d.Path != path {
+			continue
+		}
+		if d.Replace != nil {
+			return d.Replace.Version
+		}
+		return d.Version
+	}
+	return ""
+}
+
+func orUnknown(s string) string {
+	if s == "" {
+		return "unknown"
+	}
+	return s
+}
@@ -0,0 +1,68 @@
+package workers
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/pulumi/pulumi-cloud-import/pkg/config"
+)
+
+// pausePoll is how often the file given with --pause-file is checked for.
+const pausePoll = time.Second
+
+// A scan is paused while either SIGUSR1 toggled it or the pause file exists. Requests already sent
+// complete, no new request is sent until the scan is resumed.
+var (
+	pauseOnce    sync.Once
+	pauseMu      sync.Mutex
+	pauseCond    = sync.NewCond(&pauseMu)
+	signalPaused bool
+	filePaused   bool
+	pausedAt     time.Time
+)
+
+// watchPause starts listening for SIGUSR1 and polling for the file given with --pause-file.
+func watchPause() {
+	notifyPause(func() {
+		pauseMu.Lock()
+		defer pauseMu.Unlock()
+		setPaused(&signalPaused, !signalPaused, "SIGUSR1")
+	})
+	if path := config.PauseFile.Value(); path != "" {
+		go func() {
+			for range time.Tick(pausePoll) {
+				_, err := os.Stat(path)
+				pauseMu.Lock()
+				setPaused(&filePaused, err == nil, path)
+				pauseMu.Unlock()
+			}
+		}()
+	}
+}
+
+// setPaused sets one of the reasons to pause to v, and reports when the scan pauses or resumes.
+// pauseMu must be held.
+func setPaused(reason *bool, v bool, source string) {
+	was := signalPaused || filePaused
+	*reason = v
+	switch now := signalPaused || filePaused; {
+	case now && !was:
+		pausedAt = time.Now()
+		fmt.Printf("scan paused by %s, requests in flight complete but no new request is sent\n", source)
+	case !now && was:
+		fmt.Printf("scan resumed by %s after %s\n", source, time.Since(pausedAt).Round(time.Second))
+		pauseCond.Broadcast()
+	}
+}
+
+// waitResumed blocks while the scan is paused.
+func waitResumed() {
+	pauseOnce.Do(watchPause)
+	pauseMu.Lock()
+	defer pauseMu.Unlock()
+	for signalPaused || filePaused {
+		pauseCond.Wait()
+	}
+}
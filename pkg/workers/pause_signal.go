@@ -0,0 +1,20 @@
+//go:build !windows
+
+package workers
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// notifyPause calls toggle whenever the program receives SIGUSR1.
+func notifyPause(toggle func()) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGUSR1)
+	go func() {
+		for range signals {
+			toggle()
+		}
+	}()
+}
@@ -0,0 +1,4 @@
+package workers
+
+// notifyPause does nothing on Windows, which has no SIGUSR1. Scans are paused with --pause-file.
+func notifyPause(toggle func()) {}
@@ -0,0 +1,160 @@
+// Package workers sizes the pool of workers listing resources. --workers sets the number of
+// workers, which defaults to what suits the rate limits of each cloud API. With --workers auto, the
+// pool starts at four workers per CPU and adapts to the cloud API: when more than 5% of the
+// requests of the last few seconds were throttled, half of the workers pause before listing their
+// next scope, and they resume one by one once requests go through again.
+//
+// Operators can also pause a scan to relieve the cloud API of a shared account without losing its
+// progress, by sending SIGUSR1 to the program or creating the file given with --pause-file, and
+// resume it by sending SIGUSR1 again or removing the file.
+package workers
+
+import (
+	"fmt"
+	"net/http"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pulumi/pulumi-cloud-import/pkg/config"
+	"github.com/pulumi/pulumi-cloud-import/pkg/retry"
+)
+
+const (
+	perCPU = 4
+	// minimum is the number of workers auto mode never goes below.
+	minimum = 2
+	// maxThrottleRate is the share of throttled requests above which auto mode halves the workers.
+	maxThrottleRate = 0.05
+	interval        = 5 * time.Second
+)
+
+var (
+	once      sync.Once
+	mu        sync.Mutex
+	cond      = sync.NewCond(&mu)
+	limit     int
+	requests  int64
+	throttled int64
+)
+
+// totals of the whole run, for Stats
+var (
+	totalRequests  int64
+	totalThrottled int64
+	backoff        int64
+)
+
+// Auto reports whether --workers is auto.
+func Auto() bool {
+	return strings.EqualFold(config.Workers.Value(), "auto")
+}
+
+// Count returns the number of workers to start, def when --workers isn't given.
+func Count(def int) int {
+	if Auto() {
+		return runtime.NumCPU() * perCPU
+	}
+	if _, _, ok := config.Workers.Lookup(); !ok {
+		return def
+	}
+	return config.Workers.Int()
+}
+
+// Wait blocks worker i, numbered from 0, while auto mode has paused it or the scan is paused.
+// Workers call it before listing each scope. Auto mode never pauses workers when --workers is a
+// number.
+func Wait(i int) {
+	waitResumed()
+	if !Auto() {
+		return
+	}
+	once.Do(start)
+	mu.Lock()
+	defer mu.Unlock()
+	if i < limit {
+		return
+	}
+	paused := time.Now()
+	for i >= limit {
+		cond.Wait()
+	}
+	Backoff(time.Since(paused))
+}
+
+// Backoff records time spent waiting for the cloud API to stop throttling, like the delay before
+// retrying a throttled request.
+func Backoff(d time.Duration) {
+	atomic.AddInt64(&backoff, int64(d))
+}
+
+// Stats returns the number of requests made and throttled over the whole run, and the time spent
+// backing off. Requests throttled with a Retry-After header count as backing off for that long.
+func Stats() (total int64, throttled int64, backedOff time.Duration) {
+	return atomic.LoadInt64(&totalRequests), atomic.LoadInt64(&totalThrottled), time.Duration(atomic.LoadInt64(&backoff))
+}
+
+func start() {
+	limit = Count(0)
+	fmt.Printf("sizing workers automatically, starting with %d\n", limit)
+	go adjust()
+}
+
+// adjust halves the workers when too many requests were throttled over the last interval, and adds
+// one back after an interval without throttling.
+func adjust() {
+	for range time.Tick(interval) {
+		total, failed := atomic.SwapInt64(&requests, 0), atomic.SwapInt64(&throttled, 0)
+		mu.Lock()
+		switch {
+		case total > 0 && float64(failed)/float64(total) > maxThrottleRate && limit > minimum:
+			limit /= 2
+			if limit < minimum {
+				limit = minimum
+			}
+			fmt.Printf("%d of %d requests throttled, pausing workers down to %d\n", failed, total, limit)
+		case failed == 0 && limit < Count(0):
+			limit++
+		}
+		cond.Broadcast()
+		mu.Unlock()
+	}
+}
+
+// Wrap returns a transport counting the requests throttled by the cloud API, which auto mode sizes
+// the workers from and Stats reports, and holding requests back while the scan is paused.
+func Wrap(next http.RoundTripper) http.RoundTripper {
+	pauseOnce.Do(watchPause)
+	return transport{next: next}
+}
+
+type transport struct {
+	next http.RoundTripper
+}
+
+func (t transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	waitResumed()
+	resp, err := t.next.RoundTrip(req)
+	atomic.AddInt64(&requests, 1)
+	atomic.AddInt64(&totalRequests, 1)
+	if resp != nil && isThrottled(resp) {
+		atomic.AddInt64(&throttled, 1)
+		atomic.AddInt64(&totalThrottled, 1)
+		// clients honoring Retry-After, like the Azure SDK, wait that long before retrying
+		if after := retry.RetryAfter(resp.Header); after > 0 {
+			Backoff(after)
+		}
+	}
+	return resp, err
+}
+
+// isThrottled reports whether resp is a throttling error: a 429, or the 400 AWS answers with a
+// ThrottlingException.
+func isThrottled(resp *http.Response) bool {
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return true
+	}
+	return strings.Contains(resp.Header.Get("X-Amzn-Errortype"), "Throttl")
+}
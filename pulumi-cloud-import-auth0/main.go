@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/auth0/go-auth0/management"
+	"github.com/pulumi/pulumi-cloud-import/pkg/config"
+	"github.com/pulumi/pulumi-cloud-import/pkg/importer"
+	"github.com/pulumi/pulumi-cloud-import/pkg/naming"
+	"github.com/pulumi/pulumi-cloud-import/pkg/version"
+)
+
+const (
+	clientType         = "auth0:index/client:Client"
+	resourceServerType = "auth0:index/resourceServer:ResourceServer"
+	connectionType     = "auth0:index/connection:Connection"
+	ruleType           = "auth0:index/rule:Rule"
+	actionType         = "auth0:index/action:Action"
+	roleType           = "auth0:index/role:Role"
+)
+
+type auth0Discoverer struct {
+	domain string
+	api    *management.Management
+}
+
+func main() {
+	// --version is answered before the credentials are read
+	version.HandleFlag()
+	config.HandleCommand()
+	domain := getDomain()
+	api, err := management.New(domain, management.WithClientCredentials(getClientID(), getClientSecret()))
+	if err != nil {
+		panic(fmt.Sprintf("Authentication failure: %+v", err))
+	}
+	importer.Main(auth0Discoverer{domain: domain, api: api})
+}
+
+// Identity returns the tenant scanned, recorded in the run manifest.
+func (d auth0Discoverer) Identity() map[string]string {
+	return map[string]string{"domain": d.domain}
+}
+
+func (d auth0Discoverer) Types() []string {
+	return []string{clientType, resourceServerType, connectionType, ruleType, actionType, roleType}
+}
+
+// List pages through the Management API endpoint for the given type. All of these resources are
+// imported by their Auth0 ID.
+func (d auth0Discoverer) List(ctx context.Context, typ string, emit func(importer.ImportSpec)) error {
+	add := func(id, name string) {
+		emit(importer.ImportSpec{
+			ID:   id,
+			Type: typ,
+			Name: naming.Default.Name(name),
+		})
+	}
+
+	for page := 0; ; page++ {
+		opts := []management.RequestOption{management.Context(ctx), management.Page(page), management.PerPage(100)}
+
+		var list management.List
+		switch typ {
+		case clientType:
+			l, err := d.api.Client.List(opts...)
+			if err != nil {
+				return err
+			}
+			for _, c := range l.Clients {
+				add(c.GetClientID(), c.GetName())
+			}
+			list = l.List
+		case resourceServerType:
+			l, err := d.api.ResourceServer.List(opts...)
+			if err != nil {
+				return err
+			}
+			for _, r := range l.ResourceServers {
+				// the Auth0 Management API itself is listed as a resource server but can't be managed
+				if r.GetIdentifier() == fmt.Sprintf("https://%s/api/v2/", d.domain) {
+					continue
+				}
+				add(r.GetID(), r.GetName())
+			}
+			list = l.List
+		case connectionType:
+			l, err := d.api.Connection.List(opts...)
+			if err != nil {
+				return err
+			}
+			for _, c := range l.Connections {
+				add(c.GetID(), c.GetName())
+			}
+			list = l.List
+		case ruleType:
+			l, err := d.api.Rule.List(opts...)
+			if err != nil {
+				return err
+			}
+			for _, r := range l.Rules {
+				add(r.GetID(), r.GetName())
+			}
+			list = l.List
+		case actionType:
+			l, err := d.api.Action.List(opts...)
+			if err != nil {
+				return err
+			}
+			for _, a := range l.Actions {
+				add(a.GetID(), a.GetName())
+			}
+			list = l.List
+		case roleType:
+			l, err := d.api.Role.List(opts...)
+			if err != nil {
+				return err
+			}
+			for _, r := range l.Roles {
+				add(r.GetID(), r.GetName())
+			}
+			list = l.List
+		default:
+			return fmt.Errorf("unknown type %s", typ)
+		}
+
+		if !list.HasNext() {
+			return nil
+		}
+	}
+}
+
+// reads AUTH0_DOMAIN env var or panics if it is not set
+func getDomain() string {
+	domain := os.Getenv("AUTH0_DOMAIN")
+	if domain == "" {
+		panic("AUTH0_DOMAIN env var must be set")
+	}
+	return domain
+}
+
+// reads AUTH0_CLIENT_ID env var or panics if it is not set
+func getClientID() string {
+	clientID := os.Getenv("AUTH0_CLIENT_ID")
+	if clientID == "" {
+		panic("AUTH0_CLIENT_ID env var must be set")
+	}
+	return clientID
+}
+
+// reads AUTH0_CLIENT_SECRET env var or panics if it is not set
+func getClientSecret() string {
+	clientSecret := os.Getenv("AUTH0_CLIENT_SECRET")
+	if clientSecret == "" {
+		panic("AUTH0_CLIENT_SECRET env var must be set")
+	}
+	return clientSecret
+}
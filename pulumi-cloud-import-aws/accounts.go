@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/organizations"
+	"github.com/aws/aws-sdk-go-v2/service/organizations/types"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/pulumi/pulumi-cloud-import/pkg/broker"
+	"github.com/pulumi/pulumi-cloud-import/pkg/config"
+	"github.com/pulumi/pulumi-cloud-import/pkg/naming"
+	"github.com/pulumi/pulumi-cloud-import/pkg/recorder"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+const providerType = "pulumi:providers:aws-native"
+
+// account is an account to scan along with the configuration of the clients listing its resources. With
+// --aws-organization, the member accounts of the organization are scanned with a role assumed in
+// each of them, or with the credentials of --aws-credential-broker, resource names are prefixed with the account ID so that resources of the same
+// name in different accounts don't collide, and resources are read and imported with a provider
+// of their own account rather than the default provider.
+type account struct {
+	id string
+	// prefix is prepended to resource names, empty when a single account is scanned
+	prefix string
+	// provider is the name of the provider of the account, empty when a single account is scanned
+	provider string
+	// roleARN is the role assumed in the account, empty for the account of the credentials
+	roleARN string
+	// creds are the credentials of the account obtained from --aws-credential-broker
+	creds broker.Credentials
+	cfg   aws.Config
+}
+
+// name returns the resource name of a resource of the account named after parts.
+func (a *account) name(parts ...string) string {
+	return naming.AWS.Name(append([]string{a.prefix}, parts...)...)
+}
+
+// providerInputs configures the provider of the account to scan region.
+func (a *account) providerInputs(region string) pulumi.Map {
+	inputs := pulumi.Map{
+		"region": pulumi.String(region),
+	}
+	switch {
+	case a.roleARN != "":
+		inputs["assumeRole"] = pulumi.Map{
+			"roleArn": pulumi.String(a.roleARN),
+		}
+	case a.creds.AccessKeyID != "":
+		inputs["accessKey"] = pulumi.ToSecret(pulumi.String(a.creds.AccessKeyID))
+		inputs["secretKey"] = pulumi.ToSecret(pulumi.String(a.creds.SecretAccessKey))
+		inputs["token"] = pulumi.ToSecret(pulumi.String(a.creds.SessionToken))
+	case a.creds.Profile != "":
+		inputs["profile"] = pulumi.String(a.creds.Profile)
+	}
+	return inputs
+}
+
+// getAccounts returns the accounts to scan: the account of the credentials, or the active accounts
+// of the organization with --aws-organization, narrowed down to the ones passed with --aws-accounts.
+// The member accounts are scanned with the credentials of --aws-credential-broker if set, and with
+// --aws-assume-role otherwise. Accounts whose credentials can't be obtained are reported and left
+// out.
+func getAccounts(cfg aws.Config) ([]*account, error) {
+	if !config.AWSOrganization.Bool() {
+		return []*account{{cfg: cfg}}, nil
+	}
+
+	var creds broker.Broker
+	if path := config.AWSCredentialBroker.Value(); path != "" {
+		var err error
+		if creds, err = broker.Load(path); err != nil {
+			return nil, err
+		}
+	}
+
+	ctx := context.Background()
+	stsClient := sts.NewFromConfig(cfg)
+	identity, err := stsClient.GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
+	if err != nil {
+		return nil, err
+	}
+	caller, err := arn.Parse(aws.ToString(identity.Arn))
+	if err != nil {
+		return nil, err
+	}
+
+	ids := []string{}
+	paginator := organizations.NewListAccountsPaginator(organizations.NewFromConfig(cfg), &organizations.ListAccountsInput{})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list the accounts of the organization: %w", err)
+		}
+		for _, a := range page.Accounts {
+			if a.Status == types.AccountStatusActive {
+				ids = append(ids, aws.ToString(a.Id))
+			}
+		}
+	}
+
+	only := config.AWSAccounts.List()
+	role := config.AWSAssumeRole.Value()
+	accounts := []*account{}
+	for _, id := range ids {
+		if len(only) > 0 && !contains(only, id) {
+			continue
+		}
+		a := &account{id: id, prefix: id, provider: "account-" + id, cfg: cfg.Copy()}
+		// the requests of every account are the same, the recording tells them apart by account
+		a.cfg.HTTPClient = recorder.Scope(cfg.HTTPClient, id)
+		// the account of the credentials is scanned with them
+		if id != caller.AccountID && creds != nil {
+			if err := a.useBroker(ctx, creds); err != nil {
+				fmt.Printf("skipping account %s, failed to get its credentials: %v\n", id, err)
+				continue
+			}
+		} else if id != caller.AccountID {
+			a.roleARN = fmt.Sprintf("arn:%s:iam::%s:role/%s", caller.Partition, id, role)
+			a.cfg.Credentials = aws.NewCredentialsCache(stscreds.NewAssumeRoleProvider(stsClient, a.roleARN))
+			if _, err := a.cfg.Credentials.Retrieve(ctx); err != nil {
+				fmt.Printf("skipping account %s, failed to assume %s: %v\n", id, a.roleARN, err)
+				continue
+			}
+		}
+		accounts = append(accounts, a)
+	}
+	if len(accounts) == 0 {
+		return nil, fmt.Errorf("no account of the organization could be scanned")
+	}
+	fmt.Printf("scanning %d of the %d accounts of the organization\n", len(accounts), len(ids))
+	return accounts, nil
+}
+
+// useBroker points the clients of the account at the credentials b obtains for it, either a
+// profile or access keys.
+func (a *account) useBroker(ctx context.Context, b broker.Broker) error {
+	creds, err := b.Credentials(a.id)
+	if err != nil {
+		return err
+	}
+	a.creds = creds
+	if creds.AccessKeyID != "" {
+		a.cfg.Credentials = credentials.NewStaticCredentialsProvider(creds.AccessKeyID, creds.SecretAccessKey, creds.SessionToken)
+	} else {
+		profile, err := awsconfig.LoadDefaultConfig(ctx, awsconfig.WithSharedConfigProfile(creds.Profile))
+		if err != nil {
+			return err
+		}
+		a.cfg.Credentials = profile.Credentials
+	}
+	_, err = a.cfg.Credentials.Retrieve(ctx)
+	return err
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// accountPath returns the path of the import file of account with --aws-split-accounts, e.g.
+// import-123456789012.json for import.json.
+func accountPath(path, account string) string {
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + "-" + account + ext
+}
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/pulumi/pulumi-cloud-import/pkg/config"
+)
+
+// checkpointEntry is a line of the checkpoint file: a resource discovered, the page token a listing
+// got to, or a type of an account listed in full.
+type checkpointEntry struct {
+	Resource *importSpec `json:"resource,omitempty"`
+	// Account is the account of Resource, which import specs don't write
+	Account string `json:"account,omitempty"`
+	// Scope identifies a listing, see listingScope, and Next the token of its next page
+	Scope string `json:"scope,omitempty"`
+	Next  string `json:"next,omitempty"`
+	// Done is a type of an account listed in full, see typeScope
+	Done string `json:"done,omitempty"`
+}
+
+// checkpoint records the progress of a scan generating an import file, so that a scan dying partway,
+// as credentials expire or laptops sleep, can be picked up where it stopped with --resume rather than
+// start over. The resources discovered, the page tokens of the listings and the types listed in full
+// are appended to checkpoint.json, or to --checkpoint, as they go, one JSON object per line, so that
+// whatever was written before the scan died can be read back.
+//
+// A resumed scan skips the types listed in full, picks up the other listings from their last page
+// token, and writes the resources discovered before it was interrupted to the import file along
+// with the new ones. A listing whose token expired in the meantime is listed again from the start,
+// leaving out the resources already discovered. The file is removed once the import file is written.
+type checkpoint struct {
+	mu   sync.Mutex
+	path string
+	file *os.File
+	enc  *json.Encoder
+
+	resources []importSpec
+	// discovered are the resources read back, by type and ID
+	discovered map[string]bool
+	next       map[string]string
+	done       map[string]bool
+	// size is the size of the lines read back in full
+	size int64
+}
+
+// openCheckpoint reads the checkpoint of the scan --resume picks up, and opens the checkpoint file
+// of the scan for writing, which starts anew without --resume. It returns nil when --checkpoint is
+// empty and panics when the file can't be read or written.
+func openCheckpoint() *checkpoint {
+	path := config.AWSCheckpoint.Value()
+	if path == "" {
+		return nil
+	}
+	c := &checkpoint{
+		path:       path,
+		discovered: map[string]bool{},
+		next:       map[string]string{},
+		done:       map[string]bool{},
+	}
+	flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if config.AWSResume.Bool() {
+		flags = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+		if err := c.read(); err != nil {
+			panic(fmt.Sprintf("failed to read %s: %v", path, err))
+		}
+	}
+	f, err := os.OpenFile(path, flags, 0644)
+	if err == nil {
+		// a line cut short is dropped, rather than continued by the next one
+		err = f.Truncate(c.size)
+	}
+	if err != nil {
+		panic(fmt.Sprintf("failed to open checkpoint file: %v", err))
+	}
+	c.file, c.enc = f, json.NewEncoder(f)
+	return c
+}
+
+// read reads back the checkpoint of an earlier scan, if any.
+func (c *checkpoint) read() error {
+	f, err := os.Open(c.path)
+	if os.IsNotExist(err) {
+		fmt.Printf("no checkpoint to resume from in %s, scanning from the start\n", c.path)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	r := bufio.NewReader(f)
+	for {
+		line, err := r.ReadBytes('\n')
+		if err == io.EOF {
+			// the last line is cut short when the scan died while writing it
+			break
+		}
+		if err != nil {
+			return err
+		}
+		var e checkpointEntry
+		if err := json.Unmarshal(line, &e); err != nil {
+			break
+		}
+		c.size += int64(len(line))
+		switch {
+		case e.Resource != nil:
+			e.Resource.Account = e.Account
+			c.resources = append(c.resources, *e.Resource)
+			c.discovered[e.Resource.Type+"|"+e.Resource.ID] = true
+		case e.Done != "":
+			c.done[e.Done] = true
+		case e.Scope != "":
+			c.next[e.Scope] = e.Next
+		}
+	}
+	if len(c.resources) > 0 || len(c.done) > 0 {
+		fmt.Printf("resuming from %s: %d resources discovered and %d types listed already\n", c.path, len(c.resources), len(c.done))
+	}
+	return nil
+}
+
+// typeScope identifies the listings of a type in an account.
+func typeScope(account, token string) string {
+	return account + "|" + token
+}
+
+// listingScope identifies a listing of a type in an account, for a resource model in a region.
+func listingScope(account, token, region string, model *string) string {
+	scope := typeScope(account, token) + "|" + region
+	if model != nil {
+		scope += "|" + *model
+	}
+	return scope
+}
+
+// recovered returns the resources read back from the checkpoint.
+func (c *checkpoint) recovered() []importSpec {
+	if c == nil {
+		return nil
+	}
+	return c.resources
+}
+
+// listed reports whether every resource of token in account was discovered before the scan was
+// resumed.
+func (c *checkpoint) listed(account, token string) bool {
+	if c == nil {
+		return false
+	}
+	return c.done[typeScope(account, token)]
+}
+
+// resumeToken returns the page token a listing got to before the scan was resumed, nil when it
+// didn't start. A listing whose last page was listed is not resumed either, as listed tells.
+func (c *checkpoint) resumeToken(scope string) *string {
+	if c == nil {
+		return nil
+	}
+	next, ok := c.next[scope]
+	if !ok || next == "" {
+		return nil
+	}
+	return &next
+}
+
+// has reports whether resource was discovered before the scan was resumed.
+func (c *checkpoint) has(resource importSpec) bool {
+	if c == nil {
+		return false
+	}
+	return c.discovered[resource.Type+"|"+resource.ID]
+}
+
+// add records a resource discovered.
+func (c *checkpoint) add(resource importSpec) {
+	c.write(checkpointEntry{Resource: &resource, Account: resource.Account})
+}
+
+// page records that a listing got to the page of token next, once the resources of the page before
+// it were added.
+func (c *checkpoint) page(scope string, next *string) {
+	if next == nil {
+		return
+	}
+	c.write(checkpointEntry{Scope: scope, Next: *next})
+}
+
+// finish records that every resource of token in account was discovered.
+func (c *checkpoint) finish(account, token string) {
+	c.write(checkpointEntry{Done: typeScope(account, token)})
+}
+
+func (c *checkpoint) write(e checkpointEntry) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.enc.Encode(e); err != nil {
+		panic(fmt.Sprintf("failed to write %s: %v", c.path, err))
+	}
+}
+
+// close closes the checkpoint file, which is kept until the import file is written.
+func (c *checkpoint) close() error {
+	if c == nil {
+		return nil
+	}
+	return c.file.Close()
+}
+
+// removeCheckpoint removes the checkpoint file once the import file it was recorded for is written.
+func removeCheckpoint() error {
+	path := config.AWSCheckpoint.Value()
+	if path == "" {
+		return nil
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/pulumi/pulumi-cloud-import/pkg/config"
+	"github.com/pulumi/pulumi-cloud-import/pkg/debuglog"
+)
+
+// defaultsFilter leaves out the resources AWS creates by default with --skip-defaults: the default
+// VPC of the region, its default subnets and DHCP options, and the default security group, main route
+// table and default network ACL every VPC comes with. Nobody manages those, and importing them only
+// clutters the stack. They are looked up with the EC2 describe APIs.
+type defaultsFilter struct {
+	// ids are the identifiers of the default resources by type
+	ids map[string]map[string]bool
+
+	mu      sync.Mutex
+	leftOut int
+}
+
+// newDefaultsFilter returns the filter of the resources of the account cfg points to, nil without
+// --skip-defaults. It panics when the default resources can't be looked up.
+func newDefaultsFilter(cfg aws.Config) *defaultsFilter {
+	if !config.SkipDefaults.Bool() {
+		return nil
+	}
+	f := &defaultsFilter{ids: map[string]map[string]bool{}}
+	if err := f.load(ec2.NewFromConfig(cfg)); err != nil {
+		panic(fmt.Sprintf("failed to look up the default resources: %v", err))
+	}
+	return f
+}
+
+// add records id as a default resource of cfType.
+func (f *defaultsFilter) add(cfType string, id *string) {
+	if f.ids[cfType] == nil {
+		f.ids[cfType] = map[string]bool{}
+	}
+	f.ids[cfType][aws.ToString(id)] = true
+}
+
+// load looks up the default resources.
+func (f *defaultsFilter) load(client *ec2.Client) error {
+	ctx := context.Background()
+
+	vpcs := ec2.NewDescribeVpcsPaginator(client, &ec2.DescribeVpcsInput{
+		Filters: []types.Filter{{Name: aws.String("is-default"), Values: []string{"true"}}},
+	})
+	for vpcs.HasMorePages() {
+		page, err := vpcs.NextPage(ctx)
+		if err != nil {
+			return err
+		}
+		for _, v := range page.Vpcs {
+			f.add("AWS::EC2::VPC", v.VpcId)
+			f.add("AWS::EC2::DHCPOptions", v.DhcpOptionsId)
+		}
+	}
+	subnets := ec2.NewDescribeSubnetsPaginator(client, &ec2.DescribeSubnetsInput{
+		Filters: []types.Filter{{Name: aws.String("default-for-az"), Values: []string{"true"}}},
+	})
+	for subnets.HasMorePages() {
+		page, err := subnets.NextPage(ctx)
+		if err != nil {
+			return err
+		}
+		for _, s := range page.Subnets {
+			f.add("AWS::EC2::Subnet", s.SubnetId)
+		}
+	}
+	groups := ec2.NewDescribeSecurityGroupsPaginator(client, &ec2.DescribeSecurityGroupsInput{
+		Filters: []types.Filter{{Name: aws.String("group-name"), Values: []string{"default"}}},
+	})
+	for groups.HasMorePages() {
+		page, err := groups.NextPage(ctx)
+		if err != nil {
+			return err
+		}
+		for _, g := range page.SecurityGroups {
+			f.add("AWS::EC2::SecurityGroup", g.GroupId)
+		}
+	}
+	routes := ec2.NewDescribeRouteTablesPaginator(client, &ec2.DescribeRouteTablesInput{
+		Filters: []types.Filter{{Name: aws.String("association.main"), Values: []string{"true"}}},
+	})
+	for routes.HasMorePages() {
+		page, err := routes.NextPage(ctx)
+		if err != nil {
+			return err
+		}
+		for _, t := range page.RouteTables {
+			f.add("AWS::EC2::RouteTable", t.RouteTableId)
+		}
+	}
+	acls := ec2.NewDescribeNetworkAclsPaginator(client, &ec2.DescribeNetworkAclsInput{
+		Filters: []types.Filter{{Name: aws.String("default"), Values: []string{"true"}}},
+	})
+	for acls.HasMorePages() {
+		page, err := acls.NextPage(ctx)
+		if err != nil {
+			return err
+		}
+		for _, a := range page.NetworkAcls {
+			f.add("AWS::EC2::NetworkAcl", a.NetworkAclId)
+		}
+	}
+	debuglog.Println("found", len(f.ids["AWS::EC2::VPC"]), "default VPCs")
+	return nil
+}
+
+// keep reports whether the resource of cfType identified by id isn't a default resource.
+func (f *defaultsFilter) keep(cfType, id string) bool {
+	if f == nil || !f.ids[cfType][id] {
+		return true
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.leftOut++
+	return false
+}
+
+// report prints how many default resources were left out.
+func (f *defaultsFilter) report() {
+	if f == nil {
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	fmt.Printf("%d default resources were left out\n", f.leftOut)
+}
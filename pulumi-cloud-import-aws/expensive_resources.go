@@ -0,0 +1,17 @@
+package main
+
+// expensiveResources are listed once per parent or return a resource per object, burning API quota
+// and hours of runtime in large accounts for resources that are rarely worth importing. They are
+// skipped unless --include-expensive is passed.
+var expensiveResources = map[string]bool{
+	// a stream per log group and source, often hundreds of thousands
+	"aws-native:logs:LogStream": true,
+	// a deployment per rest API and release
+	"aws-native:apigateway:Deployment": true,
+	// a version per function and publish
+	"aws-native:lambda:Version": true,
+	// a version per bot and build
+	"aws-native:lex:BotVersion": true,
+	// a thing per device
+	"aws-native:iot:Thing": true,
+}
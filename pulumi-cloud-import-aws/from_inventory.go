@@ -0,0 +1,128 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pulumi/pulumi-cloud-import/pkg/config"
+	"github.com/pulumi/pulumi-cloud-import/pkg/created"
+	"github.com/pulumi/pulumi-cloud-import/pkg/ignore"
+	"github.com/pulumi/pulumi-cloud-import/pkg/manifest"
+	"github.com/pulumi/pulumi-cloud-import/pkg/offline"
+	"github.com/pulumi/pulumi-cloud-import/pkg/tagfilter"
+)
+
+// nameIdentifiedTypes are the types whose cloud control identifier is the name AWS Config records,
+// rather than the resource ID, e.g. the name of an IAM role rather than its AROA... ID.
+var nameIdentifiedTypes = map[string]bool{
+	"AWS::IAM::Role":  true,
+	"AWS::IAM::User":  true,
+	"AWS::IAM::Group": true,
+}
+
+// importFromInventory builds the import file from the AWS Config snapshot passed with
+// --from-inventory rather than from the cloud control API. Resources are identified by the resource
+// ID AWS Config records, which is the cloud control identifier of most types. Resources of types
+// aws-native doesn't have or that are unsupported are left out, and so are the ones --services,
+// --types, the tag filters, the creation time filters and --ignore-file leave out. Resources of
+// several accounts are named and imported like with --aws-organization.
+func importFromInventory(path string) (importFile, error) {
+	imports := importFile{
+		Resources: []importSpec{},
+	}
+	items, err := offline.ReadAWSConfig(path)
+	if err != nil {
+		return imports, err
+	}
+	awsNativeTypesMap, err := getAWSNativeMetadata()
+	if err != nil {
+		return imports, err
+	}
+	unsupported, err := loadUnsupportedResources()
+	if err != nil {
+		return imports, err
+	}
+
+	// the current token of each type, renamed types having several
+	all := []string{}
+	for k := range *awsNativeTypesMap {
+		all = append(all, k)
+	}
+	tokens := map[string]string{}
+	for _, k := range canonicalTypes(all, *awsNativeTypesMap) {
+		tokens[(*awsNativeTypesMap)[k]] = k
+	}
+
+	// resources of several accounts are prefixed with their account, like with --aws-organization
+	accounts := map[string]*account{}
+	for _, item := range items {
+		accounts[item.AccountID] = &account{id: item.AccountID}
+	}
+	if len(accounts) > 1 {
+		for id, a := range accounts {
+			a.prefix, a.provider = id, "account-"+id
+		}
+	}
+
+	services := config.Services.List()
+	include, exclude := config.Types.List(), config.ExcludeTypes.List()
+	tagged := tagfilter.FromFlags()
+	window := created.FromFlags()
+	names := newNameTemplate()
+	collisions := newNameCollisions()
+	missing := map[string]int{}
+	for _, item := range items {
+		k, ok := tokens[item.ResourceType]
+		if !ok {
+			missing[item.ResourceType]++
+			continue
+		}
+		if _, ok := unsupported[k]; ok || !inServices(k, services) || !inTypes(k, include, exclude) {
+			continue
+		}
+		if tagged != nil && !tagged.Matches(item.Tags, false) {
+			continue
+		}
+		if !window.Keep(item.Created()) {
+			continue
+		}
+		id := item.ResourceID
+		if nameIdentifiedTypes[item.ResourceType] {
+			id = item.ResourceName
+		}
+		if ignore.Skip(k, id, item.ARN) {
+			continue
+		}
+		// tags are passed to --name-template like the properties cloud control lists
+		properties, err := json.Marshal(map[string]interface{}{"Tags": item.Tags})
+		if err != nil {
+			return imports, err
+		}
+		listed := string(properties)
+		acct := accounts[item.AccountID]
+		parts := strings.Split(item.ResourceType, "::")
+		imports.Resources = append(imports.Resources, importSpec{
+			ID:       id,
+			Type:     k,
+			Provider: acct.provider,
+			Account:  acct.id,
+			Name:     collisions.unique(k, names.name(acct, parts[1], parts[2], id, &listed), id),
+		})
+	}
+
+	if len(missing) > 0 {
+		types := []string{}
+		for t := range missing {
+			types = append(types, t)
+		}
+		sort.Strings(types)
+		fmt.Printf("%d types of the inventory aren't in aws-native and are left out: %s\n", len(types), strings.Join(types, ", "))
+	}
+	collisions.report()
+	window.Report()
+	ignore.Report()
+	manifest.Identify("inventory", path)
+	return imports, manifest.Write()
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudcontrol"
+)
+
+// globalRegion is the region global services are served from.
+const globalRegion = "us-east-1"
+
+// globalServices are listed in globalRegion whatever region is scanned.
+var globalServices = []string{
+	"AWS::CloudFront::",
+	"AWS::IAM::",
+}
+
+// wafv2ScopedTypes are listed once per scope: REGIONAL in the scanned region, and CLOUDFRONT in
+// globalRegion.
+var wafv2ScopedTypes = map[string]bool{
+	"AWS::WAFv2::IPSet":           true,
+	"AWS::WAFv2::RegexPatternSet": true,
+	"AWS::WAFv2::RuleGroup":       true,
+	"AWS::WAFv2::WebACL":          true,
+}
+
+func isGlobalType(cfType string) bool {
+	for _, s := range globalServices {
+		if strings.HasPrefix(cfType, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// isGlobalResource reports whether the resource of cfType with the given identifier is served from
+// globalRegion. WAFv2 identifiers end in the scope, e.g. name|id|CLOUDFRONT.
+func isGlobalResource(cfType, id string) bool {
+	if wafv2ScopedTypes[cfType] {
+		return strings.HasSuffix(id, "|CLOUDFRONT")
+	}
+	return isGlobalType(cfType)
+}
+
+// regionalClients hands out a Cloud Control client per region, the scanned region being the empty
+// string. Each worker has clients of its own, so that the retryer of a client, which slows it down
+// when it gets throttled, only slows down the services of its worker. Like the workers' other
+// state, it is not safe for concurrent use. The rate of --aws-rate-limit is shared by the clients of
+// every worker though.
+type regionalClients struct {
+	cfg     aws.Config
+	clients map[string]*cloudcontrol.Client
+}
+
+func newRegionalClients(cfg aws.Config) *regionalClients {
+	return &regionalClients{cfg: cfg, clients: map[string]*cloudcontrol.Client{}}
+}
+
+func (c *regionalClients) get(region string) *cloudcontrol.Client {
+	if client, ok := c.clients[region]; ok {
+		return client
+	}
+	client := cloudcontrol.NewFromConfig(c.cfg, func(o *cloudcontrol.Options) {
+		if region != "" {
+			o.Region = region
+		}
+		o.APIOptions = append(o.APIOptions, limiter.addTo)
+	})
+	c.clients[region] = client
+	return client
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/pulumi/pulumi-cloud-import/pkg/redact"
+)
+
+// stateProperties are the properties the lifecycle state of a resource is found in, e.g. Status for
+// EKS clusters or State.Name for EC2 instances.
+var stateProperties = []string{"State", "Status", "InstanceState", "LifecycleState"}
+
+// resourceState returns the lifecycle state of a listed resource from its properties, or "" when
+// they don't tell.
+func resourceState(properties *string) string {
+	if properties == nil {
+		return ""
+	}
+	props := map[string]json.RawMessage{}
+	if err := json.Unmarshal([]byte(*properties), &props); err != nil {
+		return ""
+	}
+	for _, key := range stateProperties {
+		raw, ok := props[key]
+		if !ok {
+			continue
+		}
+		var state string
+		if err := json.Unmarshal(raw, &state); err == nil {
+			return state
+		}
+		var named struct {
+			Name string `json:"Name"`
+		}
+		if err := json.Unmarshal(raw, &named); err == nil && named.Name != "" {
+			return named.Name
+		}
+	}
+	return ""
+}
+
+// terminatedInstances are the EC2 instances shutting down or terminated, which are listed for about
+// an hour after they're terminated while ListResources doesn't return their state. They're listed
+// once, the first time an instance is discovered.
+type terminatedInstances struct {
+	once   sync.Once
+	cfg    aws.Config
+	states map[string]string
+}
+
+// state returns the state of the instance with the given ID if it is shutting down or terminated.
+func (t *terminatedInstances) state(id string) string {
+	t.once.Do(func() {
+		t.states = map[string]string{}
+		paginator := ec2.NewDescribeInstancesPaginator(ec2.NewFromConfig(t.cfg), &ec2.DescribeInstancesInput{
+			Filters: []types.Filter{{
+				Name:   aws.String("instance-state-name"),
+				Values: []string{string(types.InstanceStateNameShuttingDown), string(types.InstanceStateNameTerminated)},
+			}},
+		})
+		var err error
+		for paginator.HasMorePages() {
+			var page *ec2.DescribeInstancesOutput
+			page, err = paginator.NextPage(context.Background())
+			if err != nil {
+				break
+			}
+			for _, reservation := range page.Reservations {
+				for _, instance := range reservation.Instances {
+					if instance.State != nil {
+						t.states[aws.ToString(instance.InstanceId)] = string(instance.State.Name)
+					}
+				}
+			}
+		}
+		if err != nil {
+			redact.Println("Failed to list terminated EC2 instances, they will be imported with the others:", err)
+		}
+	})
+	return t.states[id]
+}
@@ -1,29 +1,64 @@
 package main
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
 	"net/http"
 	"os"
-	"regexp"
-	"strconv"
+	"path"
+	"sort"
 	"strings"
 	"sync"
 	"sync/atomic"
 
+	"github.com/pulumi/pulumi-cloud-import/pkg/aliases"
+	"github.com/pulumi/pulumi-cloud-import/pkg/backpressure"
+	"github.com/pulumi/pulumi-cloud-import/pkg/cancel"
+	"github.com/pulumi/pulumi-cloud-import/pkg/chaos"
+	"github.com/pulumi/pulumi-cloud-import/pkg/compress"
+	"github.com/pulumi/pulumi-cloud-import/pkg/config"
+	"github.com/pulumi/pulumi-cloud-import/pkg/costtags"
+	"github.com/pulumi/pulumi-cloud-import/pkg/coverage"
+	"github.com/pulumi/pulumi-cloud-import/pkg/created"
+	"github.com/pulumi/pulumi-cloud-import/pkg/debuglog"
+	"github.com/pulumi/pulumi-cloud-import/pkg/denied"
+	"github.com/pulumi/pulumi-cloud-import/pkg/events"
+	"github.com/pulumi/pulumi-cloud-import/pkg/fetch"
+	"github.com/pulumi/pulumi-cloud-import/pkg/hierarchy"
+	"github.com/pulumi/pulumi-cloud-import/pkg/ignore"
+	"github.com/pulumi/pulumi-cloud-import/pkg/ignorechanges"
+	"github.com/pulumi/pulumi-cloud-import/pkg/insights"
+	"github.com/pulumi/pulumi-cloud-import/pkg/manifest"
+	"github.com/pulumi/pulumi-cloud-import/pkg/offline"
+	"github.com/pulumi/pulumi-cloud-import/pkg/ownership"
+	"github.com/pulumi/pulumi-cloud-import/pkg/quarantine"
+	"github.com/pulumi/pulumi-cloud-import/pkg/recorder"
+	"github.com/pulumi/pulumi-cloud-import/pkg/redact"
+	"github.com/pulumi/pulumi-cloud-import/pkg/shard"
+	"github.com/pulumi/pulumi-cloud-import/pkg/stackchunk"
+	"github.com/pulumi/pulumi-cloud-import/pkg/stackstate"
+	"github.com/pulumi/pulumi-cloud-import/pkg/summary"
+	"github.com/pulumi/pulumi-cloud-import/pkg/supportbundle"
+	"github.com/pulumi/pulumi-cloud-import/pkg/terminal"
+	"github.com/pulumi/pulumi-cloud-import/pkg/version"
+	"github.com/pulumi/pulumi-cloud-import/pkg/workers"
 	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
 	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
 
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/client"
-	"github.com/aws/aws-sdk-go/aws/request"
-	"github.com/aws/aws-sdk-go/aws/session"
-	"github.com/aws/aws-sdk-go/service/cloudcontrolapi"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/cloudcontrol"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/aws/smithy-go/logging"
 )
 
 type importFile struct {
+	// Metadata tells which environment the import file was produced from, `pulumi import`
+	// ignores it
+	Metadata  *manifest.Header        `json:"metadata,omitempty"`
 	NameTable map[string]resource.URN `json:"nameTable"`
 	Resources []importSpec            `json:"resources"`
 }
@@ -37,6 +72,9 @@ type importSpec struct {
 	Version           string   `json:"version"`
 	PluginDownloadURL string   `json:"pluginDownloadUrl"`
 	Properties        []string `json:"properties"`
+	// Account is the account of the resource with --aws-organization, which splits the import
+	// file with --aws-split-accounts. It is not written to the import file.
+	Account string `json:"-"`
 }
 
 type Mode int64
@@ -46,10 +84,6 @@ const (
 	ReadMode
 )
 
-type CustomRetryer struct {
-	client.DefaultRetryer
-}
-
 // We download metadata from pulumi-aws-native to get supported types.
 // This sturct is only a subset of the full metadata.json
 type cfType struct {
@@ -59,47 +93,88 @@ type metadataResponse struct {
 	Resources map[string]cfType `json:"resources"`
 }
 
-// ShouldRetry overrides the SDK's built in DefaultRetryer adding customization
-// to not retry 500 internal server errors status codes.
-// TODO: some AWS services consistently return 500 internal server errors
-// when we hit the API. We shoudl open bugs against AWS for these.
-func (r CustomRetryer) ShouldRetry(req *request.Request) bool {
-	if req.HTTPResponse.StatusCode == 500 {
-		// Don't retry any 500 status codes.
-		return false
-	}
-
-	// Fallback to SDK's built in retry rules
-	return r.DefaultRetryer.ShouldRetry(req)
-}
+func main() {
+	version.HandleFlag(version.Schema{Name: "aws-native metadata", Version: awsNativeVersion})
+	config.HandleCommand()
+	supportbundle.Start()
+	defer supportbundle.Write()
 
-func debugLog(a ...any) {
-	if os.Getenv("PULUMI_CLOUD_IMPORT_DEBUG") != "" {
-		fmt.Println(a...)
+	isImportMode := isImportMode()
+	offline.Check(isImportMode)
+	if !isImportMode {
+		// the scan is recorded for the stacks the resources are split into to replay
+		stackchunk.Record()
 	}
-}
 
-func main() {
-	isImportMode := isImportMode()
+	// the SDK and the metadata download both use the default transport
+	http.DefaultTransport = denied.Wrap(workers.Wrap(chaos.Wrap(recorder.Wrap(http.DefaultTransport))))
 
 	// pulumi read resource mode
 	if !isImportMode {
-		pulumi.Run(func(ctx *pulumi.Context) error {
+		supportbundle.Run(func(ctx *pulumi.Context) error {
 			_, err := buildImportSpec(ctx, ReadMode)
-			return err
+			if err != nil {
+				return err
+			}
+			return quarantine.Save()
 		})
 	} else {
 		mode := ImportMode
-		imports, err := buildImportSpec(nil, mode)
+		// resources already in the stack given with --stack are left out of the import file
+		state, err := stackstate.FromFlags()
+		if err != nil {
+			panic(err)
+		}
+		// with --from-inventory, resources are found in an export rather than in the cloud
+		var imports importFile
+		if path := offline.Path(); path != "" {
+			imports, err = importFromInventory(path)
+		} else {
+			imports, err = buildImportSpec(nil, mode)
+		}
 		if err != nil {
 			panic(err)
 		}
+		if err := insights.Diff(imports.Resources, specRef); err != nil {
+			panic(err)
+		}
+		imports.Resources, imports.NameTable = stackstate.Filter(state, imports.Resources, specRef)
+
+		// resources of several accounts are imported with the provider of their account, which has
+		// to be in the stack
+		missing := map[string]bool{}
+		for i, r := range imports.Resources {
+			if r.Provider == "" {
+				continue
+			}
+			if urn, ok := state.Named(providerType, r.Provider); ok {
+				imports.NameTable[r.Provider] = urn
+				continue
+			}
+			if !missing[r.Provider] {
+				if state == nil {
+					fmt.Printf("Pass --stack to import resources with the %s provider of the stack, they will be imported with the default provider of whoever runs pulumi import\n", r.Provider)
+				} else {
+					fmt.Printf("No provider named %s in the stack, resources will be imported with the default provider of whoever runs pulumi import\n", r.Provider)
+				}
+			}
+			missing[r.Provider] = true
+			imports.Resources[i].Provider = ""
+		}
 		fmt.Printf("Total resources: %d", len(imports.Resources))
 
-		err = writeImportFile(imports)
+		imports.Metadata = manifest.NewHeader()
+		if config.AWSSplitAccounts.Bool() {
+			err = writeAccountImportFiles(imports)
+		} else {
+			err = writeImportFile(imports, config.Output.Value())
+		}
 		if err != nil {
 			panic(err)
 		}
+		if err := removeCheckpoint(); err != nil {
+			panic(err)
+		}
 	}
 }
 
@@ -114,134 +189,602 @@ func buildImportSpec(ctx *pulumi.Context, mode Mode) (importFile, error) {
 		Resources: []importSpec{},
 	}
 
-	r := CustomRetryer{
-		DefaultRetryer: client.DefaultRetryer{
-			NumMaxRetries: 1000,
-		},
+	cfg, err := loadAWSConfig()
+	if err != nil {
+		panic(err)
+	}
+
+	explicitModels, err := loadResourceModels()
+	if err != nil {
+		panic(err)
 	}
-	c := aws.NewConfig()
-	c.Retryer = r
-	if os.Getenv("PULUMI_CLOUD_IMPORT_DEBUG") != "" {
-		c.LogLevel = aws.LogLevel(aws.LogDebugWithHTTPBody)
+
+	if created.FromFlags() != nil {
+		fmt.Println("The cloud control API doesn't expose creation times, --created-after, --created-before, --newer-than and --older-than are ignored")
 	}
 
-	sess, err := session.NewSession(c)
+	unsupported, err := loadUnsupportedResources()
+	if err != nil {
+		panic(err)
+	}
+	services := config.Services.List()
+	include, exclude := config.Types.List(), config.ExcludeTypes.List()
+	for _, pattern := range append(include, exclude...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			panic(fmt.Sprintf("invalid type pattern %q: %v", pattern, err))
+		}
+	}
+	// types whose handlers failed in the last scans are skipped rather than failing again
+	learned := loadSkippedTypes()
+	learnedSkipped := []string{}
+	types := []string{}
+	all := []string{}
+	expensive := 0
+	for k := range *awsNativeTypesMap {
+		all = append(all, k)
+		if reason, ok := unsupported[k]; ok {
+			coverage.Skip(k, reason)
+			continue
+		}
+		if reason, ok := learned.skip(k); ok {
+			coverage.Skip(k, reason)
+			if inServices(k, services) && inTypes(k, include, exclude) {
+				learnedSkipped = append(learnedSkipped, k)
+			}
+			continue
+		}
+		if !inServices(k, services) || !inTypes(k, include, exclude) {
+			continue
+		}
+		if expensiveResources[k] && !config.IncludeExpensive.Bool() {
+			coverage.Skip(k, "expensive to list, pass --include-expensive to list it")
+			expensive++
+			continue
+		}
+		types = append(types, k)
+	}
+	if expensive > 0 {
+		fmt.Printf("%d types expensive to list are skipped, pass --include-expensive to list them\n", expensive)
+	}
+	learned.report(learnedSkipped)
+	types = canonicalTypes(types, *awsNativeTypesMap)
+	// previous tokens of renamed types are the same resources, they don't count as types to scan
+	coverage.Known(canonicalTypes(all, *awsNativeTypesMap)...)
+	// types the cloud control API can't list are found out from the registry rather than by failing
+	cache := loadTypeCache(cfg)
+	types = listableTypes(cfg, cache, types, *awsNativeTypesMap, explicitModels)
+	// with --aws-organization, the accounts of the organization are scanned one after the other
+	accounts, err := getAccounts(cfg)
 	if err != nil {
 		panic(err)
 	}
 
+	snapshot := &propertySnapshot{}
+	required := newRequiredProperties(cache, mode)
+	names := newNameTemplate()
+	collisions := newNameCollisions()
+	reader := hierarchy.NewReader()
+	// scans generating an import file can be resumed with --resume
+	var progress *checkpoint
+	if mode == ImportMode {
+		progress = openCheckpoint()
+		defer progress.close()
+		imports.Resources = append(imports.Resources, progress.recovered()...)
+		// resources discovered before the scan was resumed keep their names
+		for _, r := range progress.recovered() {
+			collisions.unique(r.Type, r.Name, r.ID)
+		}
+	}
 	var ops uint64
+	watchdog := backpressure.NewWatchdog()
 
-	importChan := make(chan importSpec, 100000)
-	var wg sync.WaitGroup
+	for _, acct := range accounts {
+		if acct.id != "" {
+			fmt.Printf("scanning account %s\n", acct.id)
+		}
+		cfg := acct.cfg
+		// types listed for a parent, like load balancer listeners, are scanned in a later phase than
+		// their parents so the parents' identifiers can be passed along and the children wired to them
+		discovered := newDiscoveredParents()
+		for _, r := range progress.recovered() {
+			if r.Account == acct.id {
+				discovered.add((*awsNativeTypesMap)[r.Type], r.ID, r.Name)
+			}
+		}
+		instances := &terminatedInstances{cfg: cfg}
+		tagged := newTagFilter(cfg)
+		vpcs := newVPCFilter(cfg)
+		managed := newManagedFilter(cfg)
+		defaults := newDefaultsFilter(cfg)
+		// with --backend resource-explorer, the resources of common types are found in seconds
+		inventory := newInventory(cfg, types, *awsNativeTypesMap)
+		// resources of several accounts are read with the provider of their account
+		var provider, globalProvider *pulumi.ProviderResourceState
+		if mode == ReadMode && acct.provider != "" {
+			provider = &pulumi.ProviderResourceState{}
+			if err := ctx.RegisterResource(providerType, acct.provider, acct.providerInputs(cfg.Region), provider); err != nil {
+				return imports, err
+			}
+		}
 
-	chunks := getConcurrentWorkers()
-	pkgChunks := make([][]string, chunks)
-	index := 0
-	// split input ino N chunks
-	for k := range *awsNativeTypesMap {
-		pkgChunks[index] = append(pkgChunks[index], k)
-		index++
-		index = index % chunks
-	}
-
-	for i := 0; i < chunks; i++ {
-		pkgs := pkgChunks[i]
-		wg.Add(1)
-		go func(pkgChunk []string, i int) {
-			defer func() {
-				if r := recover(); r != nil {
-					fmt.Printf("encountered error processing AWS resources: %v \n", r)
-				}
-			}()
-			defer wg.Done()
+		for _, phase := range discoveryPhases(types, *awsNativeTypesMap, explicitModels) {
+			importChan := make(chan importSpec, backpressure.MaxBuffer())
+			var wg sync.WaitGroup
 
-			// AWS clients are not safe for concurrent use by multiple goroutines.
-			client := cloudcontrolapi.New(sess)
+			chunks := getConcurrentWorkers()
+			pkgChunks := chunkByService(phase, *awsNativeTypesMap, chunks)
 
-			seen := map[string]bool{}
-			for _, k := range pkgChunk {
-				if _, ok := unsupportedResources[k]; ok {
-					continue
-				}
-				cloudControlType, ok := (*awsNativeTypesMap)[k]
-				if !ok {
-					fmt.Println("Type definition not found - skipping", k)
-					// This shouldn't happen
-					continue
-				}
-				parts := strings.Split(cloudControlType, "::")
-				params := &cloudcontrolapi.ListResourcesInput{
-					MaxResults: aws.Int64(100),
-					TypeName:   aws.String(cloudControlType),
-				}
-				err = client.ListResourcesPages(params,
-					func(page *cloudcontrolapi.ListResourcesOutput, lastPage bool) bool {
-						for _, r := range page.ResourceDescriptions {
-							key := clearString(*r.Identifier)
-							if seen[key] {
+			for i := 0; i < chunks; i++ {
+				pkgs := pkgChunks[i]
+				wg.Add(1)
+				go func(pkgChunk []string, i int) {
+					defer func() {
+						if r := recover(); r != nil {
+							redact.Printf("encountered error processing AWS resources: %v \n", r)
+						}
+					}()
+					defer wg.Done()
+
+					// AWS clients are not safe for concurrent use by multiple goroutines.
+					clients := newRegionalClients(cfg)
+
+					seen := map[string]bool{}
+					for _, k := range pkgChunk {
+						workers.Wait(i)
+						cloudControlType, ok := (*awsNativeTypesMap)[k]
+						if !ok {
+							fmt.Println("Type definition not found - skipping", k)
+							// This shouldn't happen
+							continue
+						}
+						if progress.listed(acct.id, k) {
+							// listed in full before the scan was resumed
+							coverage.Scanned(k)
+							discovered.markScanned(cloudControlType)
+							continue
+						}
+						parts := strings.Split(cloudControlType, "::")
+						events.Started(k)
+						count := 0
+						// some types can only be listed for a parent or with other required properties
+						models, err := resourceModels(clients, cloudControlType, explicitModels, discovered)
+						if err != nil {
+							redact.Println("Failed to list resources of type", k, err)
+							events.Error(k, err)
+							events.Finished(k, count)
+							coverage.Failed(k, err)
+							discovered.markScanned(cloudControlType)
+							continue
+						}
+						listed := true
+						ctx, done := cancel.Scope(k)
+						for _, model := range models {
+							if cancel.Cancelled(k) {
+								break
+							}
+							scope := listingScope(acct.id, k, model.Region, model.Model)
+							params := &cloudcontrol.ListResourcesInput{
+								MaxResults:    aws.Int32(100),
+								TypeName:      aws.String(cloudControlType),
+								ResourceModel: model.Model,
+								NextToken:     progress.resumeToken(scope),
+							}
+							onPage := func(page *cloudcontrol.ListResourcesOutput) bool {
+								debuglog.For(i+1, k).Println("listed", len(page.ResourceDescriptions), "resources in", model.Region)
+								for _, r := range page.ResourceDescriptions {
+									// identifiers are only unique within a type
+									key := cloudControlType + "|" + *r.Identifier
+									if seen[key] {
+										continue
+									}
+									seen[key] = true
+									if r.Identifier != nil {
+										// resources being deleted or terminated are still listed for a while
+										state := resourceState(r.Properties)
+										if state == "" && cloudControlType == "AWS::EC2::Instance" {
+											state = instances.state(*r.Identifier)
+										}
+										if terminal.IsTerminal(state) {
+											terminal.Skip(k, *r.Identifier, state)
+											continue
+										}
+										if ignore.Skip(k, *r.Identifier, resourceARN(r.Properties)) {
+											continue
+										}
+										if !tagged.keep(clients.get(model.Region), cloudControlType, model.Region, *r.Identifier, model.Parent, r.Properties) {
+											continue
+										}
+										if !vpcs.keep(clients.get(model.Region), cloudControlType, *r.Identifier, model.Parent, r.Properties) {
+											continue
+										}
+										if !managed.keep(cloudControlType, model.Region, *r.Identifier, r.Properties) {
+											continue
+										}
+										if !defaults.keep(cloudControlType, *r.Identifier) {
+											continue
+										}
+										resource := importSpec{
+											ID:       *r.Identifier,
+											Type:     k,
+											Parent:   model.Parent,
+											Provider: acct.provider,
+											Account:  acct.id,
+											// eg. name it S3Bucket<bucketName>, or after --name-template
+											Name: collisions.unique(k, names.name(acct, parts[1], parts[2], *r.Identifier, r.Properties), *r.Identifier),
+										}
+										if progress.has(resource) {
+											continue
+										}
+										resource.Properties = required.properties(clients.get(model.Region), k, cloudControlType, resource.ID)
+										discovered.add(cloudControlType, resource.ID, resource.Name)
+										tags := resourceTags(r.Properties)
+										owner, evidence := ownership.Classify(tags)
+										ownership.Add(resource.Type, resource.Name, resource.ID, owner, evidence)
+										costtags.Add(resource.Type, resource.Name, resource.ID, tags)
+										snapshot.add(clients.get(model.Region), cloudControlType, model.Region, resource, r.Properties)
+										atomic.AddUint64(&ops, 1)
+										debuglog.For(i+1, k).Println("count:", atomic.LoadUint64(&ops))
+										count++
+										events.Discovered(k, resource.Type, resource.Name, resource.ID)
+										progress.add(resource)
+										watchdog.Wait(func() int { return len(importChan) })
+										importChan <- resource
+									}
+								}
+								progress.page(scope, page.NextToken)
+								return true
+							}
+							if page, ok := inventory.page(cloudControlType); ok && model.Model == nil && model.Parent == "" {
+								onPage(page)
 								continue
 							}
-							seen[key] = true
-							if r.Identifier != nil {
-								resource := importSpec{
-									ID:   *r.Identifier,
-									Type: k,
-									// eg. name it S3Bucket<bucketName>
-									Name: clearString(fmt.Sprintf("%s%s%s", parts[1], parts[2], *r.Identifier)),
+							// global services are listed in us-east-1, whatever region is scanned
+							err = listResources(ctx, clients.get(model.Region), params, onPage)
+							if err != nil && params.NextToken != nil && !cancel.Cancelled(k) {
+								// the page token of a resumed listing expired, it is listed again
+								params.NextToken = nil
+								err = listResources(ctx, clients.get(model.Region), params, onPage)
+							}
+							if cancel.Cancelled(k) {
+								break
+							}
+
+							// just print out errors as info for now
+							// as there are some resources that don't support ListResources
+							// or have special auth requirements.
+							if err != nil {
+								redact.Println("Failed to list resources of type", k, err)
+								events.Error(k, err)
+								// the types remembered are the ones of the account of the credentials
+								if acct.roleARN == "" {
+									cache.listFailed(k, err)
 								}
-								atomic.AddUint64(&ops, 1)
-								debugLog("worker:", i+1, "count:", atomic.LoadUint64(&ops))
-								importChan <- resource
+								learned.listFailed(k, err)
+								coverage.Failed(k, err)
+								listed = false
 							}
 						}
-						return true
-					})
+						done()
+						events.Finished(k, count)
+						switch {
+						case cancel.Cancelled(k):
+							coverage.Skip(k, "cancelled with --cancel-file")
+						case listed:
+							coverage.Scanned(k)
+							learned.listSucceeded(k)
+							progress.finish(acct.id, k)
+						}
+						discovered.markScanned(cloudControlType)
+					}
+					fmt.Printf("worker %d of %d completed\n", i+1, chunks)
+				}(pkgs, i)
+			}
 
-				// just print out errors as info for now
-				// as there are some resources that don't support ListResources
-				// or have special auth requirements.
-				if err != nil {
-					fmt.Println("Failed to list resources of type", k, err)
+			go func() {
+				wg.Wait()
+				close(importChan)
+			}()
+
+			for resource := range importChan {
+				imports.Resources = append(imports.Resources, resource)
+				previous := aliases.Record(resource.Type, resource.ID, resource.Name)
+				if mode == ReadMode {
+					opts := []pulumi.ResourceOption{}
+					// global resources can only be read from us-east-1
+					if isGlobalResource((*awsNativeTypesMap)[resource.Type], resource.ID) && cfg.Region != globalRegion {
+						if globalProvider == nil {
+							globalProvider = &pulumi.ProviderResourceState{}
+							name := globalRegion
+							if acct.provider != "" {
+								name = acct.provider + "-" + globalRegion
+							}
+							err := ctx.RegisterResource(providerType, name, acct.providerInputs(globalRegion), globalProvider)
+							if err != nil {
+								return imports, err
+							}
+						}
+						opts = append(opts, pulumi.Provider(globalProvider))
+					} else if provider != nil {
+						opts = append(opts, pulumi.Provider(provider))
+					}
+					opts = append(opts, ignorechanges.Options(resource.Type)...)
+					opts = append(opts, aliases.Options(previous)...)
+					resource := resource
+					reader.Read(resource.Name, resource.Parent, func(parent ...pulumi.ResourceOption) pulumi.Resource {
+						var res pulumi.CustomResourceState
+						// resources failing to register or to be read are set aside in quarantine.json
+						if err := ctx.ReadResource(resource.Type, resource.Name, pulumi.ID(resource.ID), nil, &res, append(opts, parent...)...); err != nil {
+							quarantine.Add(resource, quarantine.StageRead, err)
+							return nil
+						}
+						quarantine.Watch(resource, &res, func(err error) { learned.readFailed(resource.Type, err) })
+						return &res
+					})
 				}
+
 			}
-			fmt.Printf("worker %d of %d completed\n", i+1, chunks)
-		}(pkgs, i)
+		}
+		tagged.report()
+		vpcs.report()
+		managed.report()
+		defaults.report()
 	}
 
-	go func() {
-		wg.Wait()
-		close(importChan)
-	}()
+	if mode == ReadMode {
+		reader.Flush()
+		// the run of the first stack reports on the scan and updates the others
+		if stackchunk.Additional() {
+			return imports, nil
+		}
+		if err := stackchunk.Rotate(ctx, reader.Chunks()); err != nil {
+			return imports, err
+		}
+	}
 
-	for resource := range importChan {
-		imports.Resources = append(imports.Resources, resource)
-		if mode == ReadMode {
-			var res pulumi.CustomResourceState
-			// currently ignore errors
-			_ = ctx.ReadResource(resource.Type, resource.Name, pulumi.ID(resource.ID), nil, &res)
+	if err := cache.save(); err != nil {
+		return imports, err
+	}
+	// the types whose resources failed to read are learned once every read completed
+	quarantine.Wait()
+	if err := learned.save(); err != nil {
+		return imports, err
+	}
+	if err := snapshot.write(); err != nil {
+		return imports, err
+	}
+	required.report()
+	collisions.report()
+	terminal.Report()
+	ignore.Report()
+	if err := summary.Report(); err != nil {
+		return imports, err
+	}
+	manifest.Identify("region", cfg.Region)
+	if config.AWSOrganization.Bool() {
+		ids := make([]string, len(accounts))
+		for i, a := range accounts {
+			ids[i] = a.id
 		}
+		manifest.Identify("accounts", strings.Join(ids, ","))
+	}
+	if err := manifest.Write(); err != nil {
+		return imports, err
+	}
+	if err := coverage.Report(); err != nil {
+		return imports, err
+	}
+	if err := aliases.Write(); err != nil {
+		return imports, err
+	}
+	if err := costtags.Report(); err != nil {
+		return imports, err
+	}
+	if err := denied.Report(); err != nil {
+		return imports, err
+	}
+	return imports, ownership.Report()
+}
+
+// loadAWSConfig loads the configuration of the clients from the environment and the shared config
+// files like the AWS CLI does, the region being overridden by --region.
+func loadAWSConfig() (aws.Config, error) {
+	opts := []func(*awsconfig.LoadOptions) error{
+		// requests go through the default transport as wrapped in main, the SDK has a transport of
+		// its own otherwise
+		awsconfig.WithHTTPClient(&http.Client{}),
+		awsconfig.WithRetryer(newRetryer),
+	}
+	if config.Debug.Bool() {
+		opts = append(opts, awsconfig.WithClientLogMode(aws.LogRequestWithBody|aws.LogResponseWithBody|aws.LogRetries))
+		if redact.Enabled() {
+			opts = append(opts, awsconfig.WithLogger(logging.LoggerFunc(func(classification logging.Classification, format string, v ...interface{}) {
+				redact.Printf(format+"\n", v...)
+			})))
+		}
+	}
+	if region := config.AWSRegion.Value(); region != "" {
+		opts = append(opts, awsconfig.WithRegion(region))
+	}
+	if recorder.Replaying() {
+		// recorded responses are served without calling AWS, but requests still need to be signed
+		opts = append(opts, awsconfig.WithCredentialsProvider(credentials.NewStaticCredentialsProvider("replay", "replay", "")))
+	}
+	cfg, err := awsconfig.LoadDefaultConfig(context.Background(), opts...)
+	if err != nil {
+		return cfg, err
+	}
+	// resources are discovered with the scan role, while the providers of the stack keep the
+	// credentials of the environment
+	if role := config.AWSScanRole.Value(); role != "" && !recorder.Replaying() {
+		cfg.Credentials = aws.NewCredentialsCache(stscreds.NewAssumeRoleProvider(sts.NewFromConfig(cfg), role))
+	}
+	return cfg, nil
+}
+
+// inServices reports whether the type token belongs to one of the services passed with --services,
+// e.g. "s3" matches every aws-native:s3:* type. All types match when no services are passed.
+func inServices(token string, services []string) bool {
+	if len(services) == 0 {
+		return true
+	}
+	for _, s := range services {
+		if strings.HasPrefix(token, "aws-native:"+strings.ToLower(s)+":") {
+			return true
+		}
+	}
+	return false
+}
+
+// inTypes reports whether the type token matches one of the patterns passed with --types and none of
+// the ones passed with --exclude-types, e.g. aws-native:s3:*. All types match when no --types are
+// passed.
+func inTypes(token string, include, exclude []string) bool {
+	for _, p := range exclude {
+		if ok, _ := path.Match(p, token); ok {
+			return false
+		}
+	}
+	if len(include) == 0 {
+		return true
+	}
+	for _, p := range include {
+		if ok, _ := path.Match(p, token); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// awsNativeVersion is the pulumi-aws-native branch or tag the metadata is downloaded from. Pin it at
+// build time with -ldflags "-X main.awsNativeVersion=v0.60.0".
+var awsNativeVersion = "master"
+
+// chunkByService splits types into chunks, one per worker, keeping the types of a service in the
+// same chunk. AWS throttles requests per service, so listing several types of a service at once
+// only multiplies throttling and retries. Services with the most types are assigned first, each
+// to the chunk with the fewest types so far.
+func chunkByService(types []string, typesMap map[string]string, chunks int) [][]string {
+	byService := map[string][]string{}
+	for _, k := range types {
+		parts := strings.Split(typesMap[k], "::")
+		service := k
+		if len(parts) == 3 {
+			service = parts[1]
+		}
+		byService[service] = append(byService[service], k)
+	}
+	services := make([]string, 0, len(byService))
+	for s := range byService {
+		services = append(services, s)
+	}
+	sort.Slice(services, func(i, j int) bool {
+		if len(byService[services[i]]) != len(byService[services[j]]) {
+			return len(byService[services[i]]) > len(byService[services[j]])
+		}
+		return services[i] < services[j]
+	})
+
+	pkgChunks := make([][]string, chunks)
+	for _, s := range services {
+		smallest := 0
+		for i := range pkgChunks {
+			if len(pkgChunks[i]) < len(pkgChunks[smallest]) {
+				smallest = i
+			}
+		}
+		pkgChunks[smallest] = append(pkgChunks[smallest], byService[s]...)
+	}
+	return pkgChunks
+}
+
+// resourceTags returns the tags in the properties returned by the cloud control API. Most types
+// have a list of key/value pairs, some a map.
+func resourceTags(properties *string) map[string]string {
+	tags := map[string]string{}
+	if properties == nil {
+		return tags
+	}
+	var props struct {
+		Tags json.RawMessage `json:"Tags"`
+	}
+	if err := json.Unmarshal([]byte(*properties), &props); err != nil || props.Tags == nil {
+		return tags
+	}
+	var list []struct {
+		Key   string `json:"Key"`
+		Value string `json:"Value"`
+	}
+	if err := json.Unmarshal(props.Tags, &list); err == nil {
+		for _, t := range list {
+			tags[t.Key] = t.Value
+		}
+		return tags
+	}
+	_ = json.Unmarshal(props.Tags, &tags)
+	return tags
+}
 
+// resourceARN returns the ARN of a listed resource from its properties, or "" when it has none. The
+// identifiers of most types aren't ARNs, e.g. IAM roles are identified by their name.
+func resourceARN(properties *string) string {
+	if properties == nil {
+		return ""
+	}
+	var props struct {
+		Arn string `json:"Arn"`
 	}
+	_ = json.Unmarshal([]byte(*properties), &props)
+	return props.Arn
+}
 
-	return imports, nil
+// canonicalTypes keeps a single token per CloudFormation type. aws-native keeps the previous token
+// of renamed resources as an alias, and listing both would emit every resource twice. The token
+// named after the CloudFormation type is kept, e.g. aws-native:s3:Bucket for AWS::S3::Bucket.
+func canonicalTypes(types []string, typesMap map[string]string) []string {
+	sort.Strings(types)
+	byCFType := map[string]string{}
+	for _, k := range types {
+		cfType := typesMap[k]
+		current, ok := byCFType[cfType]
+		if ok && (current == cfTypeToken(cfType) || k != cfTypeToken(cfType)) {
+			debuglog.For(0, k).Println("skipping, alias of", current)
+			continue
+		}
+		if ok {
+			debuglog.For(0, current).Println("skipping, alias of", k)
+		}
+		byCFType[cfType] = k
+	}
+
+	canonical := []string{}
+	for _, k := range types {
+		if byCFType[typesMap[k]] == k {
+			canonical = append(canonical, k)
+		}
+	}
+	return canonical
+}
+
+// cfTypeToken returns the token named after a CloudFormation type.
+func cfTypeToken(cfType string) string {
+	parts := strings.Split(cfType, "::")
+	if len(parts) != 3 {
+		return ""
+	}
+	return "aws-native:" + strings.ToLower(parts[1]) + ":" + parts[2]
 }
 
 // download https://raw.githubusercontent.com/pulumi/pulumi-aws-native/master/provider/cmd/pulumi-resource-aws-native/metadata.json
 // and parse it into a metadataResponse struct
 func getAWSNativeMetadata() (*map[string]string, error) {
-	metadataURL := "https://raw.githubusercontent.com/pulumi/pulumi-aws-native/master/provider/cmd/pulumi-resource-aws-native/metadata.json"
+	metadataURL := "https://raw.githubusercontent.com/pulumi/pulumi-aws-native/" + awsNativeVersion + "/provider/cmd/pulumi-resource-aws-native/metadata.json"
 
-	resp, err := http.Get(metadataURL)
+	respByte, err := fetch.Get(context.Background(), metadataURL)
 	if err != nil {
 		return nil, err
 	}
 
-	defer resp.Body.Close()
 	var schema metadataResponse
-	buf := new(bytes.Buffer)
-	buf.ReadFrom(resp.Body)
-	respByte := buf.Bytes()
 	if err := json.Unmarshal(respByte, &schema); err != nil {
 		return nil, err
 	}
@@ -255,22 +798,62 @@ func getAWSNativeMetadata() (*map[string]string, error) {
 	return &typeMap, nil
 }
 
-// write import file to disk
-func writeImportFile(imports importFile) error {
-	// write the import file to disk
-	importFile, err := json.MarshalIndent(imports, "", "    ")
-	if err != nil {
-		return err
-	}
+// write import file to disk, split into shards when --shards is passed
+func writeImportFile(imports importFile, path string) error {
+	shards := shard.Split(imports.Resources, specRef)
+	for i, resources := range shards {
+		f := importFile{Metadata: imports.Metadata, NameTable: imports.NameTable, Resources: resources}
+		// write the import file to disk
+		importFile, err := json.MarshalIndent(f, "", "    ")
+		if err != nil {
+			return err
+		}
 
-	err = ioutil.WriteFile("import.json", importFile, 0644)
-	if err != nil {
-		return err
+		err = compress.WriteFile(shard.Path(path, i, len(shards)), importFile)
+		if err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
 
+// writeAccountImportFiles writes the resources of each account to an import file of its own, with
+// --aws-split-accounts.
+func writeAccountImportFiles(imports importFile) error {
+	byAccount := map[string][]importSpec{}
+	for _, r := range imports.Resources {
+		byAccount[r.Account] = append(byAccount[r.Account], r)
+	}
+	for account, resources := range byAccount {
+		path := config.Output.Value()
+		if account != "" {
+			path = accountPath(path, account)
+		}
+		// the header of each file names its account rather than all of them
+		header := *imports.Metadata
+		header.Identity = map[string]string{}
+		for key, value := range imports.Metadata.Identity {
+			if key != "accounts" {
+				header.Identity[key] = value
+			}
+		}
+		if account != "" {
+			header.Identity["account"] = account
+		}
+		f := importFile{Metadata: &header, NameTable: imports.NameTable, Resources: resources}
+		if err := writeImportFile(f, path); err != nil {
+			return err
+		}
+		fmt.Printf("\n%d resources of account %s written to %s", len(resources), account, compress.Path(path))
+	}
+	return nil
+}
+
+func specRef(r importSpec) stackstate.Ref {
+	return stackstate.Ref{Type: r.Type, ID: r.ID, Name: r.Name, Parent: r.Parent}
+}
+
 // check for presence of --import flag
 func isImportMode() bool {
 	for _, arg := range os.Args {
@@ -281,17 +864,8 @@ func isImportMode() bool {
 	return false
 }
 
-// getConcurrentWorkers the number of workers specified in PULUMI_CLOUD_IMPORT_WORKERS or returns a default of 3
+// getConcurrentWorkers returns the number of workers specified in PULUMI_CLOUD_IMPORT_WORKERS, or a
+// default of 3 due to the rate limits of the cloud control API
 func getConcurrentWorkers() int {
-	workers, err := strconv.Atoi(os.Getenv("PULUMI_CLOUD_IMPORT_WORKERS"))
-	if err != nil {
-		return 10
-	}
-	return workers
-}
-
-var nonAlphanumericRegex = regexp.MustCompile(`[^a-zA-Z0-9 ]+`)
-
-func clearString(str string) string {
-	return nonAlphanumericRegex.ReplaceAllString(str, "")
+	return workers.Count(3)
 }
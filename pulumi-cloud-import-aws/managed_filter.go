@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	"github.com/pulumi/pulumi-cloud-import/pkg/config"
+	"github.com/pulumi/pulumi-cloud-import/pkg/redact"
+)
+
+// stackIDTag is the tag CloudFormation puts on the resources of a stack it can tag.
+const stackIDTag = "aws:cloudformation:stack-id"
+
+// managedFilter leaves out the resources managed by CloudFormation with --exclude-managed, the CDK
+// deploying through CloudFormation too, so that infrastructure already under another tool isn't pulled
+// into a Pulumi stack. A resource is managed when it has the aws:cloudformation:stack-id tag, or when
+// it's the physical resource of one of the stacks of its region, as listed with ListStackResources
+// for the resources CloudFormation doesn't tag. The stacks themselves are left out too.
+type managedFilter struct {
+	cfg aws.Config
+
+	mu sync.Mutex
+	// physical are the physical IDs of the resources of the stacks of each region, by type
+	physical map[string]map[string]map[string]bool
+	leftOut  int
+}
+
+// newManagedFilter returns the filter of the resources of the account cfg points to, nil without
+// --exclude-managed.
+func newManagedFilter(cfg aws.Config) *managedFilter {
+	if !config.ExcludeManaged.Bool() {
+		return nil
+	}
+	return &managedFilter{cfg: cfg, physical: map[string]map[string]map[string]bool{}}
+}
+
+// keep reports whether the resource of cfType identified by id, listed in region with properties,
+// isn't managed by CloudFormation.
+func (f *managedFilter) keep(cfType, region, id string, properties *string) bool {
+	if f == nil {
+		return true
+	}
+	managed := cfType == "AWS::CloudFormation::Stack" || resourceTags(properties)[stackIDTag] != ""
+	if !managed {
+		physical := f.stackResources(region)[cfType]
+		arn := resourceARN(properties)
+		managed = physical[id] || (arn != "" && physical[arn])
+		for _, part := range strings.Split(id, "|") {
+			managed = managed || physical[part]
+		}
+	}
+	if managed {
+		f.mu.Lock()
+		f.leftOut++
+		f.mu.Unlock()
+	}
+	return !managed
+}
+
+// stackResources returns the physical IDs of the resources of the stacks of region by type, listing
+// them once per region.
+func (f *managedFilter) stackResources(region string) map[string]map[string]bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if physical, ok := f.physical[region]; ok {
+		return physical
+	}
+	physical := map[string]map[string]bool{}
+	if err := f.load(region, physical); err != nil {
+		redact.Println("Failed to list the resources of the CloudFormation stacks, only the tagged ones are left out:", err)
+	}
+	f.physical[region] = physical
+	return physical
+}
+
+// load lists the resources of the stacks of region into physical.
+func (f *managedFilter) load(region string, physical map[string]map[string]bool) error {
+	ctx := context.Background()
+	client := cloudformation.NewFromConfig(f.cfg, func(o *cloudformation.Options) {
+		if region != "" {
+			o.Region = region
+		}
+	})
+	stacks := cloudformation.NewListStacksPaginator(client, &cloudformation.ListStacksInput{})
+	for stacks.HasMorePages() {
+		page, err := stacks.NextPage(ctx)
+		if err != nil {
+			return err
+		}
+		for _, s := range page.StackSummaries {
+			// deleted stacks are listed for 90 days
+			if s.StackStatus == types.StackStatusDeleteComplete {
+				continue
+			}
+			resources := cloudformation.NewListStackResourcesPaginator(client, &cloudformation.ListStackResourcesInput{StackName: s.StackId})
+			for resources.HasMorePages() {
+				page, err := resources.NextPage(ctx)
+				if err != nil {
+					return fmt.Errorf("failed to list the resources of %s: %w", aws.ToString(s.StackName), err)
+				}
+				for _, r := range page.StackResourceSummaries {
+					if r.PhysicalResourceId == nil {
+						continue
+					}
+					cfType := aws.ToString(r.ResourceType)
+					if physical[cfType] == nil {
+						physical[cfType] = map[string]bool{}
+					}
+					physical[cfType][aws.ToString(r.PhysicalResourceId)] = true
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// report prints how many resources managed by CloudFormation were left out.
+func (f *managedFilter) report() {
+	if f == nil {
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	fmt.Printf("%d resources managed by CloudFormation were left out\n", f.leftOut)
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/pulumi/pulumi-cloud-import/pkg/naming"
+	"github.com/pulumi/pulumi-cloud-import/pkg/redact"
+)
+
+// renamedResource is a resource whose name collided with the name of another resource of its type.
+type renamedResource struct {
+	typ, id, name, renamed string
+}
+
+// nameCollisions tells apart the resources of a type whose names collide once stripped of the
+// characters names don't allow, e.g. the buckets my-bucket and mybucket, which would otherwise
+// shadow each other at import and read time. The resource seen first keeps the name, the others get
+// a short hash of their identifier appended. Renamed resources are reported at the end of the scan.
+type nameCollisions struct {
+	mu sync.Mutex
+	// ids are the identifiers of the resources by type and name
+	ids     map[string]string
+	renamed []renamedResource
+}
+
+func newNameCollisions() *nameCollisions {
+	return &nameCollisions{ids: map[string]string{}}
+}
+
+// unique returns the name of the resource of typ identified by id, name unless another resource of
+// typ already has it.
+func (c *nameCollisions) unique(typ, name, id string) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	owner, ok := c.ids[typ+"|"+name]
+	if !ok || owner == id {
+		c.ids[typ+"|"+name] = id
+		return name
+	}
+	renamed := naming.AWS.Disambiguate(name, id)
+	if c.ids[typ+"|"+renamed] == id {
+		return renamed
+	}
+	c.ids[typ+"|"+renamed] = id
+	c.renamed = append(c.renamed, renamedResource{typ: typ, id: id, name: name, renamed: renamed})
+	return renamed
+}
+
+// report prints the resources that were renamed, and the resource whose name they collided with.
+func (c *nameCollisions) report() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.renamed) == 0 {
+		return
+	}
+	sort.Slice(c.renamed, func(i, j int) bool {
+		if c.renamed[i].typ != c.renamed[j].typ {
+			return c.renamed[i].typ < c.renamed[j].typ
+		}
+		return c.renamed[i].id < c.renamed[j].id
+	})
+	fmt.Printf("%d resources were renamed, their names colliding with the names of other resources of their type:\n", len(c.renamed))
+	for _, r := range c.renamed {
+		redact.Printf("  %s %s: %s, %s is named %s\n", r.typ, r.id, r.renamed, c.ids[r.typ+"|"+r.name], r.name)
+	}
+}
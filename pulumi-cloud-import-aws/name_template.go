@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/pulumi/pulumi-cloud-import/pkg/config"
+	"github.com/pulumi/pulumi-cloud-import/pkg/naming"
+)
+
+// nameFields are the fields of the resources --name-template is executed with.
+type nameFields struct {
+	// Service and Type are the parts of the type token, e.g. s3 and Bucket
+	Service string
+	Type    string
+	// ID is the cloud control identifier, and ShortID its last segment, e.g. the name at the end of
+	// an ARN
+	ID      string
+	ShortID string
+	// Tag are the tags of the resource, as listed
+	Tag map[string]string
+}
+
+// nameTemplate names resources with --name-template rather than after their type and identifier,
+// which are unreadable for the types identified by an ARN, e.g. {{.Type}}-{{.Tag.Name}}-{{.ShortID}}.
+// Missing tags are empty, and resources whose name comes out empty are named as usual. Tags are the
+// ones ListResources returns.
+type nameTemplate struct {
+	tmpl *template.Template
+}
+
+// newNameTemplate parses --name-template, nil when it isn't passed. It panics on templates that fail
+// to parse or to execute.
+func newNameTemplate() *nameTemplate {
+	text := config.NameTemplate.Value()
+	if text == "" {
+		return nil
+	}
+	tmpl, err := template.New("name").Option("missingkey=zero").Parse(text)
+	if err != nil {
+		panic(fmt.Sprintf("invalid --name-template: %v", err))
+	}
+	sample := nameFields{Service: "s3", Type: "Bucket", ID: "bucket", ShortID: "bucket", Tag: map[string]string{}}
+	if err := tmpl.Execute(&strings.Builder{}, sample); err != nil {
+		panic(fmt.Sprintf("invalid --name-template: %v", err))
+	}
+	return &nameTemplate{tmpl: tmpl}
+}
+
+// name returns the name of the resource of account a identified by id, from the template or, without
+// one, from its type and identifier.
+func (t *nameTemplate) name(a *account, service, typ, id string, properties *string) string {
+	if t != nil {
+		fields := nameFields{Service: service, Type: typ, ID: id, ShortID: shortID(id), Tag: resourceTags(properties)}
+		var b strings.Builder
+		if err := t.tmpl.Execute(&b, fields); err == nil && strings.Trim(b.String(), " -_.") != "" {
+			return naming.AWSTemplate.Name(a.prefix, b.String())
+		}
+	}
+	return a.name(service, typ, id)
+}
+
+// shortID returns the last segment of an identifier: the resource at the end of an ARN, or the last
+// part of a composite identifier.
+func shortID(id string) string {
+	id = id[strings.LastIndex(id, "|")+1:]
+	if strings.HasPrefix(id, "arn:") {
+		id = id[strings.LastIndexAny(id, ":/")+1:]
+	}
+	return id
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	"github.com/aws/aws-sdk-go-v2/service/cloudcontrol"
+	"github.com/aws/smithy-go/middleware"
+	"github.com/pulumi/pulumi-cloud-import/pkg/config"
+	"golang.org/x/time/rate"
+)
+
+// serviceKey is the stack value the service of a Cloud Control request is passed along with.
+type serviceKey struct{}
+
+// serviceLimiter holds the Cloud Control requests of every worker to the rate of --aws-rate-limit,
+// with a token bucket per service and region, so that scans stay under the rate the cloud control
+// API throttles at rather than get throttled and back off. The rate a service is throttled at
+// depends on the service the type belongs to, e.g. ec2 for AWS::EC2::VPC, and so is the bucket a
+// request takes a token from. Buckets hold a second of requests, allowing short bursts.
+type serviceLimiter struct {
+	// rate applies to the services missing from rates
+	rate  rate.Limit
+	rates map[string]rate.Limit
+
+	mu       sync.Mutex
+	limiters map[string]*rate.Limiter
+}
+
+// limiter is shared by the Cloud Control clients of every worker, nil without --aws-rate-limit.
+var limiter = newServiceLimiter()
+
+// newServiceLimiter parses --aws-rate-limit, a rate for every service and service=rate pairs for
+// the others. It returns nil when it isn't given, and panics on malformed rates.
+func newServiceLimiter() *serviceLimiter {
+	values := config.AWSRateLimit.List()
+	if len(values) == 0 {
+		return nil
+	}
+	l := &serviceLimiter{rate: rate.Inf, rates: map[string]rate.Limit{}, limiters: map[string]*rate.Limiter{}}
+	for _, v := range values {
+		service, value, ok := strings.Cut(v, "=")
+		if !ok {
+			service, value = "", v
+		}
+		r, err := strconv.ParseFloat(value, 64)
+		if err != nil || r <= 0 {
+			panic(fmt.Sprintf("%s must be requests per second such as 5 or ec2=2, got %q", config.AWSRateLimit.Flag(), v))
+		}
+		if service == "" {
+			l.rate = rate.Limit(r)
+		} else {
+			l.rates[strings.ToLower(service)] = rate.Limit(r)
+		}
+	}
+	return l
+}
+
+// wait blocks until the bucket of service in region has a token for a request.
+func (l *serviceLimiter) wait(ctx context.Context, service, region string) error {
+	limit, ok := l.rates[service]
+	if !ok {
+		limit = l.rate
+	}
+	if limit == rate.Inf {
+		return nil
+	}
+	l.mu.Lock()
+	key := service + "|" + region
+	bucket, ok := l.limiters[key]
+	if !ok {
+		burst := int(limit)
+		if burst < 1 {
+			burst = 1
+		}
+		bucket = rate.NewLimiter(limit, burst)
+		l.limiters[key] = bucket
+	}
+	l.mu.Unlock()
+	return bucket.Wait(ctx)
+}
+
+// addTo adds the middlewares limiting the requests of a client to its stack: the service of a
+// request is found out from the type in its input, and every attempt, retries included, waits for a
+// token.
+func (l *serviceLimiter) addTo(stack *middleware.Stack) error {
+	if l == nil {
+		return nil
+	}
+	err := stack.Initialize.Add(middleware.InitializeMiddlewareFunc("ServiceRateLimitType",
+		func(ctx context.Context, in middleware.InitializeInput, next middleware.InitializeHandler) (middleware.InitializeOutput, middleware.Metadata, error) {
+			var typeName *string
+			switch params := in.Parameters.(type) {
+			case *cloudcontrol.ListResourcesInput:
+				typeName = params.TypeName
+			case *cloudcontrol.GetResourceInput:
+				typeName = params.TypeName
+			}
+			if typeName != nil {
+				ctx = middleware.WithStackValue(ctx, serviceKey{}, typeService(*typeName))
+			}
+			return next.HandleInitialize(ctx, in)
+		}), middleware.After)
+	if err != nil {
+		return err
+	}
+	return stack.Finalize.Insert(middleware.FinalizeMiddlewareFunc("ServiceRateLimit",
+		func(ctx context.Context, in middleware.FinalizeInput, next middleware.FinalizeHandler) (middleware.FinalizeOutput, middleware.Metadata, error) {
+			if service, ok := middleware.GetStackValue(ctx, serviceKey{}).(string); ok {
+				if err := l.wait(ctx, service, awsmiddleware.GetRegion(ctx)); err != nil {
+					return middleware.FinalizeOutput{}, middleware.Metadata{}, err
+				}
+			}
+			return next.HandleFinalize(ctx, in)
+		}), "Retry", middleware.After)
+}
+
+// typeService returns the service of a CloudFormation type, e.g. ec2 for AWS::EC2::VPC.
+func typeService(cfType string) string {
+	parts := strings.Split(cfType, "::")
+	if len(parts) < 2 {
+		return ""
+	}
+	return strings.ToLower(parts[1])
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
+	cftypes "github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	"github.com/pulumi/pulumi-cloud-import/pkg/coverage"
+	"github.com/pulumi/pulumi-cloud-import/pkg/debuglog"
+)
+
+// registryWorkers is the number of types described at once, the CloudFormation API throttles
+// DescribeType well before the cloud control API.
+const registryWorkers = 5
+
+// registrySchema is the subset of a CloudFormation resource schema telling whether and how a type
+// can be listed, and which of its properties are required.
+type registrySchema struct {
+	Required             []string `json:"required"`
+	CreateOnlyProperties []string `json:"createOnlyProperties"`
+	Handlers             map[string]struct {
+		HandlerSchema *struct {
+			Required []string `json:"required"`
+		} `json:"handlerSchema"`
+	} `json:"handlers"`
+}
+
+// unlistableReason returns why the cloud control API can't list a type, from its description in
+// the CloudFormation registry, or "" when it can.
+func unlistableReason(out *cloudformation.DescribeTypeOutput, cfType string, explicit map[string][]*string) string {
+	if out.ProvisioningType == cftypes.ProvisioningTypeNonProvisionable {
+		return "not provisionable through the cloud control API"
+	}
+	var schema registrySchema
+	if err := json.Unmarshal([]byte(aws.ToString(out.Schema)), &schema); err != nil {
+		// a schema that can't be parsed doesn't tell anything, the type is listed anyway
+		return ""
+	}
+	list, ok := schema.Handlers["list"]
+	if !ok {
+		return "no list handler"
+	}
+	if list.HandlerSchema != nil && len(list.HandlerSchema.Required) > 0 {
+		// types listed for a parent or with explicit resource models pass the required properties
+		if _, ok := parentModels[cfType]; ok {
+			return ""
+		}
+		if _, ok := explicit[cfType]; ok {
+			return ""
+		}
+		return fmt.Sprintf("listing requires %v", list.HandlerSchema.Required)
+	}
+	return ""
+}
+
+// registryRequired returns the required properties of a type with a required create-only
+// property, from its description in the CloudFormation registry, nil for other types.
+func registryRequired(out *cloudformation.DescribeTypeOutput) []string {
+	var schema registrySchema
+	if err := json.Unmarshal([]byte(aws.ToString(out.Schema)), &schema); err != nil {
+		return nil
+	}
+	createOnly := map[string]bool{}
+	for _, p := range schema.CreateOnlyProperties {
+		createOnly[strings.TrimPrefix(p, "/properties/")] = true
+	}
+	for _, p := range schema.Required {
+		if createOnly[p] {
+			return schema.Required
+		}
+	}
+	return nil
+}
+
+// listableTypes returns the aws-native types whose CloudFormation type the cloud control API can
+// list in the scanned region, as described by the CloudFormation registry: types without a list
+// handler, types that aren't provisionable and types missing from the region are left out. Types
+// that can't be described, e.g. when cloudformation:DescribeType is denied, are kept. Types the
+// cache has a verdict for aren't described again, and the verdicts of the others are added to it.
+func listableTypes(cfg aws.Config, cache *typeCache, types []string, typesMap map[string]string, explicit map[string][]*string) []string {
+	var (
+		mu         sync.Mutex
+		wg         sync.WaitGroup
+		skipped    = map[string]string{}
+		undecided  = 0
+		remembered = 0
+	)
+	work := make(chan string)
+	for i := 0; i < registryWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// every worker has a client, and so a retryer, of its own
+			client := cloudformation.NewFromConfig(cfg)
+			for token := range work {
+				cfType := typesMap[token]
+				out, err := client.DescribeType(context.Background(), &cloudformation.DescribeTypeInput{
+					Type:     cftypes.RegistryTypeResource,
+					TypeName: aws.String(cfType),
+				})
+				reason := ""
+				var required []string
+				var notFound *cftypes.TypeNotFoundException
+				if errors.As(err, &notFound) {
+					reason = "not available in the region"
+				} else if err == nil {
+					reason = unlistableReason(out, cfType, explicit)
+					required = registryRequired(out)
+				}
+				mu.Lock()
+				if err != nil && reason == "" {
+					undecided++
+				} else {
+					cache.set(token, reason, required)
+				}
+				if reason != "" {
+					skipped[token] = reason
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, token := range types {
+		if v, ok := cache.get(token); ok {
+			remembered++
+			if v.Reason != "" {
+				mu.Lock()
+				skipped[token] = v.Reason
+				mu.Unlock()
+			}
+			continue
+		}
+		work <- token
+	}
+	close(work)
+	wg.Wait()
+
+	listable := []string{}
+	for _, token := range types {
+		if _, ok := skipped[token]; !ok {
+			listable = append(listable, token)
+		}
+	}
+	names := make([]string, 0, len(skipped))
+	for token := range skipped {
+		names = append(names, token)
+	}
+	sort.Strings(names)
+	for _, token := range names {
+		debuglog.For(0, token).Println("skipping", typesMap[token]+":", skipped[token])
+		coverage.Skip(token, skipped[token])
+	}
+	if remembered > 0 {
+		fmt.Printf("%d types were checked by an earlier run, pass --refresh-types to check them again\n", remembered)
+	}
+	fmt.Printf("%d of %d types can't be listed according to the CloudFormation registry and are skipped\n", len(skipped), len(types))
+	if undecided > 0 {
+		fmt.Printf("%d types couldn't be described in the CloudFormation registry and are listed anyway, allow cloudformation:DescribeType to skip the ones that can't be listed\n", undecided)
+	}
+	return listable
+}
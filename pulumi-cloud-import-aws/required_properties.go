@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudcontrol"
+	"github.com/pulumi/pulumi-cloud-import/pkg/config"
+	"github.com/pulumi/pulumi-cloud-import/pkg/debuglog"
+)
+
+// requiredProperties lists the required properties of a resource in the properties of its import
+// spec, for the types whose CloudFormation schema has a required create-only property: `pulumi import`
+// fails on those when the required properties aren't among the inputs it reads. The required
+// properties of a type come from the CloudFormation registry along with whether it can be listed, and
+// the ones a resource has a value for are read with GetResource, as ListResources only returns some
+// properties for many types. Properties are named like aws-native names them, e.g. vpcId for VpcId.
+type requiredProperties struct {
+	cache *typeCache
+
+	mu     sync.Mutex
+	filled int
+	failed int
+}
+
+// newRequiredProperties returns the lister of the required properties of import specs, nil in read
+// mode and with --required-properties false.
+func newRequiredProperties(cache *typeCache, mode Mode) *requiredProperties {
+	if mode != ImportMode || !config.AWSRequiredProperties.Bool() {
+		return nil
+	}
+	return &requiredProperties{cache: cache}
+}
+
+// properties returns the required properties of the resource of token identified by id, nil when its
+// type doesn't have a required create-only property.
+func (p *requiredProperties) properties(client *cloudcontrol.Client, token, cfType, id string) []string {
+	if p == nil {
+		return nil
+	}
+	v, ok := p.cache.get(token)
+	if !ok || len(v.Required) == 0 {
+		return nil
+	}
+	out, err := client.GetResource(context.Background(), &cloudcontrol.GetResourceInput{
+		TypeName:   aws.String(cfType),
+		Identifier: aws.String(id),
+	})
+	var props map[string]json.RawMessage
+	if err == nil && out.ResourceDescription != nil && out.ResourceDescription.Properties != nil {
+		err = json.Unmarshal([]byte(*out.ResourceDescription.Properties), &props)
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err != nil || props == nil {
+		debuglog.Println("failed to read the required properties of", cfType, id, err)
+		p.failed++
+		return nil
+	}
+	properties := []string{}
+	for _, name := range v.Required {
+		if _, ok := props[name]; ok {
+			properties = append(properties, sdkName(name))
+		}
+	}
+	p.filled++
+	return properties
+}
+
+// sdkName returns the aws-native name of a CloudFormation property, its leading capitals lower-cased
+// but for the one starting the next word, e.g. vpcId for VpcId and sseSpecification for
+// SSESpecification.
+func sdkName(name string) string {
+	r := []rune(name)
+	upper := 0
+	for upper < len(r) && unicode.IsUpper(r[upper]) {
+		upper++
+	}
+	if upper > 1 && upper < len(r) && unicode.IsLower(r[upper]) {
+		upper--
+	}
+	return strings.ToLower(string(r[:upper])) + string(r[upper:])
+}
+
+// report prints how many resources had their required properties listed.
+func (p *requiredProperties) report() {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.filled+p.failed == 0 {
+		return
+	}
+	fmt.Printf("listed the required properties of %d resources, %d couldn't be read\n", p.filled, p.failed)
+}
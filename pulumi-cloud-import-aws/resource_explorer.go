@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudcontrol"
+	cctypes "github.com/aws/aws-sdk-go-v2/service/cloudcontrol/types"
+	"github.com/aws/aws-sdk-go-v2/service/resourceexplorer2"
+	"github.com/pulumi/pulumi-cloud-import/pkg/config"
+	"github.com/pulumi/pulumi-cloud-import/pkg/debuglog"
+	"github.com/pulumi/pulumi-cloud-import/pkg/redact"
+)
+
+const (
+	// backendCloudControl lists every type with a ListResources request per page.
+	backendCloudControl = "cloudcontrol"
+	// backendResourceExplorer looks resources up with Resource Explorer, see inventory.
+	backendResourceExplorer = "resource-explorer"
+)
+
+// resourceExplorerTypesJSON maps the CloudFormation types Resource Explorer finds the resources of
+// to the Resource Explorer type and how the Cloud Control identifier of a resource is derived from
+// its ARN:
+//
+//   - arn: the ARN itself, e.g. for SNS topics
+//   - name: the last segment of the ARN, after the last slash or colon, e.g. for IAM roles
+//   - path: everything after the first slash of the resource, e.g. for ECR repositories
+//   - url: the URL of an SQS queue
+//
+// Types listed for a parent are never found out from Resource Explorer, they are listed for their
+// parent with Cloud Control.
+//
+//go:embed resource_explorer_types.json
+var resourceExplorerTypesJSON []byte
+
+type resourceExplorerType struct {
+	ResourceType string `json:"resourceType"`
+	Identifier   string `json:"identifier"`
+}
+
+// resourceExplorerPageSize is the number of resources the pages of Resource Explorer searches hold.
+const resourceExplorerPageSize = 1000
+
+// inventory holds the identifiers of the resources found by the Resource Explorer search of their
+// type, by CloudFormation type, with --backend resource-explorer. A search per type finds every
+// resource of a type in seconds instead of a ListResources request per hundred resources, and the
+// types of resource_explorer_types.json are found this way. Types missing from it, types whose search
+// fails, and types of more resources than a search returns are listed with Cloud Control as usual.
+//
+// Resource Explorer must be turned on with an index in the scanned region, or an aggregator index,
+// and a default view. Resources are found from its index, which lags behind changes by a few minutes.
+type inventory struct {
+	resources map[string][]string
+}
+
+// newInventory searches the resources of the types of typesMap that Resource Explorer finds in the
+// account cfg points to. It returns nil with --backend cloudcontrol, the default, and panics on
+// other backends.
+func newInventory(cfg aws.Config, types []string, typesMap map[string]string) *inventory {
+	switch backend := config.AWSBackend.Value(); backend {
+	case backendCloudControl:
+		return nil
+	case backendResourceExplorer:
+	default:
+		panic(fmt.Sprintf("%s must be %s or %s, got %q", config.AWSBackend.Flag(), backendCloudControl, backendResourceExplorer, backend))
+	}
+	var explorerTypes map[string]resourceExplorerType
+	if err := json.Unmarshal(resourceExplorerTypesJSON, &explorerTypes); err != nil {
+		panic(fmt.Sprintf("failed to parse resource_explorer_types.json: %v", err))
+	}
+	inv := &inventory{resources: map[string][]string{}}
+	client := resourceexplorer2.NewFromConfig(cfg)
+	found := 0
+	for _, token := range types {
+		cfType := typesMap[token]
+		t, ok := explorerTypes[cfType]
+		if !ok {
+			continue
+		}
+		ids, err := inv.search(client, cfg.Region, cfType, t)
+		if err != nil {
+			redact.Println("Failed to search the resources of", token, "with Resource Explorer, listing them with Cloud Control instead:", err)
+			continue
+		}
+		inv.resources[cfType] = ids
+		found += len(ids)
+	}
+	fmt.Printf("found %d resources of %d types with Resource Explorer, the other types are listed with Cloud Control\n", found, len(inv.resources))
+	return inv
+}
+
+// search returns the identifiers of the resources of cfType in region, or of every region for
+// global types, as the Resource Explorer type t.
+func (inv *inventory) search(client *resourceexplorer2.Client, region, cfType string, t resourceExplorerType) ([]string, error) {
+	query := "resourcetype:" + t.ResourceType
+	if !isGlobalType(cfType) {
+		query += " region:" + region
+	}
+	ids := []string{}
+	paginator := resourceexplorer2.NewSearchPaginator(client, &resourceexplorer2.SearchInput{
+		QueryString: aws.String(query),
+		MaxResults:  aws.Int32(resourceExplorerPageSize),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.Background())
+		if err != nil {
+			return nil, err
+		}
+		// a search returns the first thousand resources only, and tells when there are more
+		if page.Count != nil && !aws.ToBool(page.Count.Complete) {
+			return nil, fmt.Errorf("%d resources match, more than a search returns", aws.ToInt64(page.Count.TotalResources))
+		}
+		for _, r := range page.Resources {
+			id, ok := arnIdentifier(aws.ToString(r.Arn), t.Identifier)
+			if !ok {
+				debuglog.Println("failed to derive the identifier of", cfType, aws.ToString(r.Arn))
+				continue
+			}
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+// arnIdentifier derives the Cloud Control identifier of a resource from its ARN as kind says, see
+// resourceExplorerTypesJSON.
+func arnIdentifier(arn, kind string) (string, bool) {
+	parts := strings.SplitN(arn, ":", 6)
+	if len(parts) != 6 || parts[5] == "" {
+		return "", false
+	}
+	region, account, resource := parts[3], parts[4], parts[5]
+	switch kind {
+	case "arn":
+		return arn, true
+	case "name":
+		return resource[strings.LastIndexAny(resource, "/:")+1:], true
+	case "path":
+		_, path, ok := strings.Cut(resource, "/")
+		return path, ok && path != ""
+	case "url":
+		return fmt.Sprintf("https://sqs.%s.amazonaws.com/%s/%s", region, account, resource), true
+	}
+	return "", false
+}
+
+// page returns the resources of cfType found by Resource Explorer as a page of ListResources, and
+// false when cfType is listed with Cloud Control. Resources are found without their properties,
+// which are read with GetResource where needed.
+func (inv *inventory) page(cfType string) (*cloudcontrol.ListResourcesOutput, bool) {
+	if inv == nil {
+		return nil, false
+	}
+	ids, ok := inv.resources[cfType]
+	if !ok {
+		return nil, false
+	}
+	page := &cloudcontrol.ListResourcesOutput{TypeName: aws.String(cfType)}
+	for _, id := range ids {
+		page.ResourceDescriptions = append(page.ResourceDescriptions, cctypes.ResourceDescription{Identifier: aws.String(id)})
+	}
+	return page, true
+}
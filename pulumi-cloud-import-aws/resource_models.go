@@ -0,0 +1,282 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pulumi/pulumi-cloud-import/pkg/config"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudcontrol"
+)
+
+// parentModel describes a Cloud Control type that can only be listed for a given parent, like the
+// listeners of a load balancer. Every identifier of ParentType is passed to ListResources as
+// Property of the ResourceModel.
+type parentModel struct {
+	ParentType string
+	Property   string
+}
+
+var parentModels = map[string]parentModel{
+	"AWS::ApiGateway::Deployment":               {"AWS::ApiGateway::RestApi", "RestApiId"},
+	"AWS::ApiGateway::Resource":                 {"AWS::ApiGateway::RestApi", "RestApiId"},
+	"AWS::ApiGateway::Stage":                    {"AWS::ApiGateway::RestApi", "RestApiId"},
+	"AWS::ApiGatewayV2::Authorizer":             {"AWS::ApiGatewayV2::Api", "ApiId"},
+	"AWS::ApiGatewayV2::Integration":            {"AWS::ApiGatewayV2::Api", "ApiId"},
+	"AWS::ApiGatewayV2::Route":                  {"AWS::ApiGatewayV2::Api", "ApiId"},
+	"AWS::ApiGatewayV2::Stage":                  {"AWS::ApiGatewayV2::Api", "ApiId"},
+	"AWS::AutoScaling::LifecycleHook":           {"AWS::AutoScaling::AutoScalingGroup", "AutoScalingGroupName"},
+	"AWS::Cognito::UserPoolClient":              {"AWS::Cognito::UserPool", "UserPoolId"},
+	"AWS::Cognito::UserPoolGroup":               {"AWS::Cognito::UserPool", "UserPoolId"},
+	"AWS::Cognito::UserPoolResourceServer":      {"AWS::Cognito::UserPool", "UserPoolId"},
+	"AWS::Connect::ApprovedOrigin":              {"AWS::Connect::Instance", "InstanceId"},
+	"AWS::Connect::EvaluationForm":              {"AWS::Connect::Instance", "InstanceArn"},
+	"AWS::Connect::InstanceStorageConfig":       {"AWS::Connect::Instance", "InstanceArn"},
+	"AWS::Connect::SecurityKey":                 {"AWS::Connect::Instance", "InstanceId"},
+	"AWS::ECS::Service":                         {"AWS::ECS::Cluster", "Cluster"},
+	"AWS::EKS::Addon":                           {"AWS::EKS::Cluster", "ClusterName"},
+	"AWS::EKS::FargateProfile":                  {"AWS::EKS::Cluster", "ClusterName"},
+	"AWS::EKS::Nodegroup":                       {"AWS::EKS::Cluster", "ClusterName"},
+	"AWS::ElasticLoadBalancingV2::Listener":     {"AWS::ElasticLoadBalancingV2::LoadBalancer", "LoadBalancerArn"},
+	"AWS::ElasticLoadBalancingV2::ListenerRule": {"AWS::ElasticLoadBalancingV2::Listener", "ListenerArn"},
+	"AWS::GlobalAccelerator::EndpointGroup":     {"AWS::GlobalAccelerator::Listener", "ListenerArn"},
+	"AWS::GlobalAccelerator::Listener":          {"AWS::GlobalAccelerator::Accelerator", "AcceleratorArn"},
+	"AWS::GuardDuty::IPSet":                     {"AWS::GuardDuty::Detector", "DetectorId"},
+	"AWS::GuardDuty::Master":                    {"AWS::GuardDuty::Detector", "DetectorId"},
+	"AWS::GuardDuty::Member":                    {"AWS::GuardDuty::Detector", "DetectorId"},
+	"AWS::GuardDuty::ThreatIntelSet":            {"AWS::GuardDuty::Detector", "DetectorId"},
+	"AWS::Lex::BotAlias":                        {"AWS::Lex::Bot", "BotId"},
+	"AWS::Lex::BotVersion":                      {"AWS::Lex::Bot", "BotId"},
+	"AWS::Location::TrackerConsumer":            {"AWS::Location::Tracker", "TrackerName"},
+	"AWS::QLDB::Stream":                         {"AWS::QLDB::Ledger", "LedgerName"},
+	"AWS::SSMContacts::ContactChannel":          {"AWS::SSMContacts::Contact", "ContactId"},
+}
+
+// loadResourceModels reads the resource models listed in the JSON file given with
+// --aws-resource-models, e.g. {"AWS::EC2::Subnet": [{"VpcId": "vpc-123"}]}.
+// Explicit models take precedence over the ones derived from parentModels.
+func loadResourceModels() (map[string][]*string, error) {
+	models := map[string][]*string{}
+	path := config.AWSResourceModels.Value()
+	if path == "" {
+		return models, nil
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var raw map[string][]map[string]interface{}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	for typ, ms := range raw {
+		for _, m := range ms {
+			model, err := json.Marshal(m)
+			if err != nil {
+				return nil, err
+			}
+			models[typ] = append(models[typ], aws.String(string(model)))
+		}
+	}
+	return models, nil
+}
+
+// listModel is a resource model to list a type with, along with the name of the parent the
+// listed resources belong to, if any, and the region to list in when it is not the scanned one.
+type listModel struct {
+	Model  *string
+	Parent string
+	Region string
+}
+
+// discoveredParents holds the identifiers and names of the resources other types are listed for,
+// as discovered in earlier phases of the scan.
+type discoveredParents struct {
+	mu      sync.Mutex
+	names   map[string]map[string]string
+	scanned map[string]bool
+}
+
+func newDiscoveredParents() *discoveredParents {
+	return &discoveredParents{
+		names:   map[string]map[string]string{},
+		scanned: map[string]bool{},
+	}
+}
+
+func isParentType(cfType string) bool {
+	for _, p := range parentModels {
+		if p.ParentType == cfType {
+			return true
+		}
+	}
+	return false
+}
+
+// add records a discovered resource if its type is a parent of another type.
+func (d *discoveredParents) add(cfType, id, name string) {
+	if !isParentType(cfType) {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.names[cfType] == nil {
+		d.names[cfType] = map[string]string{}
+	}
+	d.names[cfType][id] = name
+}
+
+// markScanned records that every resource of cfType has been listed.
+func (d *discoveredParents) markScanned(cfType string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.scanned[cfType] = true
+}
+
+// get returns the resources of cfType by identifier, and false when cfType has not been scanned.
+func (d *discoveredParents) get(cfType string) (map[string]string, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.names[cfType], d.scanned[cfType]
+}
+
+// discoveryPhases splits the aws-native types into phases, so that every type listed for a parent
+// is listed after the parent type. Types whose parent type is not scanned end up in the first phase.
+func discoveryPhases(types []string, typesMap map[string]string, explicit map[string][]*string) [][]string {
+	scanned := map[string]bool{}
+	for _, k := range types {
+		scanned[typesMap[k]] = true
+	}
+	var depth func(cfType string) int
+	depth = func(cfType string) int {
+		if _, ok := explicit[cfType]; ok {
+			return 0
+		}
+		parent, ok := parentModels[cfType]
+		if !ok || !scanned[parent.ParentType] {
+			return 0
+		}
+		return depth(parent.ParentType) + 1
+	}
+
+	phases := [][]string{}
+	for _, k := range types {
+		d := depth(typesMap[k])
+		for len(phases) <= d {
+			phases = append(phases, []string{})
+		}
+		phases[d] = append(phases[d], k)
+	}
+	return phases
+}
+
+// resourceModels returns the resource models cfType has to be listed with. A nil model lists the
+// type without one; an empty result means there is nothing to list, e.g. when no parents exist.
+// Parents are taken from the earlier phases of the scan, or listed on the spot when their type
+// is not scanned. Global services are listed in globalRegion.
+func resourceModels(clients *regionalClients, cfType string, explicit map[string][]*string, discovered *discoveredParents) ([]listModel, error) {
+	if models, ok := explicit[cfType]; ok {
+		listModels := []listModel{}
+		for _, m := range models {
+			region := ""
+			if isGlobalType(cfType) || (wafv2ScopedTypes[cfType] && m != nil && strings.Contains(*m, `"CLOUDFRONT"`)) {
+				region = globalRegion
+			}
+			listModels = append(listModels, listModel{Model: m, Region: region})
+		}
+		return listModels, nil
+	}
+	if wafv2ScopedTypes[cfType] {
+		return []listModel{
+			{Model: aws.String(`{"Scope":"REGIONAL"}`)},
+			{Model: aws.String(`{"Scope":"CLOUDFRONT"}`), Region: globalRegion},
+		}, nil
+	}
+	parent, ok := parentModels[cfType]
+	if !ok {
+		if isGlobalType(cfType) {
+			return []listModel{{Region: globalRegion}}, nil
+		}
+		return []listModel{{}}, nil
+	}
+
+	names, ok := discovered.get(parent.ParentType)
+	if !ok {
+		ids, err := listIdentifiers(clients, parent.ParentType, explicit)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list parents of type %s: %w", parent.ParentType, err)
+		}
+		names = map[string]string{}
+		for _, id := range ids {
+			names[id] = ""
+		}
+	}
+
+	// children are listed in the order of their parents, so that the same child keeps the plain name
+	// from one scan to the next when the names of children collide
+	ids := make([]string, 0, len(names))
+	for id := range names {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	models := []listModel{}
+	for _, id := range ids {
+		model, err := json.Marshal(map[string]string{parent.Property: id})
+		if err != nil {
+			return nil, err
+		}
+		models = append(models, listModel{Model: aws.String(string(model)), Parent: names[id]})
+	}
+	return models, nil
+}
+
+// listIdentifiers lists the identifiers of every resource of cfType.
+func listIdentifiers(clients *regionalClients, cfType string, explicit map[string][]*string) ([]string, error) {
+	models, err := resourceModels(clients, cfType, explicit, newDiscoveredParents())
+	if err != nil {
+		return nil, err
+	}
+	ids := []string{}
+	for _, m := range models {
+		params := &cloudcontrol.ListResourcesInput{
+			MaxResults:    aws.Int32(100),
+			TypeName:      aws.String(cfType),
+			ResourceModel: m.Model,
+		}
+		err := listResources(context.Background(), clients.get(m.Region), params,
+			func(page *cloudcontrol.ListResourcesOutput) bool {
+				for _, r := range page.ResourceDescriptions {
+					if r.Identifier != nil {
+						ids = append(ids, *r.Identifier)
+					}
+				}
+				return true
+			})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return ids, nil
+}
+
+// listResources lists the resources of params page by page, calling fn with every page until it
+// returns false.
+func listResources(ctx context.Context, client *cloudcontrol.Client, params *cloudcontrol.ListResourcesInput, fn func(page *cloudcontrol.ListResourcesOutput) bool) error {
+	paginator := cloudcontrol.NewListResourcesPaginator(client, params)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return err
+		}
+		if !fn(page) {
+			return nil
+		}
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/ratelimit"
+	awsretry "github.com/aws/aws-sdk-go-v2/aws/retry"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/pulumi/pulumi-cloud-import/pkg/retry"
+	"github.com/pulumi/pulumi-cloud-import/pkg/workers"
+)
+
+const (
+	// retryBudget is the retry tokens of a client. A retry costs 5 tokens, 10 after a timeout, and
+	// every request going through at the first attempt gives one back.
+	retryBudget = 5000
+)
+
+// newRetryer returns the retryer of a client, built on the adaptive retry mode of the SDK: a client
+// sending requests faster than the service lets through is slowed down client side before it gets
+// throttled, and retries are paid for from a budget that requests going through refill, so that a
+// client failing over and over stops retrying rather than hammering the service. Every client gets
+// a retryer of its own, hence a rate and a budget per worker, and so per service.
+func newRetryer() aws.Retryer {
+	return awsretry.NewAdaptiveMode(func(o *awsretry.AdaptiveModeOptions) {
+		o.StandardOptions = append(o.StandardOptions, func(o *awsretry.StandardOptions) {
+			// the attempts of the most patient policy, retryDelay stopping the others short of it
+			o.MaxAttempts = retry.Throttles.Attempts
+			o.MaxBackoff = retry.Throttles.Max
+			o.Backoff = awsretry.BackoffDelayerFunc(retryDelay)
+			o.Retryables = append([]awsretry.IsErrorRetryable{awsretry.IsErrorRetryableFunc(internalError)}, o.Retryables...)
+			o.RateLimiter = ratelimit.NewTokenRateLimit(retryBudget)
+		})
+	})
+}
+
+// internalError keeps 500s from being retried.
+// TODO: some AWS services consistently return 500 internal server errors
+// when we hit the API. We should open bugs against AWS for these.
+func internalError(err error) aws.Ternary {
+	var resp *awshttp.ResponseError
+	if errors.As(err, &resp) && resp.HTTPStatusCode() == http.StatusInternalServerError {
+		return aws.FalseTernary
+	}
+	return aws.UnknownTernary
+}
+
+// retryDelay returns the delay before retrying a request that failed with err with the policy of its
+// error, at least the delay the service asked for with Retry-After, recording the delays of
+// throttled requests for the summary of the run. A request that has used up the attempts of the
+// policy of its error isn't retried.
+func retryDelay(attempt int, err error) (time.Duration, error) {
+	throttled := awsretry.IsErrorThrottles(awsretry.DefaultThrottles).IsErrorThrottle(err).Bool()
+	policy := retry.Transients
+	if throttled {
+		policy = retry.Throttles
+	}
+	if attempt >= policy.Attempts {
+		return 0, &awsretry.MaxAttemptsError{Attempt: attempt, Err: err}
+	}
+	delay := policy.Delay(attempt)
+	var resp *awshttp.ResponseError
+	if errors.As(err, &resp) && resp.Response != nil && resp.Response.Response != nil {
+		if after := retry.RetryAfter(resp.Response.Header); after > delay {
+			delay = after
+		}
+	}
+	if throttled {
+		workers.Backoff(delay)
+	}
+	return delay, nil
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/smithy-go"
+	"github.com/pulumi/pulumi-cloud-import/pkg/config"
+)
+
+// skippedTypesThreshold is how many scans in a row a type must fail in before later scans skip it,
+// so that a handler failing once in a while doesn't leave the type out.
+const skippedTypesThreshold = 2
+
+// handlerFailures are the error codes of the Cloud Control handlers failing, whatever the account.
+var handlerFailures = []string{
+	"HandlerInternalFailureException",
+	"ServiceInternalErrorException",
+	"GeneralServiceException",
+}
+
+// skippedType is a type whose handlers failed in the last scans.
+type skippedType struct {
+	Reason string `json:"reason"`
+	// Failures is how many scans in a row the type failed in
+	Failures   int       `json:"failures"`
+	LastFailed time.Time `json:"lastFailed"`
+}
+
+// skippedTypes learns the types whose Cloud Control handlers keep failing, to skip them in the next
+// scans rather than wait for every request of a known bad handler to be retried. The types failing
+// to list with a handler failure or a 5xx are written to skipped-types.json, or to --skipped-types,
+// and skipped once they failed in skippedTypesThreshold scans in a row. So are the types whose
+// resources failed to be read with a handler failure in that many read mode runs in a row. A type
+// listed, and read, successfully is forgotten, and --refresh-types tries every type again.
+type skippedTypes struct {
+	mu    sync.Mutex
+	path  string
+	types map[string]*skippedType
+	// failed are the types that failed to list in this scan along with why, listed the ones that
+	// didn't
+	failed map[string]string
+	listed map[string]bool
+	// unread are the types whose resources failed to be read in this scan along with why
+	unread map[string]string
+}
+
+// loadSkippedTypes loads the types learned by earlier scans, none with --refresh-types.
+func loadSkippedTypes() *skippedTypes {
+	s := &skippedTypes{
+		path:   config.AWSSkippedTypes.Value(),
+		types:  map[string]*skippedType{},
+		failed: map[string]string{},
+		listed: map[string]bool{},
+		unread: map[string]string{},
+	}
+	if s.path == "" || config.AWSRefreshTypes.Bool() {
+		return s
+	}
+	b, err := os.ReadFile(s.path)
+	if err != nil && !os.IsNotExist(err) {
+		panic(fmt.Sprintf("failed to read skipped types file: %v", err))
+	}
+	if err == nil {
+		if err := json.Unmarshal(b, &s.types); err != nil {
+			panic(fmt.Sprintf("failed to parse %s: %v", s.path, err))
+		}
+	}
+	return s
+}
+
+// skip returns why token is skipped, if it failed in enough scans in a row.
+func (s *skippedTypes) skip(token string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, ok := s.types[token]
+	if !ok || t.Failures < skippedTypesThreshold {
+		return "", false
+	}
+	return fmt.Sprintf("failed in %d scans in a row, see %s: %s", t.Failures, s.path, t.Reason), true
+}
+
+// listFailed records that token failed to list with err, if its handler failed.
+func (s *skippedTypes) listFailed(token string, err error) {
+	if !isHandlerFailure(err) {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failed[token] = "fails to list: " + err.Error()
+}
+
+// readFailed records that a resource of token failed to be read with err, if its handler failed.
+// The read fails after ReadResource returns, see quarantine.Watch.
+func (s *skippedTypes) readFailed(token string, err error) {
+	if !isHandlerFailureMessage(err.Error()) {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.unread[token] = "fails to read: " + err.Error()
+}
+
+// listSucceeded records that token was listed, which clears its earlier failures.
+func (s *skippedTypes) listSucceeded(token string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.listed[token] = true
+}
+
+// isHandlerFailure reports whether err is the Cloud Control handler of a type failing, rather than
+// the request being denied or throttled.
+func isHandlerFailure(err error) bool {
+	var resp *awshttp.ResponseError
+	if errors.As(err, &resp) && resp.HTTPStatusCode() >= 500 {
+		return true
+	}
+	var aerr smithy.APIError
+	if !errors.As(err, &aerr) {
+		return false
+	}
+	return contains(handlerFailures, aerr.ErrorCode())
+}
+
+// isHandlerFailureMessage reports whether the error message of a failed read, as reported by the
+// engine, is a handler failure.
+func isHandlerFailureMessage(message string) bool {
+	for _, code := range handlerFailures {
+		if strings.Contains(message, code) {
+			return true
+		}
+	}
+	return strings.Contains(message, "StatusCode: 500")
+}
+
+// save writes the types failing so far for the next scans, and reports the types skipped from now on.
+func (s *skippedTypes) save() error {
+	if s.path == "" {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// a type failing in several accounts or regions of a scan fails once
+	failed := map[string]string{}
+	for token, reason := range s.failed {
+		if !s.listed[token] {
+			failed[token] = reason
+		}
+	}
+	// types listed somewhere but failing to read fail all the same
+	for token, reason := range s.unread {
+		failed[token] = reason
+	}
+	for token, reason := range failed {
+		t, ok := s.types[token]
+		if !ok {
+			t = &skippedType{}
+			s.types[token] = t
+		}
+		t.Reason = reason
+		t.Failures++
+		t.LastFailed = time.Now().UTC()
+		if t.Failures == skippedTypesThreshold {
+			fmt.Printf("%s failed in %d scans in a row and will be skipped, see %s\n", token, t.Failures, s.path)
+		}
+	}
+	for token := range s.listed {
+		if _, ok := failed[token]; !ok {
+			delete(s.types, token)
+		}
+	}
+	if len(s.types) == 0 {
+		if err := os.Remove(s.path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	b, err := json.MarshalIndent(s.types, "", "    ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.path, b, 0644)
+}
+
+// report prints how many types were skipped as they failed in earlier scans.
+func (s *skippedTypes) report(skipped []string) {
+	if len(skipped) == 0 {
+		return
+	}
+	sort.Strings(skipped)
+	fmt.Printf("%d types that failed in earlier scans are skipped, remove them from %s or pass --refresh-types to try them again: %s\n",
+		len(skipped), s.path, strings.Join(skipped, ", "))
+}
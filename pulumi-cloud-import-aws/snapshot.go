@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudcontrol"
+	"github.com/pulumi/pulumi-cloud-import/pkg/config"
+	"github.com/pulumi/pulumi-cloud-import/pkg/redact"
+)
+
+// snapshotPath is where the properties of the discovered resources are written to.
+const snapshotPath = "properties.json"
+
+// snapshotEntry is a discovered resource along with its properties. Error is set when the
+// properties couldn't be read, Properties then being the partial ones returned by ListResources.
+type snapshotEntry struct {
+	Type       string          `json:"type"`
+	Name       string          `json:"name"`
+	ID         string          `json:"id"`
+	Region     string          `json:"region,omitempty"`
+	Properties json.RawMessage `json:"properties,omitempty"`
+	Error      string          `json:"error,omitempty"`
+}
+
+// propertySnapshot collects the properties of every discovered resource when --snapshot-properties
+// is passed, kept apart from the import file so that it can be searched or diffed offline.
+type propertySnapshot struct {
+	mu      sync.Mutex
+	entries []snapshotEntry
+}
+
+// add reads the properties of resource with GetResource, as ListResources only returns some
+// properties for many types, and records them. listed are the properties ListResources returned.
+func (s *propertySnapshot) add(client *cloudcontrol.Client, cfType, region string, resource importSpec, listed *string) {
+	if !config.AWSSnapshotProperties.Bool() {
+		return
+	}
+	entry := snapshotEntry{Type: resource.Type, Name: resource.Name, ID: resource.ID, Region: region}
+	out, err := client.GetResource(context.Background(), &cloudcontrol.GetResourceInput{
+		TypeName:   aws.String(cfType),
+		Identifier: aws.String(resource.ID),
+	})
+	properties := listed
+	if err != nil {
+		entry.Error = err.Error()
+	} else if out.ResourceDescription != nil && out.ResourceDescription.Properties != nil {
+		properties = out.ResourceDescription.Properties
+	}
+	if properties != nil && json.Valid([]byte(*properties)) {
+		entry.Properties = json.RawMessage(*properties)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries = append(s.entries, entry)
+}
+
+// write writes the recorded properties to snapshotPath, sorted so that snapshots of an account can
+// be diffed.
+func (s *propertySnapshot) write() error {
+	if !config.AWSSnapshotProperties.Bool() {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sort.Slice(s.entries, func(i, j int) bool {
+		if s.entries[i].Type != s.entries[j].Type {
+			return s.entries[i].Type < s.entries[j].Type
+		}
+		return s.entries[i].ID < s.entries[j].ID
+	})
+
+	b, err := json.MarshalIndent(s.entries, "", "    ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(snapshotPath, b, 0644); err != nil {
+		return err
+	}
+	failed := 0
+	for _, e := range s.entries {
+		if e.Error != "" {
+			failed++
+		}
+	}
+	if failed > 0 {
+		redact.Printf("%d resources couldn't be read, their snapshot only holds the properties returned when listing them\n", failed)
+	}
+	fmt.Printf("Properties of %d resources written to %s\n", len(s.entries), snapshotPath)
+	return nil
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudcontrol"
+	"github.com/aws/aws-sdk-go-v2/service/resourcegroupstaggingapi"
+	"github.com/aws/aws-sdk-go-v2/service/resourcegroupstaggingapi/types"
+	"github.com/pulumi/pulumi-cloud-import/pkg/debuglog"
+	"github.com/pulumi/pulumi-cloud-import/pkg/redact"
+	"github.com/pulumi/pulumi-cloud-import/pkg/tagfilter"
+)
+
+// tagFilter keeps the resources matching --include-tag and --exclude-tag, see pkg/tagfilter.
+//
+// Tags are read from the properties ListResources returns. The tags of the types that ListResources
+// doesn't return tags of are looked up by ARN with the Resource Groups Tagging API, and with
+// GetResource for resources without an ARN.
+type tagFilter struct {
+	filter *tagfilter.Filter
+	cfg    aws.Config
+
+	mu sync.Mutex
+	// tagged are the tags of the resources having one of the filtered keys, by ARN, as returned by
+	// the tagging API of each region
+	tagged map[string]map[string]string
+	loaded map[string]bool
+	// taggingAPI is false once the tagging API failed, after which GetResource is used instead
+	taggingAPI bool
+	kept       int
+	leftOut    int
+}
+
+// newTagFilter returns the filter of the resources of the account cfg points to, nil when no
+// filter is passed.
+func newTagFilter(cfg aws.Config) *tagFilter {
+	filter := tagfilter.FromFlags()
+	if filter == nil {
+		return nil
+	}
+	return &tagFilter{
+		filter:     filter,
+		cfg:        cfg,
+		tagged:     map[string]map[string]string{},
+		loaded:     map[string]bool{},
+		taggingAPI: true,
+	}
+}
+
+// keep reports whether the resource of cfType identified by id, listed in region with properties,
+// is kept.
+func (f *tagFilter) keep(client *cloudcontrol.Client, cfType, region, id, parent string, properties *string) bool {
+	if f == nil {
+		return true
+	}
+	kept := f.filter.Matches(f.tags(client, cfType, region, id, properties), parent != "")
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if kept {
+		f.kept++
+	} else {
+		f.leftOut++
+	}
+	return kept
+}
+
+// tags returns the tags of a resource, from its properties, the tagging API or GetResource.
+func (f *tagFilter) tags(client *cloudcontrol.Client, cfType, region, id string, properties *string) map[string]string {
+	if hasTags(properties) {
+		return resourceTags(properties)
+	}
+	arn := resourceARN(properties)
+	if arn == "" && strings.HasPrefix(id, "arn:") {
+		arn = id
+	}
+	if arn != "" && f.loadRegion(region) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		// resources missing from the tagging API have none of the filtered keys
+		return f.tagged[arn]
+	}
+	out, err := client.GetResource(context.Background(), &cloudcontrol.GetResourceInput{
+		TypeName:   aws.String(cfType),
+		Identifier: aws.String(id),
+	})
+	if err != nil || out.ResourceDescription == nil {
+		debuglog.Println("failed to read the tags of", cfType, id, err)
+		return nil
+	}
+	return resourceTags(out.ResourceDescription.Properties)
+}
+
+// hasTags reports whether properties hold the tags of the resource.
+func hasTags(properties *string) bool {
+	if properties == nil {
+		return false
+	}
+	var props map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(*properties), &props); err != nil {
+		return false
+	}
+	_, ok := props["Tags"]
+	return ok
+}
+
+// loadRegion looks up the resources having one of the filtered keys in region with the tagging API,
+// once per region. It returns false if the tagging API can't be used.
+func (f *tagFilter) loadRegion(region string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !f.taggingAPI {
+		return false
+	}
+	if f.loaded[region] {
+		return true
+	}
+	client := resourcegroupstaggingapi.NewFromConfig(f.cfg, func(o *resourcegroupstaggingapi.Options) {
+		if region != "" {
+			o.Region = region
+		}
+	})
+	// filters on several keys only return the resources having all of them, hence a query per key
+	for _, key := range f.filter.Keys() {
+		paginator := resourcegroupstaggingapi.NewGetResourcesPaginator(client, &resourcegroupstaggingapi.GetResourcesInput{
+			TagFilters: []types.TagFilter{{Key: aws.String(key)}},
+		})
+		for paginator.HasMorePages() {
+			page, err := paginator.NextPage(context.Background())
+			if err != nil {
+				redact.Println("Failed to look up tags with the Resource Groups Tagging API, reading them with GetResource instead:", err)
+				f.taggingAPI = false
+				return false
+			}
+			for _, m := range page.ResourceTagMappingList {
+				tags := map[string]string{}
+				for _, t := range m.Tags {
+					tags[aws.ToString(t.Key)] = aws.ToString(t.Value)
+				}
+				f.tagged[aws.ToString(m.ResourceARN)] = tags
+			}
+		}
+	}
+	f.loaded[region] = true
+	return true
+}
+
+// report prints how many resources the filter kept and left out.
+func (f *tagFilter) report() {
+	if f == nil {
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	fmt.Printf("%d resources matched --include-tag and --exclude-tag, %d were left out\n", f.kept, f.leftOut)
+}
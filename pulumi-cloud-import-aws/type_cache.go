@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/aws/smithy-go"
+	"github.com/pulumi/pulumi-cloud-import/pkg/config"
+	"github.com/pulumi/pulumi-cloud-import/pkg/manifest"
+	"github.com/pulumi/pulumi-cloud-import/pkg/recorder"
+)
+
+// typeCacheVersion is bumped whenever the verdicts change shape, so that the caches of earlier
+// versions, e.g. from before the required properties were remembered, aren't read.
+const typeCacheVersion = 2
+
+// typeVerdict is whether a type could be listed in an account and region, and when that was found
+// out. Reason is why it can't be listed, empty when it can. Required are the properties of the types
+// requiring create-only properties, see requiredProperties.
+type typeVerdict struct {
+	Reason   string    `json:"reason,omitempty"`
+	Required []string  `json:"required,omitempty"`
+	Checked  time.Time `json:"checked"`
+}
+
+// typeCache remembers which types can be listed in an account and region between runs, so that
+// repeated scans don't describe every type in the CloudFormation registry again or wait for the
+// types that aren't activated to fail. Verdicts older than --type-cache-ttl are checked again.
+type typeCache struct {
+	mu       sync.Mutex
+	path     string
+	verdicts map[string]typeVerdict
+}
+
+// loadTypeCache loads the verdicts of the account and region cfg points to. The cache is empty
+// when the account can't be identified, when replaying a recording and with --refresh-types. The
+// account identified is also recorded in the run manifest.
+func loadTypeCache(cfg aws.Config) *typeCache {
+	c := &typeCache{verdicts: map[string]typeVerdict{}}
+	if recorder.Replaying() {
+		return c
+	}
+	identity, err := sts.NewFromConfig(cfg).GetCallerIdentity(context.Background(), &sts.GetCallerIdentityInput{})
+	if err != nil {
+		fmt.Println("Failed to identify the account, the types that can be listed won't be remembered:", err)
+		return c
+	}
+	manifest.Identify("account", aws.ToString(identity.Account))
+	manifest.Identify("arn", aws.ToString(identity.Arn))
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return c
+	}
+	name := fmt.Sprintf("aws-types-v%d-%s-%s.json", typeCacheVersion, aws.ToString(identity.Account), cfg.Region)
+	c.path = filepath.Join(dir, "pulumi-cloud-import", name)
+	if config.AWSRefreshTypes.Bool() {
+		return c
+	}
+
+	b, err := os.ReadFile(c.path)
+	if err != nil {
+		return c
+	}
+	verdicts := map[string]typeVerdict{}
+	if err := json.Unmarshal(b, &verdicts); err != nil {
+		fmt.Printf("Ignoring the unreadable cache of types %s: %v\n", c.path, err)
+		return c
+	}
+	ttl := config.AWSTypeCacheTTL.Duration()
+	for token, v := range verdicts {
+		if time.Since(v.Checked) < ttl {
+			c.verdicts[token] = v
+		}
+	}
+	return c
+}
+
+// get returns the verdict remembered for token.
+func (c *typeCache) get(token string) (typeVerdict, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	v, ok := c.verdicts[token]
+	return v, ok
+}
+
+// set records whether token can be listed, reason being empty when it can, and its required
+// properties.
+func (c *typeCache) set(token, reason string, required []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.verdicts[token] = typeVerdict{Reason: reason, Required: required, Checked: time.Now().UTC()}
+}
+
+// save writes the verdicts for the next run.
+func (c *typeCache) save() error {
+	if c.path == "" {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	b, err := json.MarshalIndent(c.verdicts, "", "    ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(c.path, b, 0644)
+}
+
+// listFailed records that token can't be listed when err shows it never will be in this account
+// and region, like for third party types that aren't activated.
+func (c *typeCache) listFailed(token string, err error) {
+	var aerr smithy.APIError
+	if !errors.As(err, &aerr) {
+		return
+	}
+	switch aerr.ErrorCode() {
+	case "TypeNotFoundException", "UnsupportedActionException":
+		c.set(token, aerr.ErrorCode(), nil)
+	}
+}
@@ -1,377 +1,75 @@
 package main
 
-var unsupportedResources = map[string]bool{
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
 
-	// does not support LIST action
-	"aws-native:acmpca:Certificate":                              true,
-	"aws-native:acmpca:CertificateAuthorityActivation":           true,
-	"aws-native:acmpca:Permission":                               true,
-	"aws-native:apigateway:Account":                              true,
-	"aws-native:apigateway:Method":                               true,
-	"aws-native:appstream:AppBlock":                              true,
-	"aws-native:appstream:Application":                           true,
-	"aws-native:appstream:ApplicationEntitlementAssociation":     true,
-	"aws-native:appstream:ApplicationFleetAssociation":           true,
-	"aws-native:appstream:Entitlement":                           true,
-	"aws-native:appsync:DomainNameApiAssociation":                true,
-	"aws-native:autoscaling:WarmPool":                            true,
-	"aws-native:bedrock:GuardrailVersion":                        true,
-	"aws-native:certificatemanager:Account":                      true,
-	"aws-native:cloudformation:ModuleVersion":                    true,
-	"aws-native:cloudfront:MonitoringSubscription":               true,
-	"aws-native:cloudtrail:ResourcePolicy":                       true,
-	"aws-native:cognito:LogDeliveryConfiguration":                true,
-	"aws-native:cognito:UserPoolRiskConfigurationAttachment":     true,
-	"aws-native:cognito:UserPoolUiCustomizationAttachment":       true,
-	"aws-native:cognito:UserPoolUserToGroupAttachment":           true,
-	"aws-native:connect:Rule":                                    true,
-	"aws-native:ec2:GatewayRouteTableAssociation":                true,
-	"aws-native:ecs:PrimaryTaskSet":                              true,
-	"aws-native:eventschemas:RegistryPolicy":                     true,
-	"aws-native:evidently:Experiment":                            true,
-	"aws-native:evidently:Feature":                               true,
-	"aws-native:evidently:Launch":                                true,
-	"aws-native:evidently:Project":                               true,
-	"aws-native:iam:GroupPolicy":                                 true,
-	"aws-native:iam:RolePolicy":                                  true,
-	"aws-native:iam:ServiceLinkedRole":                           true,
-	"aws-native:iam:UserPolicy":                                  true,
-	"aws-native:inspector:ResourceGroup":                         true,
-	"aws-native:kinesisvideo:SignalingChannel":                   true,
-	"aws-native:kinesisvideo:Stream":                             true,
-	"aws-native:lakeformation:PrincipalPermissions":              true,
-	"aws-native:lakeformation:TagAssociation":                    true,
-	"aws-native:mediaconnect:BridgeOutputResource":               true,
-	"aws-native:mediaconnect:BridgeSource":                       true,
-	"aws-native:mediapackagev2:ChannelPolicy":                    true,
-	"aws-native:mediapackagev2:OriginEndpointPolicy":             true,
-	"aws-native:mediatailor:ChannelPolicy":                       true,
-	"aws-native:opensearchservice:Domain":                        true,
-	"aws-native:panorama:PackageVersion":                         true,
-	"aws-native:resourceexplorer2:DefaultViewAssociation":        true,
-	"aws-native:s3objectlambda:AccessPointPolicy":                true,
-	"aws-native:s3outposts:BucketPolicy":                         true,
-	"aws-native:sagemaker:Device":                                true,
-	"aws-native:sagemaker:DeviceFleet":                           true,
-	"aws-native:servicecatalog:CloudFormationProvisionedProduct": true,
-	"aws-native:ses:ConfigurationSetEventDestination":            true,
-	"aws-native:ses:VdmAttributes":                               true,
-	"aws-native:sns:TopicInlinePolicy":                           true,
-	"aws-native:sqs:QueueInlinePolicy":                           true,
-	"aws-native:ssmcontacts:Plan":                                true,
-	"aws-native:vpclattice:AuthPolicy":                           true,
-	"aws-native:vpclattice:ResourcePolicy":                       true,
-	"aws-native:wafv2:WebAclAssociation":                         true,
-	"aws-native:workspaces:ConnectionAlias":                      true,
+	"github.com/pulumi/pulumi-cloud-import/pkg/config"
+	"github.com/pulumi/pulumi-cloud-import/pkg/fetch"
+)
 
-	// is not yet supported via Cloud Control API
-	"aws-native:apigatewayv2:Integration":         true,
-	"aws-native:batch:JobDefinition":              true,
-	"aws-native:cognito:UserPoolDomain":           true,
-	"aws-native:cognito:UserPoolIdentityProvider": true,
-	"aws-native:sns:TopicPolicy":                  true,
+// unsupportedResourcesJSON maps the types skipped as they fail to list or import to why. Types
+// without a list handler, that aren't provisionable or that were shut down are skipped from their
+// description in the CloudFormation registry, see registry.go. The types listed here can be
+// described but fail to list or import. Types whose required properties can be derived from a
+// parent are listed in resource_models.go instead. There are other types that may fail depending
+// on account configuration or permissions but are worth trying to import.
+//
+//go:embed unsupported_resources.json
+var unsupportedResourcesJSON []byte
 
-	// shut down
-	"aws-native:opsworkscm:Server":                      true,
-	"aws-native:robomaker:Fleet":                        true,
-	"aws-native:robomaker:Robot":                        true,
-	"aws-native:robomaker:RobotApplication":             true,
-	"aws-native:robomaker:RobotApplicationVersion":      true,
-	"aws-native:robomaker:SimulationApplication":        true,
-	"aws-native:robomaker:SimulationApplicationVersion": true,
-	"aws-native:robomaker:SimulationJob":                true,
+// unsupportedResourcesURL is the list published on the main branch, which is ahead of the list
+// built into a release.
+const unsupportedResourcesURL = "https://raw.githubusercontent.com/pulumi/pulumi-cloud-import/main/pulumi-cloud-import-aws/unsupported_resources.json"
 
-	// can not be found
-	"aws-native:devicefarm:TestGridProject":   true,
-	"aws-native:devicefarm:DevicePool":        true,
-	"aws-native:devicefarm:VpceConfiguration": true,
-	"aws-native:devicefarm:Project":           true,
-	"aws-native:devicefarm:NetworkProfile":    true,
-	"aws-native:devicefarm:InstanceProfile":   true,
-
-	// Resources with Issues during the import ---------
-	"aws-native:ram:Permission":    true,
-	"aws-native:ssm:PatchBaseline": true,
-
-	// 'Account is not registered as a publisher' error
-	"aws-native:cloudformation:PublicTypeVersion": true,
-
-	// error: resource 'AwsDataCatalog' does not exist
-	"aws-native:athena:DataCatalog": true,
-	// error: resource 'LOCKE' does not exist
-	"aws-native:appflow:Connector": true,
-	// name collison Duplicate resource URN 'efs:FileSystem::EFSFileSystemfs0dce0ba5'; try giving it a unique name
-	"aws-native:efs:FileSystem": true,
-	// FAILED: [RSLVR-00903] Cannot tag Auto Defined Rule.
-	"aws-native:route53resolver:ResolverRule": true,
-
-	// // returns 500 instead of 404
-	"aws-native:codepipeline:CustomActionType": true,
-	// returns consistent 500s
-	"aws-native:ec2:PrefixList": true,
-	// consistent 500s
-	"aws-native:scheduler:ScheduleGroup": true,
-	// 500s
-	"aws-native:ecs:CapacityProvider": true,
-	// 400 "you don't have permissions"
-	"aws-native:organizations:Organization": true,
-	// 400 "List Handler returned status FAILED: Invalid request provided"
-	"aws-native:route53resolver:FirewallDomainList": true,
-	// 500 "List Handler returned status FAILED: Invalid request provided"
-	"aws-native:cloudformation:Stack": true,
-	// ------------
-
-	// - the following require to pass additional properties which we don't yet support
-	"aws-native:amplify:Branch":                                      true,
-	"aws-native:amplifyuibuilder:Component":                          true,
-	"aws-native:amplifyuibuilder:Form":                               true,
-	"aws-native:amplifyuibuilder:Theme":                              true,
-	"aws-native:apigateway:Authorizer":                               true,
-	"aws-native:apigateway:BasePathMapping":                          true,
-	"aws-native:apigateway:Deployment":                               true,
-	"aws-native:apigateway:DocumentationPart":                        true,
-	"aws-native:apigateway:DocumentationVersion":                     true,
-	"aws-native:apigateway:GatewayResponse":                          true,
-	"aws-native:apigateway:Model":                                    true,
-	"aws-native:apigateway:RequestValidator":                         true,
-	"aws-native:apigateway:Resource":                                 true,
-	"aws-native:apigateway:Stage":                                    true,
-	"aws-native:apigateway:UsagePlanKey":                             true,
-	"aws-native:apigatewayv2:ApiMapping":                             true,
-	"aws-native:apigatewayv2:Authorizer":                             true,
-	"aws-native:apigatewayv2:Deployment":                             true,
-	"aws-native:apigatewayv2:Model":                                  true,
-	"aws-native:apigatewayv2:Route":                                  true,
-	"aws-native:appconfig:ConfigurationProfile":                      true,
-	"aws-native:appconfig:Environment":                               true,
-	"aws-native:appconfig:HostedConfigurationVersion":                true,
-	"aws-native:applicationautoscaling:ScalingPolicy":                true,
-	"aws-native:appsync:FunctionConfiguration":                       true,
-	"aws-native:appsync:Resolver":                                    true,
-	"aws-native:appsync:SourceApiAssociation":                        true,
-	"aws-native:aps:RuleGroupsNamespace":                             true,
-	"aws-native:athena:PreparedStatement":                            true,
-	"aws-native:bedrock:AgentAlias":                                  true,
-	"aws-native:bedrock:DataSource":                                  true,
-	"aws-native:bedrock:FlowAlias":                                   true,
-	"aws-native:bedrock:FlowVersion":                                 true,
-	"aws-native:bedrock:PromptVersion":                               true,
-	"aws-native:cleanrooms:AnalysisTemplate":                         true,
-	"aws-native:cleanrooms:ConfiguredTableAssociation":               true,
-	"aws-native:cleanrooms:PrivacyBudgetTemplate":                    true,
-	"aws-native:cloudformation:ResourceVersion":                      true,
-	"aws-native:codeartifact:PackageGroup":                           true,
-	"aws-native:cognito:IdentityPoolPrincipalTag":                    true,
-	"aws-native:cognito:IdentityPoolRoleAttachment":                  true,
-	"aws-native:cognito:UserPoolClient":                              true,
-	"aws-native:cognito:UserPoolGroup":                               true,
-	"aws-native:cognito:UserPoolResourceServer":                      true,
-	"aws-native:cognito:UserPoolUser":                                true,
-	"aws-native:connect:ContactFlow":                                 true,
-	"aws-native:connect:ContactFlowModule":                           true,
-	"aws-native:connect:HoursOfOperation":                            true,
-	"aws-native:connect:IntegrationAssociation":                      true,
-	"aws-native:connect:PhoneNumber":                                 true,
-	"aws-native:connect:PredefinedAttribute":                         true,
-	"aws-native:connect:Prompt":                                      true,
-	"aws-native:connect:Queue":                                       true,
-	"aws-native:connect:QuickConnect":                                true,
-	"aws-native:connect:RoutingProfile":                              true,
-	"aws-native:connect:SecurityProfile":                             true,
-	"aws-native:connect:TaskTemplate":                                true,
-	"aws-native:connect:User":                                        true,
-	"aws-native:connect:UserHierarchyGroup":                          true,
-	"aws-native:connect:View":                                        true,
-	"aws-native:connect:ViewVersion":                                 true,
-	"aws-native:controltower:EnabledControl":                         true,
-	"aws-native:customerprofiles:CalculatedAttributeDefinition":      true,
-	"aws-native:customerprofiles:Domain":                             true,
-	"aws-native:customerprofiles:EventStream":                        true,
-	"aws-native:customerprofiles:Integration":                        true,
-	"aws-native:customerprofiles:ObjectType":                         true,
-	"aws-native:datazone:DataSource":                                 true,
-	"aws-native:datazone:Environment":                                true,
-	"aws-native:datazone:EnvironmentBlueprintConfiguration":          true,
-	"aws-native:datazone:EnvironmentProfile":                         true,
-	"aws-native:datazone:GroupProfile":                               true,
-	"aws-native:datazone:Project":                                    true,
-	"aws-native:datazone:ProjectMembership":                          true,
-	"aws-native:datazone:SubscriptionTarget":                         true,
-	"aws-native:datazone:UserProfile":                                true,
-	"aws-native:deadline:Fleet":                                      true,
-	"aws-native:deadline:MeteredProduct":                             true,
-	"aws-native:deadline:Queue":                                      true,
-	"aws-native:deadline:QueueEnvironment":                           true,
-	"aws-native:deadline:QueueFleetAssociation":                      true,
-	"aws-native:deadline:StorageProfile":                             true,
-	"aws-native:ec2:IpamAllocation":                                  true,
-	"aws-native:ec2:IpamPoolCidr":                                    true,
-	"aws-native:ec2:Route":                                           true,
-	"aws-native:ec2:TransitGatewayMulticastDomainAssociation":        true,
-	"aws-native:ec2:TransitGatewayMulticastGroupMember":              true,
-	"aws-native:ec2:TransitGatewayMulticastGroupSource":              true,
-	"aws-native:ec2:TransitGatewayRoute":                             true,
-	"aws-native:ec2:TransitGatewayRouteTableAssociation":             true,
-	"aws-native:ec2:TransitGatewayRouteTablePropagation":             true,
-	"aws-native:ec2:VpcCidrBlock":                                    true,
-	"aws-native:ecs:TaskSet":                                         true,
-	"aws-native:efs:MountTarget":                                     true,
-	"aws-native:eks:AccessEntry":                                     true,
-	"aws-native:eks:Addon":                                           true,
-	"aws-native:eks:FargateProfile":                                  true,
-	"aws-native:eks:IdentityProviderConfig":                          true,
-	"aws-native:eks:Nodegroup":                                       true,
-	"aws-native:eks:PodIdentityAssociation":                          true,
-	"aws-native:elasticloadbalancingv2:Listener":                     true,
-	"aws-native:elasticloadbalancingv2:ListenerRule":                 true,
-	"aws-native:elasticloadbalancingv2:TrustStoreRevocation":         true,
-	"aws-native:entityresolution:PolicyStatement":                    true,
-	"aws-native:eventschemas:Schema":                                 true,
-	"aws-native:fis:TargetAccountConfiguration":                      true,
-	"aws-native:glue:SchemaVersion":                                  true,
-	"aws-native:glue:SchemaVersionMetadata":                          true,
-	"aws-native:greengrassv2:ComponentVersion":                       true,
-	"aws-native:identitystore:Group":                                 true,
-	"aws-native:identitystore:GroupMembership":                       true,
-	"aws-native:imagebuilder:Component":                              true,
-	"aws-native:imagebuilder:Image":                                  true,
-	"aws-native:imagebuilder:Workflow":                               true,
-	"aws-native:iotsitewise:AccessPolicy":                            true,
-	"aws-native:iotsitewise:Dashboard":                               true,
-	"aws-native:iotsitewise:Project":                                 true,
-	"aws-native:iottwinmaker:ComponentType":                          true,
-	"aws-native:iottwinmaker:Entity":                                 true,
-	"aws-native:iottwinmaker:Scene":                                  true,
-	"aws-native:iottwinmaker:SyncJob":                                true,
-	"aws-native:ivs:StreamKey":                                       true,
-	"aws-native:kendra:DataSource":                                   true,
-	"aws-native:kendra:Faq":                                          true,
-	"aws-native:lambda:Alias":                                        true,
-	"aws-native:lambda:EventInvokeConfig":                            true,
-	"aws-native:lambda:Permission":                                   true,
-	"aws-native:lambda:Url":                                          true,
-	"aws-native:lambda:Version":                                      true,
-	"aws-native:logs:AccountPolicy":                                  true,
-	"aws-native:logs:LogStream":                                      true,
-	"aws-native:logs:SubscriptionFilter":                             true,
-	"aws-native:mediaconnect:FlowEntitlement":                        true,
-	"aws-native:mediaconnect:FlowOutput":                             true,
-	"aws-native:mediaconnect:FlowSource":                             true,
-	"aws-native:mediaconnect:FlowVpcInterface":                       true,
-	"aws-native:medialive:Multiplexprogram":                          true,
-	"aws-native:mediapackage:Asset":                                  true,
-	"aws-native:mediapackage:PackagingConfiguration":                 true,
-	"aws-native:mediapackagev2:Channel":                              true,
-	"aws-native:mediapackagev2:OriginEndpoint":                       true,
-	"aws-native:mediatailor:LiveSource":                              true,
-	"aws-native:mediatailor:VodSource":                               true,
-	"aws-native:msk:BatchScramSecret":                                true,
-	"aws-native:msk:ClusterPolicy":                                   true,
-	"aws-native:networkfirewall:LoggingConfiguration":                true,
-	"aws-native:networkmanager:CustomerGatewayAssociation":           true,
-	"aws-native:networkmanager:Device":                               true,
-	"aws-native:networkmanager:Link":                                 true,
-	"aws-native:networkmanager:LinkAssociation":                      true,
-	"aws-native:networkmanager:Site":                                 true,
-	"aws-native:networkmanager:TransitGatewayRegistration":           true,
-	"aws-native:nimblestudio:LaunchProfile":                          true,
-	"aws-native:nimblestudio:StreamingImage":                         true,
-	"aws-native:nimblestudio:StudioComponent":                        true,
-	"aws-native:opensearchserverless:AccessPolicy":                   true,
-	"aws-native:opensearchserverless:LifecyclePolicy":                true,
-	"aws-native:opensearchserverless:SecurityConfig":                 true,
-	"aws-native:opensearchserverless:SecurityPolicy":                 true,
-	"aws-native:organizations:OrganizationalUnit":                    true,
-	"aws-native:organizations:Policy":                                true,
-	"aws-native:pcaconnectorad:ServicePrincipalName":                 true,
-	"aws-native:pcaconnectorad:Template":                             true,
-	"aws-native:pcaconnectorad:TemplateGroupAccessControlEntry":      true,
-	"aws-native:qbusiness:DataSource":                                true,
-	"aws-native:qbusiness:Index":                                     true,
-	"aws-native:qbusiness:Plugin":                                    true,
-	"aws-native:qbusiness:Retriever":                                 true,
-	"aws-native:qbusiness:WebExperience":                             true,
-	"aws-native:quicksight:Analysis":                                 true,
-	"aws-native:quicksight:Dashboard":                                true,
-	"aws-native:quicksight:DataSet":                                  true,
-	"aws-native:quicksight:DataSource":                               true,
-	"aws-native:quicksight:RefreshSchedule":                          true,
-	"aws-native:quicksight:Template":                                 true,
-	"aws-native:quicksight:Theme":                                    true,
-	"aws-native:rds:DbProxyTargetGroup":                              true,
-	"aws-native:refactorspaces:Application":                          true,
-	"aws-native:refactorspaces:Route":                                true,
-	"aws-native:refactorspaces:Service":                              true,
-	"aws-native:route53profiles:ProfileResourceAssociation":          true,
-	"aws-native:route53recoverycontrol:RoutingControl":               true,
-	"aws-native:route53recoverycontrol:SafetyRule":                   true,
-	"aws-native:s3outposts:AccessPoint":                              true,
-	"aws-native:s3outposts:Bucket":                                   true,
-	"aws-native:sagemaker:ImageVersion":                              true,
-	"aws-native:servicecatalog:ServiceActionAssociation":             true,
-	"aws-native:servicecatalogappregistry:AttributeGroupAssociation": true,
-	"aws-native:servicecatalogappregistry:ResourceAssociation":       true,
-	"aws-native:signer:ProfilePermission":                            true,
-	"aws-native:sso:Application":                                     true,
-	"aws-native:sso:Assignment":                                      true,
-	"aws-native:sso:InstanceAccessControlAttributeConfiguration":     true,
-	"aws-native:sso:PermissionSet":                                   true,
-	"aws-native:stepfunctions:StateMachineAlias":                     true,
-	"aws-native:stepfunctions:StateMachineVersion":                   true,
-	"aws-native:transfer:Agreement":                                  true,
-	"aws-native:verifiedpermissions:IdentitySource":                  true,
-	"aws-native:verifiedpermissions:Policy":                          true,
-	"aws-native:verifiedpermissions:PolicyTemplate":                  true,
-	"aws-native:vpclattice:AccessLogSubscription":                    true,
-	"aws-native:vpclattice:Listener":                                 true,
-	"aws-native:vpclattice:Rule":                                     true,
-	"aws-native:wafv2:IpSet":                                         true,
-	"aws-native:wafv2:RegexPatternSet":                               true,
-	"aws-native:wafv2:RuleGroup":                                     true,
-	"aws-native:wafv2:WebAcl":                                        true,
-	"aws-native:wisdom:AssistantAssociation":                         true,
-	"aws-native:workspacesweb:IdentityProvider":                      true,
-
-	// "seem" to be required to pass additional properties - they fail with "Invalid request"
-	"aws-native:guardduty:Member":                            true,
-	"aws-native:guardduty:ThreatIntelSet":                    true,
-	"aws-native:amplify:Domain":                              true,
-	"aws-native:lex:BotVersion":                              true,
-	"aws-native:quicksight:VpcConnection":                    true,
-	"aws-native:quicksight:Topic":                            true,
-	"aws-native:guardduty:IpSet":                             true,
-	"aws-native:guardduty:Master":                            true,
-	"aws-native:qldb:Stream":                                 true,
-	"aws-native:location:TrackerConsumer":                    true,
-	"aws-native:apigatewayv2:RouteResponse":                  true,
-	"aws-native:lex:BotAlias":                                true,
-	"aws-native:apigatewayv2:IntegrationResponse":            true,
-	"aws-native:globalaccelerator:Listener":                  true,
-	"aws-native:globalaccelerator:EndpointGroup":             true,
-	"aws-native:cloudformation:ResourceDefaultVersion":       true,
-	"aws-native:lambda:LayerVersionPermission":               true,
-	"aws-native:ec2:EnclaveCertificateIamRoleAssociation":    true,
-	"aws-native:lambda:LayerVersion":                         true,
-	"aws-native:shield:Protection":                           true,
-	"aws-native:shield:ProtectionGroup":                      true,
-	"aws-native:shield:DrtAccess":                            true,
-	"aws-native:shield:ProactiveEngagement":                  true,
-	"aws-native:ssmcontacts:ContactChannel":                  true,
-	"aws-native:applicationautoscaling:ScalableTarget":       true,
-	"aws-native:vpclattice:ServiceNetworkVpcAssociation":     true,
-	"aws-native:connect:ApprovedOrigin":                      true,
-	"aws-native:connect:EvaluationForm":                      true,
-	"aws-native:connect:InstanceStorageConfig":               true,
-	"aws-native:connect:SecurityKey":                         true,
-	"aws-native:vpclattice:ServiceNetworkServiceAssociation": true,
-	"aws-native:autoscaling:LifecycleHook":                   true,
-	"aws-native:s3:AccessGrantsLocation":                     true,
-	"aws-native:s3:AccessGrant":                              true,
-	"aws-native:ssmcontacts:Rotation":                        true,
-	"aws-native:lightsail:LoadBalancerTlsCertificate":        true,
-	"aws-native:cloudformation:Publisher":                    true,
-
-	// There are other resources that may fail depending on account configuration or permissions
-	// but worth trying to import them
+// loadUnsupportedResources returns the types to skip along with why: the built-in list, merged with
+// the published list with --fetch-unsupported-types and with the file passed with
+// --unsupported-types, so that newly found failing types are skipped without a new release.
+func loadUnsupportedResources() (map[string]string, error) {
+	types := map[string]string{}
+	if err := mergeUnsupportedResources(types, unsupportedResourcesJSON); err != nil {
+		return nil, err
+	}
+	if config.AWSFetchUnsupportedTypes.Bool() {
+		b, err := fetch.Get(context.Background(), unsupportedResourcesURL)
+		if err == nil {
+			err = mergeUnsupportedResources(types, b)
+		}
+		if err != nil {
+			fmt.Println("Failed to load the published unsupported types, using the built-in ones:", err)
+		}
+	}
+	if file := config.AWSUnsupportedTypes.Value(); file != "" {
+		b, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read unsupported types file: %w", err)
+		}
+		if err := mergeUnsupportedResources(types, b); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", file, err)
+		}
+	}
+	return types, nil
+}
 
+// mergeUnsupportedResources adds the types of the JSON object b to types. A type mapped to null is
+// removed, to try a type again that the built-in list skips.
+func mergeUnsupportedResources(types map[string]string, b []byte) error {
+	var list map[string]*string
+	if err := json.Unmarshal(b, &list); err != nil {
+		return err
+	}
+	for typ, reason := range list {
+		switch {
+		case reason == nil:
+			delete(types, typ)
+		case *reason == "":
+			types[typ] = "fails to list or import"
+		default:
+			types[typ] = *reason
+		}
+	}
+	return nil
 }
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudcontrol"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/pulumi/pulumi-cloud-import/pkg/config"
+	"github.com/pulumi/pulumi-cloud-import/pkg/debuglog"
+)
+
+// vpcFilter keeps the resources associated with one of the VPCs of --vpc-id, for teams owning a VPC
+// of a shared account. The VPCs and what's in them, their subnets, security groups, route tables,
+// network ACLs, gateways, endpoints and network interfaces, along with the instances the interfaces
+// are attached to, are looked up with the EC2 describe APIs. A resource is associated with a VPC when
+// its identifier or one of its properties is one of those, like a Lambda function in one of its
+// subnets or a load balancer in one of its security groups. Resources never referring to a VPC, like
+// IAM roles or S3 buckets, are left out.
+//
+// Properties are the ones ListResources returns, or GetResource for the types ListResources doesn't
+// return properties of.
+type vpcFilter struct {
+	// ids are the identifiers of the VPCs and of what's in them
+	ids map[string]bool
+
+	mu      sync.Mutex
+	kept    int
+	leftOut int
+}
+
+// newVPCFilter returns the filter of the resources of the account cfg points to, nil when no VPC is
+// passed. It panics when the VPCs can't be looked up, as every resource would be left out.
+func newVPCFilter(cfg aws.Config) *vpcFilter {
+	vpcs := config.VPCIDs.List()
+	if len(vpcs) == 0 {
+		return nil
+	}
+	f := &vpcFilter{ids: map[string]bool{}}
+	if err := f.load(ec2.NewFromConfig(cfg), cfg.Region, vpcs); err != nil {
+		panic(fmt.Sprintf("failed to look up the resources of %s: %v", strings.Join(vpcs, ", "), err))
+	}
+	return f
+}
+
+// load looks up the VPCs and what's in them in region.
+func (f *vpcFilter) load(client *ec2.Client, region string, vpcs []string) error {
+	ctx := context.Background()
+	filters := []types.Filter{{Name: aws.String("vpc-id"), Values: vpcs}}
+
+	found := 0
+	out, err := client.DescribeVpcs(ctx, &ec2.DescribeVpcsInput{Filters: filters})
+	if err != nil {
+		return err
+	}
+	for _, v := range out.Vpcs {
+		f.ids[aws.ToString(v.VpcId)] = true
+		found++
+	}
+	if found < len(vpcs) {
+		fmt.Printf("only %d of the %d VPCs of --vpc-id were found in %s\n", found, len(vpcs), region)
+	}
+
+	subnets := ec2.NewDescribeSubnetsPaginator(client, &ec2.DescribeSubnetsInput{Filters: filters})
+	for subnets.HasMorePages() {
+		page, err := subnets.NextPage(ctx)
+		if err != nil {
+			return err
+		}
+		for _, s := range page.Subnets {
+			f.ids[aws.ToString(s.SubnetId)] = true
+		}
+	}
+	groups := ec2.NewDescribeSecurityGroupsPaginator(client, &ec2.DescribeSecurityGroupsInput{Filters: filters})
+	for groups.HasMorePages() {
+		page, err := groups.NextPage(ctx)
+		if err != nil {
+			return err
+		}
+		for _, g := range page.SecurityGroups {
+			f.ids[aws.ToString(g.GroupId)] = true
+		}
+	}
+	routes := ec2.NewDescribeRouteTablesPaginator(client, &ec2.DescribeRouteTablesInput{Filters: filters})
+	for routes.HasMorePages() {
+		page, err := routes.NextPage(ctx)
+		if err != nil {
+			return err
+		}
+		for _, t := range page.RouteTables {
+			f.ids[aws.ToString(t.RouteTableId)] = true
+		}
+	}
+	acls := ec2.NewDescribeNetworkAclsPaginator(client, &ec2.DescribeNetworkAclsInput{Filters: filters})
+	for acls.HasMorePages() {
+		page, err := acls.NextPage(ctx)
+		if err != nil {
+			return err
+		}
+		for _, a := range page.NetworkAcls {
+			f.ids[aws.ToString(a.NetworkAclId)] = true
+		}
+	}
+	// internet gateways are attached to a VPC rather than in it
+	gateways := ec2.NewDescribeInternetGatewaysPaginator(client, &ec2.DescribeInternetGatewaysInput{
+		Filters: []types.Filter{{Name: aws.String("attachment.vpc-id"), Values: vpcs}},
+	})
+	for gateways.HasMorePages() {
+		page, err := gateways.NextPage(ctx)
+		if err != nil {
+			return err
+		}
+		for _, g := range page.InternetGateways {
+			f.ids[aws.ToString(g.InternetGatewayId)] = true
+		}
+	}
+	nats := ec2.NewDescribeNatGatewaysPaginator(client, &ec2.DescribeNatGatewaysInput{Filter: filters})
+	for nats.HasMorePages() {
+		page, err := nats.NextPage(ctx)
+		if err != nil {
+			return err
+		}
+		for _, g := range page.NatGateways {
+			f.ids[aws.ToString(g.NatGatewayId)] = true
+		}
+	}
+	endpoints := ec2.NewDescribeVpcEndpointsPaginator(client, &ec2.DescribeVpcEndpointsInput{Filters: filters})
+	for endpoints.HasMorePages() {
+		page, err := endpoints.NextPage(ctx)
+		if err != nil {
+			return err
+		}
+		for _, e := range page.VpcEndpoints {
+			f.ids[aws.ToString(e.VpcEndpointId)] = true
+		}
+	}
+	interfaces := ec2.NewDescribeNetworkInterfacesPaginator(client, &ec2.DescribeNetworkInterfacesInput{Filters: filters})
+	for interfaces.HasMorePages() {
+		page, err := interfaces.NextPage(ctx)
+		if err != nil {
+			return err
+		}
+		for _, i := range page.NetworkInterfaces {
+			f.ids[aws.ToString(i.NetworkInterfaceId)] = true
+			if i.Attachment != nil && i.Attachment.InstanceId != nil {
+				f.ids[aws.ToString(i.Attachment.InstanceId)] = true
+			}
+		}
+	}
+	debuglog.Println("found", len(f.ids), "resources in", strings.Join(vpcs, ", "))
+	return nil
+}
+
+// keep reports whether the resource of cfType identified by id, listed with properties, is kept. Resources listed for a parent, which was kept, are kept.
+func (f *vpcFilter) keep(client *cloudcontrol.Client, cfType, id, parent string, properties *string) bool {
+	if f == nil {
+		return true
+	}
+	kept := parent != "" || f.associated(id)
+	if !kept {
+		if properties == nil {
+			properties = f.properties(client, cfType, id)
+		}
+		kept = f.references(properties)
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if kept {
+		f.kept++
+	} else {
+		f.leftOut++
+	}
+	return kept
+}
+
+// associated reports whether value is one of the resources of the VPCs, on its own, as a part of a
+// composite identifier like vpc-1|igw-2, or at the end of an ARN.
+func (f *vpcFilter) associated(value string) bool {
+	for _, part := range strings.Split(value, "|") {
+		if f.ids[part] || f.ids[part[strings.LastIndex(part, "/")+1:]] {
+			return true
+		}
+	}
+	return false
+}
+
+// references reports whether one of the properties, however deeply nested, is associated with the
+// VPCs.
+func (f *vpcFilter) references(properties *string) bool {
+	if properties == nil {
+		return false
+	}
+	var props any
+	if err := json.Unmarshal([]byte(*properties), &props); err != nil {
+		return false
+	}
+	var walk func(v any) bool
+	walk = func(v any) bool {
+		switch v := v.(type) {
+		case string:
+			return f.associated(v)
+		case []any:
+			for _, e := range v {
+				if walk(e) {
+					return true
+				}
+			}
+		case map[string]any:
+			for _, e := range v {
+				if walk(e) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	return walk(props)
+}
+
+// properties reads the properties of a resource with GetResource.
+func (f *vpcFilter) properties(client *cloudcontrol.Client, cfType, id string) *string {
+	out, err := client.GetResource(context.Background(), &cloudcontrol.GetResourceInput{
+		TypeName:   aws.String(cfType),
+		Identifier: aws.String(id),
+	})
+	if err != nil || out.ResourceDescription == nil {
+		debuglog.Println("failed to read the properties of", cfType, id, err)
+		return nil
+	}
+	return out.ResourceDescription.Properties
+}
+
+// report prints how many resources the filter kept and left out.
+func (f *vpcFilter) report() {
+	if f == nil {
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	fmt.Printf("%d resources are associated with --vpc-id, %d were left out\n", f.kept, f.leftOut)
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
+	"github.com/pulumi/pulumi-cloud-import/pkg/naming"
+	"github.com/pulumi/pulumi-cloud-import/pkg/terminal"
+)
+
+// childListing lists a resource type that resource group listings leave out, like the subnets of a
+// virtual network or the record sets of a DNS zone, from the resource provider's API.
+type childListing struct {
+	// parentType is the ARM type of the resources the children are listed for
+	parentType string
+	// path is appended to the ID of the parent to list its children
+	path       string
+	apiVersion string
+	token      string
+	// typedNames qualifies the names of the children with the last segment of their ARM type, as
+	// record sets of different types share names, e.g. the A and TXT records of www
+	typedNames bool
+	// skip reports whether a child is created along with its parent and can't be imported on its own
+	skip func(name, armType string) bool
+}
+
+// childListings are the child resource types listed for their parents. Children nested deeper, like
+// blob containers under the default blob service, are parented to the closest discovered ancestor.
+var childListings = []childListing{
+	{parentType: "Microsoft.Network/virtualNetworks", path: "subnets", apiVersion: "2023-04-01", token: "azure-native:network:Subnet"},
+	{parentType: "Microsoft.Network/virtualNetworks", path: "virtualNetworkPeerings", apiVersion: "2023-04-01", token: "azure-native:network:VirtualNetworkPeering"},
+	{parentType: "Microsoft.Network/networkSecurityGroups", path: "securityRules", apiVersion: "2023-04-01", token: "azure-native:network:SecurityRule"},
+	{parentType: "Microsoft.Network/routeTables", path: "routes", apiVersion: "2023-04-01", token: "azure-native:network:Route"},
+	{parentType: "Microsoft.Network/dnszones", path: "recordsets", apiVersion: "2018-05-01", token: "azure-native:network:RecordSet", typedNames: true, skip: isApexRecord},
+	{parentType: "Microsoft.Network/privateDnsZones", path: "ALL", apiVersion: "2020-06-01", token: "azure-native:network:PrivateRecordSet", typedNames: true, skip: isApexRecord},
+	{parentType: "Microsoft.Storage/storageAccounts", path: "blobServices/default/containers", apiVersion: "2023-01-01", token: "azure-native:storage:BlobContainer"},
+	{parentType: "Microsoft.Sql/servers", path: "firewallRules", apiVersion: "2021-11-01", token: "azure-native:sql:FirewallRule"},
+}
+
+// isApexRecord reports whether a record set is the SOA or NS record set of the zone apex, which
+// Azure creates with the zone.
+func isApexRecord(name, armType string) bool {
+	recordType := strings.ToUpper(armType[strings.LastIndex(armType, "/")+1:])
+	return name == "@" && (recordType == "SOA" || recordType == "NS")
+}
+
+// childResource is a resource listed by a childListing.
+type childResource struct {
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	Type       string `json:"type"`
+	Properties struct {
+		ProvisioningState string `json:"provisioningState"`
+	} `json:"properties"`
+}
+
+type childPage struct {
+	Value    []childResource `json:"value"`
+	NextLink *string         `json:"nextLink"`
+}
+
+// childLister lists the children of discovered resources through the Azure Resource Manager API.
+type childLister struct {
+	client *arm.Client
+}
+
+func newChildLister(cred azcore.TokenCredential, options *arm.ClientOptions) (*childLister, error) {
+	client, err := arm.NewClient("main.childLister", "v1.0.0", cred, options)
+	if err != nil {
+		return nil, err
+	}
+	return &childLister{client: client}, nil
+}
+
+// listings returns the child listings of the resources of an ARM type.
+func (l *childLister) listings(armType string) []childListing {
+	listings := []childListing{}
+	for _, c := range childListings {
+		if strings.EqualFold(c.parentType, armType) {
+			listings = append(listings, c)
+		}
+	}
+	return listings
+}
+
+// list returns the children of the parent with the given ID and name, named after their parent as
+// children of different parents often share names, like the default subnet of every virtual network.
+func (l *childLister) list(ctx context.Context, c childListing, parentID, parentName string) ([]importSpec, error) {
+	children := []importSpec{}
+	url := fmt.Sprintf("%s%s/%s?api-version=%s", strings.TrimSuffix(l.client.Endpoint(), "/"), parentID, c.path, c.apiVersion)
+	for url != "" {
+		req, err := runtime.NewRequest(ctx, http.MethodGet, url)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := l.client.Pipeline().Do(req)
+		if err != nil {
+			return nil, err
+		}
+		if !runtime.HasStatusCode(resp, http.StatusOK) {
+			return nil, runtime.NewResponseError(resp)
+		}
+		var page childPage
+		if err := runtime.UnmarshalAsJSON(resp, &page); err != nil {
+			return nil, err
+		}
+		for _, r := range page.Value {
+			if c.skip != nil && c.skip(r.Name, r.Type) {
+				continue
+			}
+			if terminal.IsTerminal(r.Properties.ProvisioningState) {
+				terminal.Skip(r.Type, r.ID, r.Properties.ProvisioningState)
+				continue
+			}
+			// the prefix and suffix of the parent's name aren't repeated
+			name := naming.Azure.Name(naming.Base(parentName), r.Name)
+			if c.typedNames {
+				name = naming.Azure.Name(naming.Base(parentName), r.Type[strings.LastIndex(r.Type, "/")+1:], r.Name)
+			}
+			children = append(children, importSpec{ID: r.ID, Type: c.token, Name: name})
+		}
+		url = ""
+		if page.NextLink != nil {
+			url = *page.NextLink
+		}
+	}
+	return children, nil
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/resourcegraph/armresourcegraph"
+)
+
+// dependentResource is a discovered resource along with the IDs of its parent and of the
+// resources it references, e.g. a network interface referencing a subnet of a virtual network.
+type dependentResource struct {
+	importSpec
+	ParentID      string
+	DependencyIDs []string
+}
+
+var resourceIDRegex = regexp.MustCompile(`(?i)^/subscriptions/[^/]+/resourceGroups/[^/]+/providers/.+`)
+
+// queryResourceProperties returns the properties of every resource in the location by lower cased
+// ID. It uses a single Azure Resource Graph query rather than a request per resource.
+func queryResourceProperties(ctx context.Context, cred azcore.TokenCredential, options *arm.ClientOptions, subscriptionIDs []string, location string) (map[string]interface{}, error) {
+	client, err := armresourcegraph.NewClient(cred, options)
+	if err != nil {
+		return nil, err
+	}
+
+	query := fmt.Sprintf("Resources | where location =~ '%s' | project id, properties", location)
+	format := armresourcegraph.ResultFormatObjectArray
+	subscriptions := []*string{}
+	for i := range subscriptionIDs {
+		subscriptions = append(subscriptions, &subscriptionIDs[i])
+	}
+	properties := map[string]interface{}{}
+	var skipToken *string
+	for {
+		res, err := client.Resources(ctx, armresourcegraph.QueryRequest{
+			Query:         &query,
+			Subscriptions: subscriptions,
+			Options: &armresourcegraph.QueryRequestOptions{
+				ResultFormat: &format,
+				SkipToken:    skipToken,
+			},
+		}, nil)
+		if err != nil {
+			return nil, err
+		}
+		rows, _ := res.Data.([]interface{})
+		for _, row := range rows {
+			r, ok := row.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if id, ok := r["id"].(string); ok {
+				properties[strings.ToLower(id)] = r["properties"]
+			}
+		}
+		if res.SkipToken == nil || *res.SkipToken == "" {
+			return properties, nil
+		}
+		skipToken = res.SkipToken
+	}
+}
+
+// collectReferences appends every resource ID found in v to refs.
+func collectReferences(v interface{}, refs []string) []string {
+	switch v := v.(type) {
+	case string:
+		if resourceIDRegex.MatchString(v) {
+			refs = append(refs, v)
+		}
+	case map[string]interface{}:
+		for _, e := range v {
+			refs = collectReferences(e, refs)
+		}
+	case []interface{}:
+		for _, e := range v {
+			refs = collectReferences(e, refs)
+		}
+	}
+	return refs
+}
+
+// resolveDependencies wires every resource to the discovered resource its ID is nested in, falling
+// back to its resource group, and records the discovered resources its properties reference.
+// References to nested resources that are not imported themselves, like the IP configurations of a
+// network interface, resolve to the
+// closest discovered ancestor. The result is ordered so that parents and dependencies come first.
+func resolveDependencies(resources []importSpec, properties map[string]interface{}) []dependentResource {
+	byID := map[string]int{}
+	for i, r := range resources {
+		byID[strings.ToLower(r.ID)] = i
+	}
+
+	// resolve returns the index of the discovered resource id is, or is nested in
+	resolve := func(id string) (int, bool) {
+		id = strings.ToLower(strings.TrimSuffix(id, "/"))
+		for {
+			if i, ok := byID[id]; ok {
+				return i, true
+			}
+			parts := strings.Split(id, "/")
+			// stop once only the resource group and provider namespace are left
+			if len(parts) < 9 || parts[5] != "providers" {
+				return 0, false
+			}
+			id = strings.Join(parts[:len(parts)-2], "/")
+		}
+	}
+
+	deps := make([]dependentResource, len(resources))
+	for i, r := range resources {
+		d := dependentResource{importSpec: r, ParentID: r.Parent}
+		parts := strings.Split(strings.TrimSuffix(r.ID, "/"), "/")
+		if len(parts) > 9 {
+			if p, ok := resolve(strings.Join(parts[:len(parts)-2], "/")); ok && p != i {
+				d.ParentID = resources[p].ID
+			}
+		}
+
+		seen := map[int]bool{i: true}
+		for _, ref := range collectReferences(properties[strings.ToLower(r.ID)], nil) {
+			j, ok := resolve(ref)
+			if !ok || seen[j] || resources[j].ID == d.ParentID {
+				continue
+			}
+			// references to nested resources, like the IP configurations of a network interface, are
+			// not dependencies
+			if strings.HasPrefix(strings.ToLower(resources[j].ID), strings.ToLower(r.ID)+"/") {
+				continue
+			}
+			seen[j] = true
+			d.DependencyIDs = append(d.DependencyIDs, resources[j].ID)
+		}
+		deps[i] = d
+	}
+
+	// order parents and dependencies first, references forming a cycle are dropped
+	ordered := []dependentResource{}
+	state := map[string]int{}
+	var visit func(i int)
+	visit = func(i int) {
+		id := strings.ToLower(deps[i].ID)
+		state[id] = 1
+		kept := []string{}
+		for _, dep := range append([]string{deps[i].ParentID}, deps[i].DependencyIDs...) {
+			j, ok := byID[strings.ToLower(dep)]
+			if !ok {
+				continue
+			}
+			switch state[strings.ToLower(dep)] {
+			case 0:
+				visit(j)
+			case 1:
+				if dep != deps[i].ParentID {
+					continue
+				}
+			}
+			if dep != deps[i].ParentID {
+				kept = append(kept, dep)
+			}
+		}
+		deps[i].DependencyIDs = kept
+		state[id] = 2
+		ordered = append(ordered, deps[i])
+	}
+	for i := range deps {
+		if state[strings.ToLower(deps[i].ID)] == 0 {
+			visit(i)
+		}
+	}
+	return ordered
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/pulumi/pulumi-cloud-import/pkg/redact"
+	"github.com/pulumi/pulumi-cloud-import/pkg/supportbundle"
+)
+
+// Kinds of errors discovery fails with. The errors returned by buildImportSpec match one of them
+// with errors.Is.
+var (
+	errConfiguration  = errors.New("invalid configuration")
+	errAuthentication = errors.New("authentication failed")
+	errTokenExpired   = errors.New("the OIDC token has expired")
+	errTokenEndpoint  = errors.New("the token endpoint failed")
+	errPermission     = errors.New("permission denied")
+	errSchema         = errors.New("failed to download the azure-native schema")
+	errListing        = errors.New("failed to list resources")
+)
+
+// discoveryError is an error of the discovery of resources along with a hint at how to fix it.
+type discoveryError struct {
+	kind error
+	err  error
+	hint string
+}
+
+func (e *discoveryError) Error() string {
+	msg := e.kind.Error()
+	if e.err != nil {
+		msg += ": " + e.err.Error()
+	}
+	if e.hint != "" {
+		msg += "\n" + e.hint
+	}
+	return msg
+}
+
+func (e *discoveryError) Is(target error) bool {
+	return target == e.kind
+}
+
+func (e *discoveryError) Unwrap() error {
+	return e.err
+}
+
+const (
+	configurationHint  = "Pass the subscriptions to scan with --subscription or set ARM_SUBSCRIPTION_ID."
+	authenticationHint = "Sign in with `az login`, or set AZURE_CLIENT_ID, AZURE_TENANT_ID and AZURE_CLIENT_SECRET, or ARM_OIDC_TOKEN along with ARM_CLIENT_ID and ARM_TENANT_ID."
+	tokenExpiredHint   = "OIDC tokens of CI providers are short lived, request a fresh ARM_OIDC_TOKEN and run the scan again."
+	permissionHint     = "Grant the Reader role on the subscription to the identity running the scan."
+	schemaHint         = "Check that raw.githubusercontent.com is reachable, raise --fetch-timeout, or pin a released version with -ldflags \"-X main.azureNativeVersion=v2.0.0\"."
+	notFoundHint       = "Check the subscription IDs passed with --subscription or ARM_SUBSCRIPTION_ID."
+)
+
+// isExpiredAssertion reports whether err is Azure AD rejecting an OIDC token that has expired
+// (AADSTS700024), which no retry fixes.
+func isExpiredAssertion(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "AADSTS700024")
+}
+
+// listingError classifies an error of the resource APIs, which is how expired or missing
+// credentials and missing role assignments surface.
+func listingError(err error) error {
+	var d *discoveryError
+	if errors.As(err, &d) {
+		return err
+	}
+	var authErr *azidentity.AuthenticationFailedError
+	if errors.As(err, &authErr) {
+		return &discoveryError{kind: errAuthentication, err: err, hint: authenticationHint}
+	}
+	var respErr *azcore.ResponseError
+	if errors.As(err, &respErr) {
+		switch respErr.StatusCode {
+		case http.StatusUnauthorized:
+			return &discoveryError{kind: errAuthentication, err: err, hint: authenticationHint}
+		case http.StatusForbidden:
+			return &discoveryError{kind: errPermission, err: err, hint: permissionHint}
+		case http.StatusNotFound:
+			return &discoveryError{kind: errListing, err: err, hint: notFoundHint}
+		}
+	}
+	return &discoveryError{kind: errListing, err: err}
+}
+
+// retriable reports whether the scan may succeed if run again, as when the token endpoint was
+// unavailable or the OIDC token expired, which a run with a fresh token gets past. Credentials
+// that are rejected won't be accepted the next time either.
+func retriable(err error) bool {
+	return errors.Is(err, errTokenEndpoint) || errors.Is(err, errTokenExpired)
+}
+
+// exit prints err and exits, for the errors the program can't carry on after.
+func exit(err error) {
+	fmt.Fprintln(os.Stderr, redact.String(err.Error()))
+	if retriable(err) {
+		fmt.Fprintln(os.Stderr, "The failure may be transient, run the scan again.")
+	}
+	supportbundle.Exit(1)
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pulumi/pulumi-cloud-import/pkg/config"
+	"github.com/pulumi/pulumi-cloud-import/pkg/ignore"
+	"github.com/pulumi/pulumi-cloud-import/pkg/manifest"
+	"github.com/pulumi/pulumi-cloud-import/pkg/offline"
+	"github.com/pulumi/pulumi-cloud-import/pkg/tagfilter"
+)
+
+// resourceGroupType is the ARM type of resource groups in Resource Graph, which lists them in the
+// ResourceContainers table.
+const resourceGroupType = "microsoft.resources/subscriptions/resourcegroups"
+
+// importFromInventory builds the import file from the Azure Resource Graph export passed with
+// --from-inventory rather than from the resources API. The export is the scope of the import: every
+// location and subscription in it is imported. Resource groups come from the export when it has
+// them, and from the IDs of their resources otherwise. Resources are nested in each other and depend
+// on each other like in a scan when the export has their properties.
+func importFromInventory(path string) (importFile, error) {
+	imports := importFile{
+		Resources: []importSpec{},
+	}
+	rows, err := offline.ReadResourceGraph(path)
+	if err != nil {
+		return imports, &discoveryError{kind: errConfiguration, err: err}
+	}
+	pkgSpec, err := getAzureNativeSchema()
+	if err != nil {
+		return imports, &discoveryError{kind: errSchema, err: err, hint: schemaHint}
+	}
+	// Resource Graph lower-cases types, tokens are looked up in any case
+	tokens := map[string]string{}
+	for tok := range pkgSpec.Resources {
+		tokens[strings.ToLower(tok)] = tok
+	}
+	for previous, current := range schemaAliases(pkgSpec) {
+		if _, ok := tokens[strings.ToLower(previous)]; !ok {
+			tokens[strings.ToLower(previous)] = current
+		}
+	}
+
+	subscriptionIDs := []string{}
+	bySubscription := map[string]*subscription{}
+	for _, r := range rows {
+		if id := subscriptionOf(r.ID); id != "" && bySubscription[id] == nil {
+			bySubscription[id] = &subscription{}
+			subscriptionIDs = append(subscriptionIDs, id)
+		}
+	}
+	sort.Strings(subscriptionIDs)
+	for _, id := range subscriptionIDs {
+		bySubscription[id] = namedSubscription(id, len(subscriptionIDs) > 1)
+	}
+
+	includeGroups := config.ResourceGroups.List()
+	excludeGroups := config.ExcludeResourceGroups.List()
+	tagged := tagfilter.FromFlags()
+	unsupported := &unsupportedResources{}
+	defer unsupported.report()
+
+	// resource groups are added once, whether listed in the export or found in the ID of a resource
+	groups := map[string]bool{}
+	discovered := []importSpec{}
+	properties := map[string]interface{}{}
+	addGroup := func(id string) bool {
+		parts := strings.Split(id, "/")
+		if groups[strings.ToLower(id)] {
+			return true
+		}
+		if !includeResourceGroup(parts[4], includeGroups, excludeGroups) || ignore.Skip("azure-native:resources:ResourceGroup", id) {
+			return false
+		}
+		groups[strings.ToLower(id)] = true
+		discovered = append(discovered, importSpec{
+			ID:   id,
+			Type: "azure-native:resources:ResourceGroup",
+			Name: bySubscription[subscriptionOf(id)].name(parts[4]),
+		})
+		return true
+	}
+
+	seen := map[string]bool{}
+	missing := 0
+	for _, r := range rows {
+		parts := strings.Split(r.ID, "/")
+		// resources outside of resource groups, like subscriptions, aren't imported by scans either
+		if len(parts) < 5 || subscriptionOf(r.ID) == "" || !strings.EqualFold(parts[3], "resourceGroups") || seen[strings.ToLower(r.ID)] {
+			continue
+		}
+		seen[strings.ToLower(r.ID)] = true
+		group := strings.Join(parts[:5], "/")
+		if strings.EqualFold(r.Type, resourceGroupType) {
+			addGroup(group)
+			continue
+		}
+		if isClassicType(r.Type) {
+			unsupported.addClassic(r.ID, r.Type)
+			continue
+		}
+		if ignore.Skip(r.Type, r.ID) {
+			continue
+		}
+		typeToken, ok := tokens[strings.ToLower(armTypeToken(r.Type))]
+		if !ok {
+			missing++
+			continue
+		}
+		if resourcesToSkip[typeToken] {
+			continue
+		}
+		if tagged != nil && !tagged.Matches(r.Tags, false) {
+			continue
+		}
+		// the resources of groups that are filtered out are left out
+		if !addGroup(group) {
+			continue
+		}
+		discovered = append(discovered, importSpec{
+			ID:     r.ID,
+			Type:   typeToken,
+			Name:   bySubscription[subscriptionOf(r.ID)].name(parts[len(parts)-1]),
+			Parent: group,
+		})
+		if r.Properties != nil {
+			properties[strings.ToLower(r.ID)] = r.Properties
+		}
+	}
+	if missing > 0 {
+		fmt.Printf("%d resources of types that aren't in the azure-native schema are left out\n", missing)
+	}
+	unsupported.checkUnmanagedDisks(discovered, properties)
+
+	names := map[string]string{}
+	for _, resource := range resolveDependencies(discovered, properties) {
+		names[resource.ID] = resource.Name
+		spec := importSpec{
+			ID:       resource.ID,
+			Type:     resource.Type,
+			Name:     resource.Name,
+			Parent:   names[resource.ParentID],
+			Provider: bySubscription[subscriptionOf(resource.ID)].provider,
+		}
+		for _, dep := range resource.DependencyIDs {
+			spec.Dependencies = append(spec.Dependencies, names[dep])
+		}
+		imports.Resources = append(imports.Resources, spec)
+	}
+
+	ignore.Report()
+	manifest.Identify("subscription", strings.Join(subscriptionIDs, ","))
+	manifest.Identify("inventory", path)
+	if err := manifest.Write(); err != nil {
+		return imports, err
+	}
+	return imports, nil
+}
@@ -5,9 +5,11 @@ go 1.19
 require (
 	github.com/Azure/azure-sdk-for-go/sdk/azcore v1.5.0
 	github.com/Azure/azure-sdk-for-go/sdk/azidentity v1.2.2
+	github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/resourcegraph/armresourcegraph v0.7.1
 	github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/resources/armresources v1.1.0
 	github.com/gertd/go-pluralize v0.2.1
 	github.com/hashicorp/go-azure-sdk v0.20230408.1052134
+	github.com/pulumi/pulumi-cloud-import/pkg v0.0.0
 	github.com/pulumi/pulumi/pkg/v3 v3.60.1
 	github.com/pulumi/pulumi/sdk/v3 v3.60.1
 )
@@ -96,3 +98,5 @@ require (
 	software.sslmate.com/src/go-pkcs12 v0.2.0 // indirect
 	sourcegraph.com/sourcegraph/appdash v0.0.0-20211028080628-e2786a622600 // indirect
 )
+
+replace github.com/pulumi/pulumi-cloud-import/pkg => ../pkg
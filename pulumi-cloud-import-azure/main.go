@@ -1,31 +1,62 @@
 package main
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io/ioutil"
-	"log"
 	"net/http"
 	"os"
-	"regexp"
+	"sort"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
 	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/resources/armresources"
-	"github.com/gertd/go-pluralize"
 	"github.com/hashicorp/go-azure-sdk/sdk/auth"
 	"github.com/hashicorp/go-azure-sdk/sdk/environments"
+	"github.com/pulumi/pulumi-cloud-import/pkg/aliases"
+	"github.com/pulumi/pulumi-cloud-import/pkg/cancel"
+	"github.com/pulumi/pulumi-cloud-import/pkg/chaos"
+	"github.com/pulumi/pulumi-cloud-import/pkg/compress"
+	"github.com/pulumi/pulumi-cloud-import/pkg/config"
+	"github.com/pulumi/pulumi-cloud-import/pkg/costtags"
+	"github.com/pulumi/pulumi-cloud-import/pkg/coverage"
+	"github.com/pulumi/pulumi-cloud-import/pkg/created"
+	"github.com/pulumi/pulumi-cloud-import/pkg/debuglog"
+	"github.com/pulumi/pulumi-cloud-import/pkg/denied"
+	"github.com/pulumi/pulumi-cloud-import/pkg/events"
+	"github.com/pulumi/pulumi-cloud-import/pkg/fetch"
+	"github.com/pulumi/pulumi-cloud-import/pkg/ignore"
+	"github.com/pulumi/pulumi-cloud-import/pkg/ignorechanges"
+	"github.com/pulumi/pulumi-cloud-import/pkg/insights"
+	"github.com/pulumi/pulumi-cloud-import/pkg/manifest"
+	"github.com/pulumi/pulumi-cloud-import/pkg/offline"
+	"github.com/pulumi/pulumi-cloud-import/pkg/ownership"
+	"github.com/pulumi/pulumi-cloud-import/pkg/quarantine"
+	"github.com/pulumi/pulumi-cloud-import/pkg/recorder"
+	"github.com/pulumi/pulumi-cloud-import/pkg/redact"
+	"github.com/pulumi/pulumi-cloud-import/pkg/retry"
+	"github.com/pulumi/pulumi-cloud-import/pkg/shard"
+	"github.com/pulumi/pulumi-cloud-import/pkg/stackstate"
+	"github.com/pulumi/pulumi-cloud-import/pkg/summary"
+	"github.com/pulumi/pulumi-cloud-import/pkg/supportbundle"
+	"github.com/pulumi/pulumi-cloud-import/pkg/terminal"
+	"github.com/pulumi/pulumi-cloud-import/pkg/version"
+	"github.com/pulumi/pulumi-cloud-import/pkg/workers"
 	pschema "github.com/pulumi/pulumi/pkg/v3/codegen/schema"
 	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
 	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
 )
 
 type importFile struct {
+	// Metadata tells which environment the import file was produced from, `pulumi import`
+	// ignores it
+	Metadata  *manifest.Header        `json:"metadata,omitempty"`
 	NameTable map[string]resource.URN `json:"nameTable"`
 	Resources []importSpec            `json:"resources"`
 }
@@ -39,6 +70,8 @@ type importSpec struct {
 	Version           string   `json:"version"`
 	PluginDownloadURL string   `json:"pluginDownloadUrl"`
 	Properties        []string `json:"properties"`
+	// names of the resources this resource references, for tools building a dependency graph
+	Dependencies []string `json:"dependencies,omitempty"`
 }
 
 type Mode int64
@@ -50,45 +83,129 @@ const (
 )
 
 func main() {
+	version.HandleFlag(version.Schema{Name: "azure-native schema", Version: azureNativeVersion})
+	config.HandleCommand()
+	supportbundle.Start()
+	defer supportbundle.Write()
+
+	http.DefaultTransport = denied.Wrap(workers.Wrap(chaos.Wrap(recorder.Wrap(http.DefaultTransport))))
+
 	isImportMode := isImportMode()
+	offline.Check(isImportMode)
 
 	// pulumi read resource mode
 	if !isImportMode {
-		pulumi.Run(func(ctx *pulumi.Context) error {
+		supportbundle.Run(func(ctx *pulumi.Context) error {
 			_, err := buildImportSpec(ctx, ReadMode)
-			return err
+			if err != nil {
+				return err
+			}
+			return quarantine.Save()
 		})
 	} else {
 		mode := ImportMode
-		imports, err := buildImportSpec(nil, mode)
+		// resources already in the stack given with --stack are left out of the import file
+		state, err := stackstate.FromFlags()
+		if err != nil {
+			exit(err)
+		}
+		// with --from-inventory, resources are found in an export rather than in the cloud
+		var imports importFile
+		if path := offline.Path(); path != "" {
+			imports, err = importFromInventory(path)
+		} else {
+			imports, err = buildImportSpec(nil, mode)
+		}
 		if err != nil {
-			panic(err)
+			exit(err)
+		}
+		if err := insights.Diff(imports.Resources, specRef); err != nil {
+			exit(err)
+		}
+		imports.Resources, imports.NameTable = stackstate.Filter(state, imports.Resources, specRef)
+
+		// resources of several subscriptions are imported with the provider of their subscription,
+		// which has to be in the stack
+		missing := map[string]bool{}
+		for i, r := range imports.Resources {
+			if r.Provider == "" {
+				continue
+			}
+			if urn, ok := state.Named(providerType, r.Provider); ok {
+				imports.NameTable[r.Provider] = urn
+				continue
+			}
+			if !missing[r.Provider] {
+				if state == nil {
+					fmt.Printf("Pass --stack to import resources with the %s provider of the stack, they will be imported with the default provider of whoever runs pulumi import\n", r.Provider)
+				} else {
+					fmt.Printf("No provider named %s in the stack, resources will be imported with the default provider of whoever runs pulumi import\n", r.Provider)
+				}
+			}
+			missing[r.Provider] = true
+			imports.Resources[i].Provider = ""
 		}
 		fmt.Printf("Total resources: %d", len(imports.Resources))
 
+		imports.Metadata = manifest.NewHeader()
 		err = writeImportFile(imports)
 		if err != nil {
-			panic(err)
+			exit(err)
 		}
 	}
 
 }
 
+// tokenRefreshMargin is how long before it expires a cached token is refreshed.
+const tokenRefreshMargin = 5 * time.Minute
+
+// tokenWrapper adapts an OIDC authorizer to the credential ARM clients take. Every request of every
+// client asks for a token, so the token is cached until shortly before it expires.
 type tokenWrapper struct {
 	auth.Authorizer
+	mu    sync.Mutex
+	token azcore.AccessToken
 }
 
-func (t tokenWrapper) GetToken(ctx context.Context, options policy.TokenRequestOptions) (azcore.AccessToken, error) {
-	tok, err := t.Token(ctx, nil)
-	if err != nil {
-		panic(err)
+func (t *tokenWrapper) GetToken(ctx context.Context, options policy.TokenRequestOptions) (azcore.AccessToken, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.token.Token != "" && time.Until(t.token.ExpiresOn) > tokenRefreshMargin {
+		return t.token, nil
+	}
+
+	// the token endpoint fails transiently under load, but an expired assertion won't get any better
+	err := retry.Do(ctx, func() error {
+		tok, err := t.Token(ctx, nil)
+		if err != nil {
+			if isExpiredAssertion(err) {
+				return retry.Stop(err)
+			}
+			return err
+		}
+		t.token = azcore.AccessToken{Token: tok.AccessToken, ExpiresOn: tok.Expiry}
+		return nil
+	}, func(err error, class retry.Class, delay time.Duration) {
+		debuglog.Println("failed to get a token, retrying in", delay, err)
+	})
+	if err == nil {
+		return t.token, nil
+	}
+	// the token being refreshed is still good until it expires
+	if t.token.Token != "" && time.Now().Before(t.token.ExpiresOn) {
+		return t.token, nil
 	}
-	at := azcore.AccessToken{
-		Token:     tok.AccessToken,
-		ExpiresOn: tok.Expiry,
+	if isExpiredAssertion(err) {
+		return azcore.AccessToken{}, &discoveryError{kind: errTokenExpired, err: err, hint: tokenExpiredHint}
 	}
+	return azcore.AccessToken{}, &discoveryError{kind: errTokenEndpoint, err: err, hint: authenticationHint}
+}
+
+// replayCredential stands in for real credentials when responses are replayed from a recording
+type replayCredential struct{}
 
-	return at, nil
+func (replayCredential) GetToken(ctx context.Context, options policy.TokenRequestOptions) (azcore.AccessToken, error) {
+	return azcore.AccessToken{Token: "replay", ExpiresOn: time.Now().Add(time.Hour)}, nil
 }
 
 var resourcesToSkip = map[string]bool{}
@@ -98,15 +215,18 @@ func buildImportSpec(ctx *pulumi.Context, mode Mode) (importFile, error) {
 		Resources: []importSpec{},
 	}
 
-	subscriptionID := getSubscriptionID()
+	subscriptionIDs, err := getSubscriptionIDs()
+	if err != nil {
+		return imports, err
+	}
 	location := getLocation()
 
 	pkgSpec, err := getAzureNativeSchema()
 	if err != nil {
-		panic(err)
+		return imports, &discoveryError{kind: errSchema, err: err, hint: schemaHint}
 	}
 
-	pluralize := pluralize.NewClient()
+	typeAliases := schemaAliases(pkgSpec)
 
 	var wg sync.WaitGroup
 
@@ -114,7 +234,9 @@ func buildImportSpec(ctx *pulumi.Context, mode Mode) (importFile, error) {
 
 	var cred azcore.TokenCredential
 
-	if oidcToken != "" {
+	if recorder.Replaying() {
+		cred = replayCredential{}
+	} else if oidcToken != "" {
 		env := *environments.AzurePublic()
 		c, err := auth.NewOIDCAuthorizer(context.Background(), auth.OIDCAuthorizerOptions{
 			FederatedAssertion: oidcToken,
@@ -124,51 +246,119 @@ func buildImportSpec(ctx *pulumi.Context, mode Mode) (importFile, error) {
 			Api:                env.ResourceManager,
 		})
 		if err != nil {
-			panic(err)
+			return imports, &discoveryError{kind: errAuthentication, err: err, hint: authenticationHint}
 		}
 
-		cred = tokenWrapper{c}
+		cred = &tokenWrapper{Authorizer: c}
 	} else {
 		cred, err = azidentity.NewDefaultAzureCredential(nil)
 		if err != nil {
-			panic(fmt.Sprintf("Authentication failure: %+v", err))
+			return imports, &discoveryError{kind: errAuthentication, err: err, hint: authenticationHint}
 		}
 	}
 
-	// Azure SDK Azure Resource Management clients accept the credential as a parameter
-	resourceClient, err := armresources.NewClient(subscriptionID, cred, nil)
-	if err != nil {
-		panic(err)
+	// ARM clients don't use the default transport unless told to, which records denied requests and
+	// throttling. They retry throttled and failed requests themselves, honoring Retry-After, with the
+	// delays of the policy of throttled requests.
+	clientOptions := &arm.ClientOptions{
+		ClientOptions: policy.ClientOptions{
+			Transport: http.DefaultClient,
+			Retry: policy.RetryOptions{
+				MaxRetries:    int32(retry.Throttles.Attempts - 1),
+				RetryDelay:    retry.Throttles.Base,
+				MaxRetryDelay: retry.Throttles.Max,
+			},
+		},
 	}
-	resourceGroupClient, err := armresources.NewResourceGroupsClient(subscriptionID, cred, nil)
+
+	subscriptions := []*subscription{}
+	bySubscription := map[string]*subscription{}
+	for _, id := range subscriptionIDs {
+		s, err := newSubscription(id, cred, clientOptions, len(subscriptionIDs) > 1)
+		if err != nil {
+			return imports, &discoveryError{kind: errConfiguration, err: err}
+		}
+		subscriptions = append(subscriptions, s)
+		bySubscription[strings.ToLower(id)] = s
+	}
+
+	children, err := newChildLister(cred, clientOptions)
 	if err != nil {
-		panic(err)
+		return imports, &discoveryError{kind: errConfiguration, err: err}
 	}
 
-	rgPager := resourceGroupClient.NewListPager(nil)
+	includeGroups := config.ResourceGroups.List()
+	excludeGroups := config.ExcludeResourceGroups.List()
+	tagged := newTagFilter()
 
-	resourceGroups := []importSpec{}
+	// the first listing failure stops the scan, the listings already started complete
+	var failOnce sync.Once
+	var listErr error
+	fail := func(err error) {
+		failOnce.Do(func() { listErr = listingError(err) })
+	}
 
-	for rgPager.More() {
-		page, err := rgPager.NextPage(context.Background())
-		if err != nil {
-			log.Fatalf("Failed to list resources: %+v", err)
-		}
+	// the resource groups of every subscription are listed at once
+	var mu sync.Mutex
+	resourceGroups := []importSpec{}
+	for _, s := range subscriptions {
+		wg.Add(1)
+		go func(s *subscription) {
+			defer wg.Done()
+			scope := "/subscriptions/" + s.id
+			events.Started(scope)
+			count := 0
+			rgPager := s.groups.NewListPager(nil)
+			for rgPager.More() {
+				page, err := rgPager.NextPage(context.Background())
+				if err != nil {
+					events.Error(scope, err)
+					fail(err)
+					return
+				}
 
-		for _, resource := range page.ResourceGroupListResult.Value {
-			if resource.Location != nil && *resource.Location != location {
-				continue
-			}
-			id := *resource.ID
-			name := *resource.Name
-			resource := importSpec{
-				ID:   id,
-				Type: "azure-native:resources:ResourceGroup",
-				Name: clearString(name),
+				for _, resource := range page.ResourceGroupListResult.Value {
+					if resource.Location != nil && *resource.Location != location {
+						continue
+					}
+					if resource.Properties != nil && resource.Properties.ProvisioningState != nil && terminal.IsTerminal(*resource.Properties.ProvisioningState) {
+						terminal.Skip("azure-native:resources:ResourceGroup", *resource.ID, *resource.Properties.ProvisioningState)
+						continue
+					}
+					// the resources of ignored groups are never listed
+					if ignore.Skip("azure-native:resources:ResourceGroup", *resource.ID) {
+						continue
+					}
+					id := *resource.ID
+					name := *resource.Name
+					// resources of groups that are filtered out are never listed
+					if !includeResourceGroup(name, includeGroups, excludeGroups) {
+						continue
+					}
+					owner, evidence := classifyOwnership(resource.Tags, resource.ManagedBy)
+					tags := tagValues(resource.Tags)
+					resource := importSpec{
+						ID:   id,
+						Type: "azure-native:resources:ResourceGroup",
+						Name: s.name(name),
+					}
+					ownership.Add(resource.Type, resource.Name, resource.ID, owner, evidence)
+					costtags.Add(resource.Type, resource.Name, resource.ID, tags)
+					count++
+					events.Discovered(scope, resource.Type, resource.Name, resource.ID)
+					mu.Lock()
+					resourceGroups = append(resourceGroups, resource)
+					mu.Unlock()
+				}
 			}
-			resourceGroups = append(resourceGroups, resource)
-		}
+			events.Finished(scope, count)
+		}(s)
+	}
+	wg.Wait()
+	if listErr != nil {
+		return imports, listErr
 	}
+	sort.Slice(resourceGroups, func(i, j int) bool { return resourceGroups[i].ID < resourceGroups[j].ID })
 
 	// create a buffered channel. we want to register all resource groups first, and then process resources so that parents are present
 	importChan := make(chan importSpec, len(resourceGroups))
@@ -177,44 +367,87 @@ func buildImportSpec(ctx *pulumi.Context, mode Mode) (importFile, error) {
 		importChan <- resourceGroup
 	}
 
-	// currently one goroutine per resource group. This could be too many for large subscriptions.
+	// one goroutine per resource group, of which --workers list resources at a time whatever their
+	// subscription
 	chunks := len(resourceGroups)
+	slots := make(chan int, workers.Count(10))
+	for i := 0; i < cap(slots); i++ {
+		slots <- i
+	}
+	unsupported := &unsupportedResources{}
+	// resource groups are kept whatever their creation time, for the resources created in them
+	window := created.FromFlags()
+	// the provisioning state tells the resources being deleted apart
+	expand := "provisioningState"
+	if window != nil {
+		expand += ",createdTime"
+	}
 
 	for i := 0; i < chunks; i++ {
 		wg.Add(1)
-		go func(resourceGroup string) {
+		go func(resourceGroup string, s *subscription) {
 			defer func() {
 				if r := recover(); r != nil {
-					fmt.Printf("encountered error processing Azure resources: %v \n", r)
+					redact.Printf("encountered error processing Azure resources: %v \n", r)
 				}
 			}()
 			defer wg.Done()
+			slot := <-slots
+			defer func() { slots <- slot }()
+			workers.Wait(slot)
 
 			seen := map[string]bool{}
+			events.Started(resourceGroup)
+			count := 0
 
-			filter := fmt.Sprintf("location eq '%s'", location)
+			filter := tagged.listFilter(location)
 
 			rgParts := strings.Split(resourceGroup, "/")
 			rgName := rgParts[len(rgParts)-1]
 
-			pager := resourceClient.NewListByResourceGroupPager(rgName, &armresources.ClientListByResourceGroupOptions{
+			// a cancelled resource group keeps the resources listed so far
+			ctx, done := cancel.Scope(resourceGroup)
+			defer done()
+
+			pager := s.resources.NewListByResourceGroupPager(rgName, &armresources.ClientListByResourceGroupOptions{
 				Filter: &filter,
+				Expand: &expand,
 			})
-			for pager.More() {
-				page, err := pager.NextPage(context.Background())
+			for pager.More() && !cancel.Cancelled(resourceGroup) {
+				page, err := pager.NextPage(ctx)
+				if cancel.Cancelled(resourceGroup) {
+					break
+				}
 				if err != nil {
-					log.Fatalf("Failed to list resources: %+v", err)
+					events.Error(resourceGroup, err)
+					fail(err)
+					return
 				}
 
 				for _, resource := range page.ResourceListResult.Value {
 					id := *resource.ID
-					parts := strings.Split(*resource.Type, ".")
-					parts = strings.Split(parts[1], "/")
+					armType := *resource.Type
+					if isClassicType(*resource.Type) {
+						unsupported.addClassic(id, *resource.Type)
+						continue
+					}
+					if resource.ProvisioningState != nil && terminal.IsTerminal(*resource.ProvisioningState) {
+						terminal.Skip(armType, id, *resource.ProvisioningState)
+						continue
+					}
+					if ignore.Skip(armType, id) {
+						continue
+					}
 					nameParts := strings.Split(*resource.ID, "/")
-					namespace := parts[0]
-					resourceType := pluralize.Singular(strings.Title(parts[len(parts)-1]))
 					name := nameParts[len(nameParts)-1]
-					typeToken := fmt.Sprintf("azure-native:%s:%s", strings.ToLower(namespace), resourceType)
+					typeToken := armTypeToken(*resource.Type)
+
+					// resources that were renamed in the schema are only emitted under their current token
+					if _, ok := pkgSpec.Resources[typeToken]; !ok {
+						if current, ok := typeAliases[typeToken]; ok {
+							typeToken = current
+						}
+					}
 
 					if _, ok := pkgSpec.Resources[typeToken]; !ok {
 						fmt.Printf("skipping resource %s because it is not in the schema, translated to %s (this could be a bug)\n", *resource.Type, typeToken)
@@ -225,22 +458,72 @@ func buildImportSpec(ctx *pulumi.Context, mode Mode) (importFile, error) {
 						continue
 					}
 
-					if seen[id] {
+					// resource IDs are case insensitive
+					if seen[strings.ToLower(id)] {
+						continue
+					}
+					seen[strings.ToLower(id)] = true
+
+					var createdTime time.Time
+					if resource.CreatedTime != nil {
+						createdTime = *resource.CreatedTime
+					}
+					if !window.Keep(createdTime) {
+						continue
+					}
+					if !tagged.inLocation(resource.Location, location) || !tagged.keep(tagValues(resource.Tags)) {
 						continue
 					}
-					seen[id] = true
 
+					owner, evidence := classifyOwnership(resource.Tags, resource.ManagedBy)
+					tags := tagged.tags(tagValues(resource.Tags))
 					resource := importSpec{
 						ID:     id,
 						Type:   typeToken,
-						Name:   clearString(name),
+						Name:   s.name(name),
 						Parent: resourceGroup,
 					}
+					ownership.Add(resource.Type, resource.Name, resource.ID, owner, evidence)
+					costtags.Add(resource.Type, resource.Name, resource.ID, tags)
+					count++
+					events.Discovered(resourceGroup, resource.Type, resource.Name, resource.ID)
 					importChan <- resource
+
+					// some child types are missing from resource group listings, they're listed
+					// for their parent instead
+					for _, listing := range children.listings(armType) {
+						listed, err := children.list(ctx, listing, id, s.prefix+name)
+						if err != nil {
+							redact.Println("Failed to list", listing.path, "of", id, err)
+							events.Error(resourceGroup, err)
+							continue
+						}
+						for _, child := range listed {
+							if _, ok := pkgSpec.Resources[child.Type]; !ok {
+								continue
+							}
+							if _, ok := resourcesToSkip[child.Type]; ok || seen[strings.ToLower(child.ID)] {
+								continue
+							}
+							if ignore.Skip(child.Type, child.ID) {
+								continue
+							}
+							seen[strings.ToLower(child.ID)] = true
+							child.Parent = resourceGroup
+							// children carry no tags, they belong to whatever manages their parent
+							// and are paid for by whoever pays for it
+							ownership.Add(child.Type, child.Name, child.ID, owner, evidence)
+							costtags.Add(child.Type, child.Name, child.ID, tags)
+							count++
+							events.Discovered(resourceGroup, child.Type, child.Name, child.ID)
+							importChan <- child
+						}
+					}
 				}
 			}
+			events.Finished(resourceGroup, count)
 
-		}(resourceGroups[i].ID)
+		}(resourceGroups[i].ID, bySubscription[subscriptionOf(resourceGroups[i].ID)])
 	}
 
 	go func() {
@@ -248,47 +531,152 @@ func buildImportSpec(ctx *pulumi.Context, mode Mode) (importFile, error) {
 		close(importChan)
 	}()
 
-	rgs := map[string]pulumi.Resource{}
-
+	discovered := []importSpec{}
 	for resource := range importChan {
-		// create a new import spec as the parent needs to be a URN, so just strip it our for now
-		imports.Resources = append(imports.Resources, importSpec{
-			ID:   resource.ID,
-			Type: resource.Type,
-			Name: resource.Name,
-		})
+		discovered = append(discovered, resource)
+	}
+	if listErr != nil {
+		return imports, listErr
+	}
+	// resource group listings return resources of every type, so every type that isn't skipped is
+	// scanned. Previous tokens of renamed types and explicit API versions are the same resources.
+	for tok := range pkgSpec.Resources {
+		if _, ok := typeAliases[tok]; ok || isVersionedToken(tok) {
+			continue
+		}
+		coverage.Known(tok)
+		if _, ok := resourcesToSkip[tok]; !ok {
+			coverage.Scanned(tok)
+		}
+	}
+
+	// references between resources are only available in their properties, which the resources API
+	// doesn't return
+	properties, err := queryResourceProperties(context.Background(), cred, clientOptions, subscriptionIDs, location)
+	if err != nil {
+		redact.Println("Failed to query resource properties, dependencies between resources will not be recorded:", err)
+	}
+	unsupported.checkUnmanagedDisks(discovered, properties)
+	window.Report()
+	terminal.Report()
+	ignore.Report()
+	defer unsupported.report()
+
+	names := map[string]string{}
+	parents := map[string]string{}
+	read := map[string]pulumi.Resource{}
+
+	// resources of several subscriptions are read with the provider of their subscription
+	providers := map[string]pulumi.ProviderResource{}
+	if mode == ReadMode {
+		for _, s := range subscriptions {
+			if s.provider == "" {
+				continue
+			}
+			var p pulumi.ProviderResourceState
+			if err := ctx.RegisterResource(providerType, s.provider, s.providerInputs(location), &p); err != nil {
+				return imports, err
+			}
+			providers[s.id] = &p
+		}
+	}
+
+	for _, resource := range resolveDependencies(discovered, properties) {
+		names[resource.ID] = resource.Name
+		parents[resource.ID] = resource.ParentID
+		spec := importSpec{
+			ID:     resource.ID,
+			Type:   resource.Type,
+			Name:   resource.Name,
+			Parent: names[resource.ParentID],
+		}
+		s := bySubscription[subscriptionOf(resource.ID)]
+		if s != nil {
+			spec.Provider = s.provider
+		}
+		for _, dep := range resource.DependencyIDs {
+			spec.Dependencies = append(spec.Dependencies, names[dep])
+		}
+		imports.Resources = append(imports.Resources, spec)
+		previous := aliases.Record(spec.Type, spec.ID, spec.Name)
+
 		if mode == ReadMode {
 			var res pulumi.CustomResourceState
-			// currently ignore errors
-			if resource.Type == "azure-native:resources:ResourceGroup" {
-				rgs[resource.ID] = &res
-			}
 			opts := []pulumi.ResourceOption{}
-			if p, ok := rgs[resource.Parent]; ok {
-				opts = append(opts, pulumi.Parent(p))
+			if s != nil && providers[s.id] != nil {
+				opts = append(opts, pulumi.Provider(providers[s.id]))
+			}
+			// resources are read underneath their parent, whether a resource group or the resource
+			// they are nested in, or the closest ancestor read when their parent failed to read
+			for id := resource.ParentID; id != ""; id = parents[id] {
+				if p, ok := read[id]; ok {
+					opts = append(opts, pulumi.Parent(p))
+					break
+				}
+			}
+			dependsOn := []pulumi.Resource{}
+			for _, dep := range resource.DependencyIDs {
+				if d, ok := read[dep]; ok {
+					dependsOn = append(dependsOn, d)
+				}
+			}
+			if len(dependsOn) > 0 {
+				opts = append(opts, pulumi.DependsOn(dependsOn))
 			}
-			_ = ctx.ReadResource(resource.Type, resource.Name, pulumi.ID(resource.ID), nil, &res, opts...)
+			opts = append(opts, ignorechanges.Options(resource.Type)...)
+			opts = append(opts, aliases.Options(previous)...)
+			// resources failing to register or to be read are set aside in quarantine.json
+			if err := ctx.ReadResource(resource.Type, resource.Name, pulumi.ID(resource.ID), nil, &res, opts...); err != nil {
+				quarantine.Add(spec, quarantine.StageRead, err)
+				continue
+			}
+			quarantine.Watch(spec, &res, nil)
+			read[resource.ID] = &res
 		}
 	}
 
-	return imports, nil
+	tagged.report()
+	if err := summary.Report(); err != nil {
+		return imports, err
+	}
+	manifest.Identify("subscription", strings.Join(subscriptionIDs, ","))
+	manifest.Identify("location", location)
+	for typeToken := range resourcesToSkip {
+		coverage.Skip(typeToken, "fails to read or import")
+	}
+	if err := manifest.Write(); err != nil {
+		return imports, err
+	}
+	if err := coverage.Report(); err != nil {
+		return imports, err
+	}
+	if err := aliases.Write(); err != nil {
+		return imports, err
+	}
+	if err := costtags.Report(); err != nil {
+		return imports, err
+	}
+	if err := denied.Report(); err != nil {
+		return imports, err
+	}
+	return imports, ownership.Report()
 }
 
+// azureNativeVersion is the pulumi-azure-native branch or tag the schema is downloaded from. Pin it at
+// build time with -ldflags "-X main.azureNativeVersion=v1.100.0".
+var azureNativeVersion = "master"
+
 // download hhttps://raw.githubusercontent.com/pulumi/pulumi-azure-native/master/provider/cmd/pulumi-resource-azure-native/schema.json
 // and parse it into a pschema.PackageSpec
 func getAzureNativeSchema() (*pschema.PackageSpec, error) {
-	schemaURL := "https://raw.githubusercontent.com/pulumi/pulumi-azure-native/master/provider/cmd/pulumi-resource-azure-native/schema.json"
+	schemaURL := "https://raw.githubusercontent.com/pulumi/pulumi-azure-native/" + azureNativeVersion + "/provider/cmd/pulumi-resource-azure-native/schema.json"
 
-	resp, err := http.Get(schemaURL)
+	respByte, err := fetch.Get(context.Background(), schemaURL)
 	if err != nil {
 		return nil, err
 	}
 
-	defer resp.Body.Close()
 	var schema pschema.PackageSpec
-	buf := new(bytes.Buffer)
-	buf.ReadFrom(resp.Body)
-	respByte := buf.Bytes()
 	if err := json.Unmarshal(respByte, &schema); err != nil {
 		return nil, err
 	}
@@ -296,22 +684,94 @@ func getAzureNativeSchema() (*pschema.PackageSpec, error) {
 	return &schema, nil
 }
 
-// write import file to disk
-func writeImportFile(imports importFile) error {
-	// write the import file to disk
-	importFile, err := json.MarshalIndent(imports, "", "    ")
-	if err != nil {
-		return err
+// includeResourceGroup reports whether the resource group name passes the --resource-groups and
+// --exclude-resource-groups filters. Resource group names are case insensitive.
+func includeResourceGroup(name string, include []string, exclude []string) bool {
+	for _, g := range exclude {
+		if strings.EqualFold(g, name) {
+			return false
+		}
 	}
+	if len(include) == 0 {
+		return true
+	}
+	for _, g := range include {
+		if strings.EqualFold(g, name) {
+			return true
+		}
+	}
+	return false
+}
 
-	err = ioutil.WriteFile("import.json", importFile, 0644)
-	if err != nil {
-		return err
+// classifyOwnership classifies a resource from its tags. Resources with managedBy set are managed
+// by another resource, like the node resource group of an AKS cluster.
+func classifyOwnership(tags map[string]*string, managedBy *string) (string, string) {
+	if managedBy != nil && *managedBy != "" {
+		return "azure", "managedBy"
+	}
+	return ownership.Classify(tagValues(tags))
+}
+
+// tagValues returns the tags of a resource as the SDK returns them without the missing values.
+func tagValues(tags map[string]*string) map[string]string {
+	values := map[string]string{}
+	for k, v := range tags {
+		if v != nil {
+			values[k] = *v
+		}
+	}
+	return values
+}
+
+// schemaAliases maps the tokens resources are aliased from, like the tokens of renamed resources,
+// to the token of the resource. Aliases of default versions are preferred over the ones of explicit
+// API versions, like azure-native:network/v20220701:VirtualNetwork.
+func schemaAliases(pkgSpec *pschema.PackageSpec) map[string]string {
+	aliases := map[string]string{}
+	for tok, res := range pkgSpec.Resources {
+		for _, alias := range res.Aliases {
+			if alias.Type == nil || *alias.Type == tok {
+				continue
+			}
+			if current, ok := aliases[*alias.Type]; ok && (isVersionedToken(tok) || (!isVersionedToken(current) && current < tok)) {
+				continue
+			}
+			aliases[*alias.Type] = tok
+		}
+	}
+	return aliases
+}
+
+// isVersionedToken reports whether tok belongs to an explicit API version of a module.
+func isVersionedToken(tok string) bool {
+	parts := strings.Split(tok, ":")
+	return len(parts) == 3 && strings.Contains(parts[1], "/")
+}
+
+// write import file to disk, split into shards when --shards is passed
+func writeImportFile(imports importFile) error {
+	shards := shard.Split(imports.Resources, specRef)
+	for i, resources := range shards {
+		f := importFile{Metadata: imports.Metadata, NameTable: imports.NameTable, Resources: resources}
+		// write the import file to disk
+		importFile, err := json.MarshalIndent(f, "", "    ")
+		if err != nil {
+			return err
+		}
+
+		err = compress.WriteFile(shard.Path(config.Output.Value(), i, len(shards)), importFile)
+		if err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
 
+func specRef(r importSpec) stackstate.Ref {
+	return stackstate.Ref{Type: r.Type, ID: r.ID, Name: r.Name, Parent: r.Parent, Dependencies: r.Dependencies}
+}
+
 // check for presence of --import flag
 func isImportMode() bool {
 	for _, arg := range os.Args {
@@ -322,31 +782,19 @@ func isImportMode() bool {
 	return false
 }
 
-var nonAlphanumericRegex = regexp.MustCompile(`[^a-zA-Z0-9 ]+`)
-
-func clearString(str string) string {
-	return nonAlphanumericRegex.ReplaceAllString(str, "")
-}
-
-// reads ARM_LOCATION env var or returns default of uswest2
+// reads --location or ARM_LOCATION or returns default of westus2
 func getLocation() string {
-	location := os.Getenv("ARM_LOCATION")
-	if location == "" {
-		location = "westus2"
-	}
-	return location
+	return config.AzureLocation.Value()
 }
 
-// reads ARM_SUBSCRIPTION_ID env var or ARM_SUBSCRIPTION_ID env var or panics if none is set
-func getSubscriptionID() string {
-	subscriptionID := os.Getenv("ARM_SUBSCRIPTION_ID")
-	if subscriptionID == "" {
-		subscriptionID = os.Getenv("AZURE_SUBSCRIPTION_ID")
-	}
-	if subscriptionID == "" {
-		panic("ARM_SUBSCRIPTION_ID env var must be set")
+// reads the comma separated subscriptions of --subscription, ARM_SUBSCRIPTION_ID or
+// AZURE_SUBSCRIPTION_ID or fails if none is set
+func getSubscriptionIDs() ([]string, error) {
+	subscriptionIDs := config.AzureSubscription.List()
+	if len(subscriptionIDs) == 0 {
+		return nil, &discoveryError{kind: errConfiguration, err: errors.New("no subscription to scan"), hint: configurationHint}
 	}
-	return subscriptionID
+	return subscriptionIDs, nil
 }
 
 // reads ARM_OIDC_TOKEN env var or AZURE_OIDC_TOKEN env var returns "" if none is set
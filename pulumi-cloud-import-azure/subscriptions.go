@@ -0,0 +1,82 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/resources/armresources"
+	"github.com/pulumi/pulumi-cloud-import/pkg/naming"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+const providerType = "pulumi:providers:azure-native"
+
+// subscriptionPrefixLength is the number of characters of the subscription ID names are prefixed
+// with when several subscriptions are scanned.
+const subscriptionPrefixLength = 8
+
+// subscription is a subscription to scan along with the clients listing its resources. When several
+// subscriptions are scanned, resource names are prefixed with the start of the subscription ID so
+// that resources of the same name in different subscriptions don't collide, and resources are read
+// and imported with a provider of their own subscription rather than the default provider.
+type subscription struct {
+	id string
+	// prefix is prepended to resource names, empty when a single subscription is scanned
+	prefix string
+	// provider is the name of the provider of the subscription, empty when a single subscription
+	// is scanned
+	provider  string
+	resources *armresources.Client
+	groups    *armresources.ResourceGroupsClient
+}
+
+func newSubscription(id string, cred azcore.TokenCredential, options *arm.ClientOptions, multiple bool) (*subscription, error) {
+	s := namedSubscription(id, multiple)
+	var err error
+	// Azure SDK Azure Resource Management clients accept the credential as a parameter
+	if s.resources, err = armresources.NewClient(id, cred, options); err != nil {
+		return nil, err
+	}
+	if s.groups, err = armresources.NewResourceGroupsClient(id, cred, options); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// namedSubscription returns the subscription id without clients, its resources named and imported
+// with a provider of their own when multiple subscriptions are scanned.
+func namedSubscription(id string, multiple bool) *subscription {
+	s := &subscription{id: id}
+	if multiple {
+		s.prefix = id
+		if len(s.prefix) > subscriptionPrefixLength {
+			s.prefix = s.prefix[:subscriptionPrefixLength]
+		}
+		s.provider = "subscription-" + strings.ToLower(id)
+	}
+	return s
+}
+
+// name returns the resource name of a resource of the subscription named name in Azure.
+func (s *subscription) name(name string) string {
+	return naming.Azure.Name(s.prefix, name)
+}
+
+// providerInputs configures the provider of the subscription to scan location.
+func (s *subscription) providerInputs(location string) pulumi.Map {
+	return pulumi.Map{
+		"subscriptionId": pulumi.String(s.id),
+		"location":       pulumi.String(location),
+	}
+}
+
+// subscriptionOf returns the subscription ID of the resource with the given ID in lower case, or ""
+// for IDs that aren't scoped to a subscription.
+func subscriptionOf(id string) string {
+	parts := strings.Split(id, "/")
+	if len(parts) < 3 || !strings.EqualFold(parts[1], "subscriptions") {
+		return ""
+	}
+	return strings.ToLower(parts[2])
+}
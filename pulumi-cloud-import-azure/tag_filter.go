@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/pulumi/pulumi-cloud-import/pkg/tagfilter"
+)
+
+// tagFilter keeps the resources matching --include-tag and --exclude-tag, see pkg/tagfilter.
+//
+// Azure Resource Manager filters resource group listings by a single tag, optionally with a value,
+// so that a filter on one tag is pushed down into the $filter of the listings rather than every
+// resource being listed and filtered here. Resource Manager can't combine a tag with other
+// conditions, so the location is checked here instead, and doesn't return the tags of the resources
+// it filtered by tag, so the ownership and cost allocation reports miss the other tags.
+// Other filters, with several tags, several values or --exclude-tag, are applied to the tags of the
+// resources listed.
+type tagFilter struct {
+	filter *tagfilter.Filter
+	// pushed is the tag pushed down into the listings, with its value if given
+	pushed        bool
+	key, value    string
+	mu            sync.Mutex
+	kept, leftOut int
+}
+
+// newTagFilter returns the filter of --include-tag and --exclude-tag, nil when neither is passed.
+func newTagFilter() *tagFilter {
+	filter := tagfilter.FromFlags()
+	if filter == nil {
+		return nil
+	}
+	f := &tagFilter{filter: filter}
+	if len(filter.Include) == 1 && len(filter.Exclude) == 0 {
+		for key, values := range filter.Include {
+			if len(values) <= 1 {
+				f.pushed, f.key = true, key
+				if len(values) == 1 {
+					f.value = values[0]
+				}
+			}
+		}
+	}
+	return f
+}
+
+// listFilter returns the $filter of the listing of the resources of a resource group in location.
+func (f *tagFilter) listFilter(location string) string {
+	if f == nil || !f.pushed {
+		return fmt.Sprintf("location eq '%s'", location)
+	}
+	filter := fmt.Sprintf("tagName eq '%s'", quote(f.key))
+	if f.value != "" {
+		filter += fmt.Sprintf(" and tagValue eq '%s'", quote(f.value))
+	}
+	return filter
+}
+
+// inLocation reports whether a resource listed in location is in it, which the listing only checked
+// when no tag was pushed down.
+func (f *tagFilter) inLocation(resourceLocation *string, location string) bool {
+	if f == nil || !f.pushed || resourceLocation == nil {
+		return true
+	}
+	return strings.EqualFold(*resourceLocation, location)
+}
+
+// tags returns the tags of a resource listed with tags, which are missing when a tag was pushed down.
+func (f *tagFilter) tags(tags map[string]string) map[string]string {
+	if f == nil || !f.pushed || len(tags) > 0 {
+		return tags
+	}
+	if f.value == "" {
+		return tags
+	}
+	return map[string]string{f.key: f.value}
+}
+
+// keep reports whether a resource tagged with tags is kept.
+func (f *tagFilter) keep(tags map[string]string) bool {
+	if f == nil {
+		return true
+	}
+	kept := f.pushed || f.filter.Matches(tags, false)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if kept {
+		f.kept++
+	} else {
+		f.leftOut++
+	}
+	return kept
+}
+
+// report prints how many resources the filter kept and left out.
+func (f *tagFilter) report() {
+	if f == nil {
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.pushed {
+		fmt.Printf("%d resources matched --include-tag, filtered by Azure Resource Manager\n", f.kept)
+		return
+	}
+	fmt.Printf("%d resources matched --include-tag and --exclude-tag, %d were left out\n", f.kept, f.leftOut)
+}
+
+// quote escapes the single quotes of a value of a $filter.
+func quote(value string) string {
+	return strings.ReplaceAll(value, "'", "''")
+}
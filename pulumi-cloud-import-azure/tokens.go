@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gertd/go-pluralize"
+)
+
+var plural = pluralize.NewClient()
+
+// armTypeOverrides are the tokens of the ARM types whose azure-native token isn't derived from their
+// last segment, by lower-cased ARM type.
+var armTypeOverrides = map[string]string{
+	"microsoft.apimanagement/service":                                    "azure-native:apimanagement:ApiManagementService",
+	"microsoft.cache/redis":                                              "azure-native:cache:Redis",
+	"microsoft.compute/virtualmachines/extensions":                       "azure-native:compute:VirtualMachineExtension",
+	"microsoft.compute/virtualmachinescalesets/extensions":               "azure-native:compute:VirtualMachineScaleSetExtension",
+	"microsoft.documentdb/databaseaccounts/sqldatabases":                 "azure-native:documentdb:SqlResourceSqlDatabase",
+	"microsoft.documentdb/databaseaccounts/sqldatabases/containers":      "azure-native:documentdb:SqlResourceSqlContainer",
+	"microsoft.eventhub/namespaces/eventhubs":                            "azure-native:eventhub:EventHub",
+	"microsoft.insights/autoscalesettings":                               "azure-native:insights:AutoscaleSetting",
+	"microsoft.insights/webtests":                                        "azure-native:insights:WebTest",
+	"microsoft.network/applicationgatewaywebapplicationfirewallpolicies": "azure-native:network:WebApplicationFirewallPolicy",
+	"microsoft.network/dnszones":                                         "azure-native:network:Zone",
+	"microsoft.network/frontdoorwebapplicationfirewallpolicies":          "azure-native:network:Policy",
+	"microsoft.network/privatednszones":                                  "azure-native:network:PrivateZone",
+	"microsoft.network/trafficmanagerprofiles":                           "azure-native:network:Profile",
+	"microsoft.storage/storageaccounts/blobservices":                     "azure-native:storage:BlobServiceProperties",
+	"microsoft.storage/storageaccounts/blobservices/containers":          "azure-native:storage:BlobContainer",
+	"microsoft.storage/storageaccounts/fileservices":                     "azure-native:storage:FileServiceProperties",
+	"microsoft.storage/storageaccounts/fileservices/shares":              "azure-native:storage:FileShare",
+	"microsoft.web/serverfarms":                                          "azure-native:web:AppServicePlan",
+	"microsoft.web/sites":                                                "azure-native:web:WebApp",
+	"microsoft.web/sites/functions":                                      "azure-native:web:WebAppFunction",
+	"microsoft.web/sites/slots":                                          "azure-native:web:WebAppSlot",
+}
+
+func init() {
+	// the record sets of DNS zones are typed after their record type
+	for _, record := range []string{"a", "aaaa", "caa", "cname", "mx", "ns", "ptr", "soa", "srv", "txt"} {
+		armTypeOverrides["microsoft.network/dnszones/"+record] = "azure-native:network:RecordSet"
+		if record != "caa" && record != "ns" {
+			armTypeOverrides["microsoft.network/privatednszones/"+record] = "azure-native:network:PrivateRecordSet"
+		}
+	}
+}
+
+// armTypeToken derives the azure-native type token of an ARM resource type from its namespace and
+// the singular of its last segment, e.g. Microsoft.Network/virtualNetworks/subnets is
+// azure-native:network:Subnet, unless armTypeOverrides has it. Tokens that were renamed in the schema
+// are resolved by the caller through the schema's aliases. Changes to the mapping are checked against
+// token_corpus.json by go test, as a mapping regression silently skips every resource of the affected
+// types.
+func armTypeToken(armType string) string {
+	if token, ok := armTypeOverrides[strings.ToLower(armType)]; ok {
+		return token
+	}
+	parts := strings.Split(armType, ".")
+	parts = strings.Split(parts[1], "/")
+	namespace := parts[0]
+	resourceType := plural.Singular(strings.Title(parts[len(parts)-1]))
+	return fmt.Sprintf("azure-native:%s:%s", strings.ToLower(namespace), resourceType)
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"sort"
+	"testing"
+)
+
+// TestArmTypeToken maps every ARM type of token_corpus.json, which lists real ARM types along with
+// the token they have to be mapped to, irregular ones included.
+func TestArmTypeToken(t *testing.T) {
+	b, err := os.ReadFile("token_corpus.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var corpus map[string]string
+	if err := json.Unmarshal(b, &corpus); err != nil {
+		t.Fatal(err)
+	}
+	armTypes := make([]string, 0, len(corpus))
+	for armType := range corpus {
+		armTypes = append(armTypes, armType)
+	}
+	sort.Strings(armTypes)
+
+	for _, armType := range armTypes {
+		if got := armTypeToken(armType); got != corpus[armType] {
+			t.Errorf("%s: expected %s, got %s", armType, corpus[armType], got)
+		}
+	}
+}
+
+// TestArmTypeTokenCase maps the lower-cased ARM types Resource Graph returns like the ARM types of
+// the overrides.
+func TestArmTypeTokenCase(t *testing.T) {
+	for armType, expected := range map[string]string{
+		"microsoft.web/sites":            "azure-native:web:WebApp",
+		"MICROSOFT.CACHE/REDIS":          "azure-native:cache:Redis",
+		"microsoft.network/dnszones/txt": "azure-native:network:RecordSet",
+	} {
+		if got := armTypeToken(armType); got != expected {
+			t.Errorf("%s: expected %s, got %s", armType, expected, got)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pulumi/pulumi-cloud-import/pkg/redact"
+)
+
+// unsupportedResource is a discovered resource that can't be imported as is, along with what
+// prevents it.
+type unsupportedResource struct {
+	ID     string
+	Type   string
+	Detail string
+}
+
+// unsupportedResources collects the classic resources and unmanaged artifacts found by a scan, so
+// they can be reported together at the end rather than lost among the skipped resources.
+type unsupportedResources struct {
+	mu        sync.Mutex
+	classic   []unsupportedResource
+	unmanaged []unsupportedResource
+}
+
+// isClassicType reports whether an ARM resource type belongs to the classic deployment model
+// (Azure Service Manager), e.g. Microsoft.ClassicCompute/virtualMachines. azure-native only
+// manages Azure Resource Manager resources.
+func isClassicType(armType string) bool {
+	return strings.HasPrefix(strings.ToLower(armType), "microsoft.classic")
+}
+
+func (u *unsupportedResources) addClassic(id, armType string) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.classic = append(u.classic, unsupportedResource{ID: id, Type: armType})
+}
+
+// checkUnmanagedDisks records the virtual machines among resources whose disks are VHDs stored in
+// a storage account rather than managed disks. The virtual machines are imported, but their
+// disks are page blobs that azure-native has no resource for.
+func (u *unsupportedResources) checkUnmanagedDisks(resources []importSpec, properties map[string]interface{}) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	for _, r := range resources {
+		if r.Type != "azure-native:compute:VirtualMachine" {
+			continue
+		}
+		if vhds := unmanagedDisks(properties[strings.ToLower(r.ID)]); len(vhds) > 0 {
+			u.unmanaged = append(u.unmanaged, unsupportedResource{ID: r.ID, Type: r.Type, Detail: strings.Join(vhds, ", ")})
+		}
+	}
+}
+
+// unmanagedDisks returns the VHD URIs of the OS and data disks of a virtual machine's properties.
+func unmanagedDisks(properties interface{}) []string {
+	props, _ := properties.(map[string]interface{})
+	storage, _ := props["storageProfile"].(map[string]interface{})
+	disks := []interface{}{storage["osDisk"]}
+	if data, ok := storage["dataDisks"].([]interface{}); ok {
+		disks = append(disks, data...)
+	}
+
+	vhds := []string{}
+	for _, d := range disks {
+		disk, _ := d.(map[string]interface{})
+		vhd, _ := disk["vhd"].(map[string]interface{})
+		if uri, ok := vhd["uri"].(string); ok && uri != "" {
+			vhds = append(vhds, uri)
+		}
+	}
+	return vhds
+}
+
+// report prints the classic resources and the virtual machines with unmanaged disks found.
+func (u *unsupportedResources) report() {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if len(u.classic) > 0 {
+		sort.Slice(u.classic, func(i, j int) bool { return u.classic[i].ID < u.classic[j].ID })
+		redact.Printf("%d classic resources were not imported, azure-native only supports Azure Resource Manager resources. Migrate them to Azure Resource Manager first:\n", len(u.classic))
+		for _, r := range u.classic {
+			redact.Printf("  %s (%s)\n", r.ID, r.Type)
+		}
+	}
+	if len(u.unmanaged) > 0 {
+		sort.Slice(u.unmanaged, func(i, j int) bool { return u.unmanaged[i].ID < u.unmanaged[j].ID })
+		redact.Printf("%d virtual machines use unmanaged disks. The virtual machines are imported, but their VHDs are page blobs that can't be imported, convert them to managed disks to manage them with Pulumi:\n", len(u.unmanaged))
+		for _, r := range u.unmanaged {
+			redact.Printf("  %s: %s\n", r.ID, r.Detail)
+		}
+	}
+}
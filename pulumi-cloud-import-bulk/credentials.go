@@ -0,0 +1,113 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// credentialsRefresh is how long before they expire the credentials of the assumed role are renewed,
+// so that a batch doesn't start with credentials expiring halfway through.
+const credentialsRefresh = 15 * time.Minute
+
+// awsKeys are the environment variables of AWS access keys, which take precedence over AWS_PROFILE.
+var awsKeys = []string{"AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY", "AWS_SESSION_TOKEN"}
+
+// importCredentials are the AWS credentials `pulumi import` and `pulumi refresh` run with, when they
+// aren't the ones the cloud was scanned with: the state is often written with a more privileged or
+// differently scoped role than the read-only one discovering resources.
+type importCredentials struct {
+	profile string
+	roleARN string
+
+	keys       map[string]string
+	expiration time.Time
+}
+
+// credentialFlags registers the flags selecting the AWS credentials of pulumi on flags.
+func credentialFlags(flags *flag.FlagSet) *importCredentials {
+	c := &importCredentials{}
+	flags.StringVar(&c.profile, "aws-profile", "", "AWS profile pulumi imports with, defaults to the credentials of the environment")
+	flags.StringVar(&c.roleARN, "aws-role-arn", "", "ARN of an AWS role assumed to import, with the credentials of --aws-profile if any")
+	return c
+}
+
+// environ returns the environment of pulumi, with the credentials of the profile or the role in
+// place of the ones of the environment.
+func (c *importCredentials) environ() ([]string, error) {
+	env := os.Environ()
+	if c.profile == "" && c.roleARN == "" {
+		return env, nil
+	}
+	if c.roleARN != "" && time.Until(c.expiration) < credentialsRefresh {
+		if err := c.assumeRole(); err != nil {
+			return nil, err
+		}
+	}
+
+	vars := []string{}
+	for _, v := range env {
+		name := strings.SplitN(v, "=", 2)[0]
+		if contains(awsKeys, name) || (c.profile != "" && name == "AWS_PROFILE") {
+			continue
+		}
+		vars = append(vars, v)
+	}
+	if c.profile != "" {
+		vars = append(vars, "AWS_PROFILE="+c.profile)
+	}
+	for _, name := range awsKeys {
+		if v, ok := c.keys[name]; ok {
+			vars = append(vars, name+"="+v)
+		}
+	}
+	return vars, nil
+}
+
+// assumeRole assumes the role with the AWS CLI, which chains from the profile or the environment
+// like the providers do.
+func (c *importCredentials) assumeRole() error {
+	cmd := exec.Command("aws", "sts", "assume-role", "--role-arn", c.roleARN,
+		"--role-session-name", "pulumi-cloud-import-bulk", "--output", "json")
+	cmd.Env = os.Environ()
+	if c.profile != "" {
+		cmd.Env = append(cmd.Env, "AWS_PROFILE="+c.profile)
+	}
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return fmt.Errorf("failed to assume %s: %w", c.roleARN, err)
+	}
+	var resp struct {
+		Credentials struct {
+			AccessKeyID     string    `json:"AccessKeyId"`
+			SecretAccessKey string    `json:"SecretAccessKey"`
+			SessionToken    string    `json:"SessionToken"`
+			Expiration      time.Time `json:"Expiration"`
+		} `json:"Credentials"`
+	}
+	if err := json.Unmarshal(out, &resp); err != nil {
+		return fmt.Errorf("failed to parse the credentials of %s: %w", c.roleARN, err)
+	}
+	c.keys = map[string]string{
+		"AWS_ACCESS_KEY_ID":     resp.Credentials.AccessKeyID,
+		"AWS_SECRET_ACCESS_KEY": resp.Credentials.SecretAccessKey,
+		"AWS_SESSION_TOKEN":     resp.Credentials.SessionToken,
+	}
+	c.expiration = resp.Credentials.Expiration
+	fmt.Printf("importing as %s until %s\n", c.roleARN, c.expiration.Format(time.RFC3339))
+	return nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/pulumi/pulumi-cloud-import/pkg/compress"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+)
+
+// lintRule is a known way for the spec of a resource to fail or misbehave at import time. Rules
+// that can fix the spec rewrite it, the others only flag it.
+type lintRule struct {
+	name string
+	// check returns what's wrong with r, "" if nothing is, rewriting r when fix is true.
+	check func(l *linter, r map[string]interface{}, fix bool) string
+	// fixes reports whether check rewrites the specs it flags.
+	fixes bool
+}
+
+// lintRules are the known provider quirks, in the order they are checked. Add new quirks here
+// rather than working around them in the programs, so that import files written by older
+// programs get fixed too.
+var lintRules = []lintRule{
+	{name: "azure-id-case", check: azureIDCase, fixes: true},
+	{name: "urn-separator", check: urnSeparator, fixes: true},
+	{name: "missing-parent", check: missingParent, fixes: true},
+	{name: "missing-id", check: missingID},
+	{name: "duplicate-name", check: duplicateName},
+	{name: "short-lived", check: shortLived},
+}
+
+// azureIDPrefix matches the subscription and resource group of an Azure resource ID, whose
+// segments azure-native only reads back in their canonical case. Some resource providers return
+// them lower-cased, e.g. /resourcegroups/, and the resources then import with a diff on their ID.
+var azureIDPrefix = regexp.MustCompile(`(?i)^/subscriptions/([^/]+)/resourcegroups/([^/]+)`)
+
+func azureIDCase(l *linter, r map[string]interface{}, fix bool) string {
+	id := str(r, "id")
+	loc := azureIDPrefix.FindStringIndex(id)
+	if !strings.HasPrefix(str(r, "type"), "azure-native:") || loc == nil {
+		return ""
+	}
+	canonical := azureIDPrefix.ReplaceAllString(id, "/subscriptions/$1/resourceGroups/$2")
+	if rest := canonical[loc[1]:]; strings.HasPrefix(strings.ToLower(rest), "/providers/") {
+		canonical = canonical[:loc[1]] + "/providers/" + rest[len("/providers/"):]
+	}
+	if canonical == id {
+		return ""
+	}
+	if fix {
+		r["id"] = canonical
+	}
+	return fmt.Sprintf("ID %s isn't in canonical case, %s", id, canonical)
+}
+
+// urnSeparator renames resources whose name contains ::, which separates the parts of URNs.
+func urnSeparator(l *linter, r map[string]interface{}, fix bool) string {
+	name := str(r, "name")
+	if !strings.Contains(name, "::") {
+		return ""
+	}
+	renamed := strings.ReplaceAll(name, "::", "-")
+	if fix {
+		l.rename(name, renamed)
+	}
+	return fmt.Sprintf("name contains ::, which URNs are separated with, renamed %s", renamed)
+}
+
+// missingParent imports resources whose parent is neither in the file nor in its name table at the
+// top level, as `pulumi import` fails on the whole file otherwise.
+func missingParent(l *linter, r map[string]interface{}, fix bool) string {
+	parent := str(r, "parent")
+	if parent == "" || l.names[parent] || l.nameTable[parent] != "" {
+		return ""
+	}
+	if fix {
+		delete(r, "parent")
+	}
+	return fmt.Sprintf("parent %s is neither in the file nor in its name table, imported without it", parent)
+}
+
+func missingID(l *linter, r map[string]interface{}, fix bool) string {
+	if str(r, "id") != "" {
+		return ""
+	}
+	return "has no ID to import"
+}
+
+// duplicateName flags resources of the same type and name, which `pulumi import` can't tell apart.
+func duplicateName(l *linter, r map[string]interface{}, fix bool) string {
+	key := str(r, "type") + "|" + str(r, "name")
+	if l.seen[key] {
+		return fmt.Sprintf("another %s is named %s", str(r, "type"), str(r, "name"))
+	}
+	l.seen[key] = true
+	return ""
+}
+
+// shortLivedTypes are the types whose objects are replaced or renewed all the time: they are gone
+// or changed by the time they are imported, and import with a diff on every refresh.
+var shortLivedTypes = map[string]bool{
+	"kubernetes:core/v1:Event":                true,
+	"kubernetes:events.k8s.io/v1:Event":       true,
+	"kubernetes:coordination.k8s.io/v1:Lease": true,
+}
+
+func shortLived(l *linter, r map[string]interface{}, fix bool) string {
+	if !shortLivedTypes[str(r, "type")] {
+		return ""
+	}
+	return "objects of this type are short-lived and rarely import cleanly, leave them out with --exclude-kinds"
+}
+
+// linter checks the resources of an import file against lintRules.
+type linter struct {
+	resources []map[string]interface{}
+	nameTable map[string]resource.URN
+	// names are the names of the resources of the file
+	names map[string]bool
+	seen  map[string]bool
+}
+
+// lintFinding is a resource a rule flagged.
+type lintFinding struct {
+	rule    string
+	name    string
+	message string
+	fixed   bool
+}
+
+func (f lintFinding) String() string {
+	state := "flagged"
+	if f.fixed {
+		state = "fixed"
+	}
+	return fmt.Sprintf("%s: %s %s: %s", f.rule, state, f.name, f.message)
+}
+
+// lint checks the resources of f, fixing the ones rules know how to fix when fix is true.
+func lint(f *importFile, fix bool) []lintFinding {
+	l := &linter{resources: f.Resources, nameTable: f.NameTable, names: map[string]bool{}, seen: map[string]bool{}}
+	for _, r := range f.Resources {
+		l.names[str(r, "name")] = true
+	}
+	findings := []lintFinding{}
+	for _, rule := range lintRules {
+		for _, r := range f.Resources {
+			name := str(r, "name")
+			if message := rule.check(l, r, fix && rule.fixes); message != "" {
+				findings = append(findings, lintFinding{rule: rule.name, name: name, message: message, fixed: fix && rule.fixes})
+			}
+		}
+	}
+	return findings
+}
+
+// rename renames the resource named from to to, along with the references to it.
+func (l *linter) rename(from, to string) {
+	for _, r := range l.resources {
+		if str(r, "name") == from {
+			r["name"] = to
+		}
+		if str(r, "parent") == from {
+			r["parent"] = to
+		}
+	}
+	delete(l.names, from)
+	l.names[to] = true
+}
+
+// lintFile reports the resources of an import file that are known to fail or misbehave at import
+// time, and rewrites the file with the fixes with --fix. It exits with an error when some findings
+// are left unfixed.
+func lintFile(args []string) {
+	flags := flag.NewFlagSet("lint", flag.ExitOnError)
+	file := flags.String("file", "import.json", "import file to lint")
+	fix := flags.Bool("fix", false, "rewrite the import file with the fixes")
+	output := flags.String("output", "", "file the fixed import file is written to, defaults to --file")
+	flags.Parse(args)
+
+	// import files written with --compress are read as they are
+	b, err := compress.ReadFile(*file)
+	if err != nil {
+		panic(err)
+	}
+	var imports struct {
+		Metadata json.RawMessage `json:"metadata,omitempty"`
+		importFile
+	}
+	if err := json.Unmarshal(b, &imports); err != nil {
+		panic(err)
+	}
+
+	findings := lint(&imports.importFile, *fix)
+	unfixed := 0
+	for _, f := range findings {
+		fmt.Println(f)
+		if !f.fixed {
+			unfixed++
+		}
+	}
+	fmt.Printf("%d findings, %d fixed\n", len(findings), len(findings)-unfixed)
+
+	if *fix && len(findings) > unfixed {
+		path := *output
+		if path == "" {
+			path = *file
+			// like the programs reading import files, --file import.json reads import.json.gz
+			if _, err := os.Stat(path); os.IsNotExist(err) {
+				path += compress.Ext
+			}
+		}
+		if err := writeImportFile(path, imports); err != nil {
+			panic(err)
+		}
+		fmt.Printf("fixed import file written to %s\n", path)
+	}
+	if unfixed > 0 {
+		os.Exit(1)
+	}
+}
+
+// writeImportFile writes v to path, gzipped when path ends in .gz.
+func writeImportFile(path string, v interface{}) error {
+	b, err := json.MarshalIndent(v, "", "    ")
+	if err != nil {
+		return err
+	}
+	if strings.HasSuffix(path, compress.Ext) {
+		if b, err = compress.Compress(b); err != nil {
+			return err
+		}
+	}
+	return os.WriteFile(path, b, 0644)
+}
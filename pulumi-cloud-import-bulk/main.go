@@ -0,0 +1,303 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/pulumi/pulumi-cloud-import/pkg/compress"
+	"github.com/pulumi/pulumi-cloud-import/pkg/quarantine"
+	"github.com/pulumi/pulumi-cloud-import/pkg/stackstate"
+	"github.com/pulumi/pulumi-cloud-import/pkg/version"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+)
+
+// importFile mirrors the import file written by the provider programs. Resources are kept as raw
+// maps so that every field written by a program is passed on to `pulumi import` untouched.
+type importFile struct {
+	NameTable map[string]resource.URN  `json:"nameTable"`
+	Resources []map[string]interface{} `json:"resources"`
+}
+
+func main() {
+	version.HandleFlag()
+
+	if len(os.Args) > 1 && os.Args[1] == "retry-quarantine" {
+		retryQuarantine(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "verify" {
+		verify(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "lint" {
+		lintFile(os.Args[2:])
+		return
+	}
+
+	file := flag.String("file", "import.json", "import file written by a cloud import program")
+	stack := flag.String("stack", "", "stack to import into, defaults to the selected stack")
+	batchSize := flag.Int("batch-size", 500, "number of resources imported at once")
+	quarantinePath := flag.String("quarantine", quarantine.DefaultPath, "file the resources failing to import are written to")
+	fix := flag.Bool("lint", true, "fix the known provider quirks of the import file before importing it, see the lint command")
+	opts := importFlags(flag.CommandLine)
+	flag.Parse()
+
+	// import files written with --compress are read as they are
+	b, err := compress.ReadFile(*file)
+	if err != nil {
+		panic(err)
+	}
+	var imports importFile
+	if err := json.Unmarshal(b, &imports); err != nil {
+		panic(err)
+	}
+
+	if *fix {
+		for _, f := range lint(&imports, true) {
+			fmt.Println(f)
+		}
+	}
+
+	previous, err := quarantine.Read(*quarantinePath)
+	if os.IsNotExist(err) {
+		previous = &quarantine.File{}
+	} else if err != nil {
+		panic(err)
+	}
+	attempts := map[string]int{}
+	for _, e := range previous.Resources {
+		attempts[key(e.Resource)] = e.Attempts
+	}
+
+	entries := []quarantine.Entry{}
+	imported := map[string]bool{}
+	for _, r := range imports.Resources {
+		entries = append(entries, quarantine.Entry{Resource: r, Attempts: attempts[key(r)]})
+		imported[key(r)] = true
+	}
+	failed := importInBatches(*stack, opts, imports.NameTable, entries, *batchSize)
+
+	// resources quarantined by earlier runs are kept, unless this run imported or quarantined them again
+	kept := []quarantine.Entry{}
+	for _, e := range previous.Resources {
+		if !imported[key(e.Resource)] {
+			kept = append(kept, e)
+		}
+	}
+	if len(kept) == len(previous.Resources) && len(failed.Resources) == 0 {
+		return
+	}
+	for name, urn := range previous.NameTable {
+		failed.NameTable[name] = urn
+	}
+	failed.Resources = append(kept, failed.Resources...)
+	if err := writeQuarantine(*quarantinePath, failed); err != nil {
+		panic(err)
+	}
+}
+
+// retryQuarantine re-attempts the import of quarantined resources, e.g. after a provider upgrade.
+// Resources that fail again stay in the quarantine file with their latest error.
+func retryQuarantine(args []string) {
+	flags := flag.NewFlagSet("retry-quarantine", flag.ExitOnError)
+	stack := flags.String("stack", "", "stack to import into, defaults to the selected stack")
+	batchSize := flags.Int("batch-size", 500, "number of resources imported at once")
+	quarantinePath := flags.String("quarantine", quarantine.DefaultPath, "quarantine file to retry")
+	upgrade := flags.Bool("upgrade-providers", false, "install the latest version of the providers of quarantined resources first")
+	opts := importFlags(flags)
+	flags.Parse(args)
+
+	q, err := quarantine.Read(*quarantinePath)
+	if err != nil {
+		panic(err)
+	}
+	if *upgrade {
+		if err := upgradeProviders(q.Resources); err != nil {
+			panic(err)
+		}
+	}
+	failed := importInBatches(*stack, opts, q.NameTable, q.Resources, *batchSize)
+	if err := writeQuarantine(*quarantinePath, failed); err != nil {
+		panic(err)
+	}
+}
+
+// importInBatches imports the resources of entries and returns the ones failing to import on their own.
+func importInBatches(stack string, opts *importOptions, nameTable map[string]resource.URN, entries []quarantine.Entry, batchSize int) *quarantine.File {
+	if nameTable == nil {
+		nameTable = map[string]resource.URN{}
+	}
+
+	// resources imported by an earlier, interrupted run are skipped
+	state, err := stackstate.Load(stack)
+	if err != nil {
+		panic(err)
+	}
+	entries, existing := stackstate.Filter(state, entries, ref)
+	for name, urn := range existing {
+		nameTable[name] = urn
+	}
+
+	parents := map[string]bool{}
+	for _, e := range entries {
+		parents[str(e.Resource, "parent")] = true
+	}
+
+	batches := [][]quarantine.Entry{}
+	for i := 0; i < len(entries); i += batchSize {
+		end := i + batchSize
+		if end > len(entries) {
+			end = len(entries)
+		}
+		batches = append(batches, entries[i:end])
+	}
+
+	imported := 0
+	failed := &quarantine.File{NameTable: map[string]resource.URN{}, Resources: []quarantine.Entry{}}
+	for len(batches) > 0 {
+		batch := batches[0]
+		batches = batches[1:]
+
+		fmt.Printf("importing %d resources, %d imported and %d quarantined so far\n", len(batch), imported, len(failed.Resources))
+		f := importFile{NameTable: nameTable, Resources: []map[string]interface{}{}}
+		for _, e := range batch {
+			f.Resources = append(f.Resources, e.Resource)
+		}
+		output, err := runImport(stack, opts, f)
+		if err == nil {
+			imported += len(batch)
+			// later batches refer to parents imported by this one through the name table
+			if state, err = stackstate.Load(stack); err != nil {
+				panic(err)
+			}
+			for _, e := range batch {
+				if !parents[str(e.Resource, "name")] {
+					continue
+				}
+				if urn, ok := state.URN(str(e.Resource, "type"), str(e.Resource, "id")); ok {
+					nameTable[str(e.Resource, "name")] = urn
+				}
+			}
+			continue
+		}
+
+		// a failing resource fails the whole batch, so split it until the failing resources are on their own
+		if len(batch) == 1 {
+			e := quarantine.NewEntry(batch[0].Resource, quarantine.StageImport, errorLines(output, err))
+			e.Attempts = batch[0].Attempts + 1
+			failed.Resources = append(failed.Resources, e)
+			continue
+		}
+		half := len(batch) / 2
+		batches = append([][]quarantine.Entry{batch[:half], batch[half:]}, batches...)
+	}
+
+	// quarantined resources keep referring to their imported parents when retried
+	for _, e := range failed.Resources {
+		if urn, ok := nameTable[str(e.Resource, "parent")]; ok {
+			failed.NameTable[str(e.Resource, "parent")] = urn
+		}
+	}
+	fmt.Printf("imported %d resources, %d quarantined\n", imported, len(failed.Resources))
+	return failed
+}
+
+func writeQuarantine(path string, failed *quarantine.File) error {
+	if err := quarantine.Write(path, failed); err != nil {
+		return err
+	}
+	if len(failed.Resources) > 0 {
+		fmt.Printf("resources that failed to import written to %s\n", path)
+		os.Exit(1)
+	}
+	return nil
+}
+
+// runImport imports the resources of f into the stack, returning the output of `pulumi import`.
+func runImport(stack string, opts *importOptions, f importFile) (string, error) {
+	dir, err := os.MkdirTemp("", "pulumi-cloud-import-bulk")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(dir)
+	b, err := json.MarshalIndent(f, "", "    ")
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, "import.json")
+	if err := os.WriteFile(path, b, 0644); err != nil {
+		return "", err
+	}
+
+	args := append([]string{"import", "--file", path, "--yes", "--non-interactive"}, opts.args(f.Resources)...)
+	if stack != "" {
+		args = append(args, "--stack", stack)
+	}
+	env, err := opts.credentials.environ()
+	if err != nil {
+		return "", err
+	}
+	var output bytes.Buffer
+	cmd := exec.Command("pulumi", args...)
+	cmd.Env = env
+	cmd.Stdout = io.MultiWriter(os.Stdout, &output)
+	cmd.Stderr = io.MultiWriter(os.Stderr, &output)
+	err = cmd.Run()
+	return output.String(), err
+}
+
+// upgradeProviders installs the latest plugin of every provider a quarantined resource belongs to.
+func upgradeProviders(entries []quarantine.Entry) error {
+	installed := map[string]bool{}
+	for _, e := range entries {
+		pkg := strings.SplitN(str(e.Resource, "type"), ":", 2)[0]
+		if pkg == "" || installed[pkg] {
+			continue
+		}
+		installed[pkg] = true
+		fmt.Printf("installing the latest %s provider\n", pkg)
+		cmd := exec.Command("pulumi", "plugin", "install", "resource", pkg)
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("failed to install the %s provider: %w", pkg, err)
+		}
+	}
+	return nil
+}
+
+// errorLines picks the error diagnostics out of the output of `pulumi import`.
+func errorLines(output string, err error) string {
+	lines := []string{}
+	for _, l := range strings.Split(output, "\n") {
+		if strings.Contains(strings.ToLower(l), "error") {
+			lines = append(lines, strings.TrimSpace(l))
+		}
+	}
+	if len(lines) == 0 {
+		return err.Error()
+	}
+	return strings.Join(lines, "\n")
+}
+
+func ref(e quarantine.Entry) stackstate.Ref {
+	r := e.Resource
+	return stackstate.Ref{Type: str(r, "type"), ID: str(r, "id"), Name: str(r, "name"), Parent: str(r, "parent")}
+}
+
+// key identifies a resource across import files and quarantine files by its type and ID.
+func key(r map[string]interface{}) string {
+	return str(r, "type") + "|" + str(r, "id")
+}
+
+func str(r map[string]interface{}, key string) string {
+	s, _ := r[key].(string)
+	return s
+}
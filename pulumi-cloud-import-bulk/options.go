@@ -0,0 +1,70 @@
+package main
+
+import (
+	"flag"
+	"strconv"
+	"strings"
+)
+
+// defaultParallelism is the number of resources `pulumi import` reads at once by provider, low
+// enough to stay clear of the provider's API throttling.
+var defaultParallelism = map[string]int{
+	"aws-native":   4,
+	"aws":          8,
+	"azure-native": 10,
+	"kubernetes":   20,
+}
+
+// fallbackParallelism is the parallelism of providers missing from defaultParallelism.
+const fallbackParallelism = 10
+
+// importOptions are the `pulumi import` flags the resources are imported with.
+type importOptions struct {
+	parallel     int
+	skipPreview  bool
+	generateCode bool
+	extra        string
+	credentials  *importCredentials
+}
+
+// importFlags registers the flags tuning `pulumi import` on flags.
+func importFlags(flags *flag.FlagSet) *importOptions {
+	opts := &importOptions{credentials: credentialFlags(flags)}
+	flags.IntVar(&opts.parallel, "parallel", 0, "number of resources imported at once, defaults to a limit per provider")
+	flags.BoolVar(&opts.skipPreview, "skip-preview", true, "import without previewing the import first")
+	flags.BoolVar(&opts.generateCode, "generate-code", false, "print the code of the imported resources")
+	flags.StringVar(&opts.extra, "pulumi-import-args", "", "space separated arguments passed on to pulumi import, e.g. \"--protect=false\"")
+	return opts
+}
+
+// args returns the arguments of `pulumi import` for importing resources.
+func (o *importOptions) args(resources []map[string]interface{}) []string {
+	parallel := o.parallel
+	if parallel == 0 {
+		parallel = parallelism(resources)
+	}
+	args := []string{
+		"--parallel", strconv.Itoa(parallel),
+		"--skip-preview=" + strconv.FormatBool(o.skipPreview),
+		"--generate-code=" + strconv.FormatBool(o.generateCode),
+	}
+	return append(args, strings.Fields(o.extra)...)
+}
+
+// parallelism returns the lowest default parallelism of the providers of resources.
+func parallelism(resources []map[string]interface{}) int {
+	lowest := 0
+	for _, r := range resources {
+		p, ok := defaultParallelism[strings.SplitN(str(r, "type"), ":", 2)[0]]
+		if !ok {
+			p = fallbackParallelism
+		}
+		if lowest == 0 || p < lowest {
+			lowest = p
+		}
+	}
+	if lowest == 0 {
+		return fallbackParallelism
+	}
+	return lowest
+}
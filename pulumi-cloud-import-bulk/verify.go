@@ -0,0 +1,201 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math/rand"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pulumi/pulumi-cloud-import/pkg/compress"
+	"github.com/pulumi/pulumi-cloud-import/pkg/stackstate"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+)
+
+// verifyResult is the outcome of reading an imported resource back from the cloud.
+type verifyResult struct {
+	missing bool
+	// changed are the outputs that differ between the stack and the cloud.
+	changed []string
+}
+
+// verify reads a sample of the resources of an import file back from the cloud with a preview-only
+// `pulumi refresh`, and reports the resources that are missing from the stack or the cloud and the
+// ones whose state doesn't match the cloud anymore. The stack is left untouched.
+func verify(args []string) {
+	flags := flag.NewFlagSet("verify", flag.ExitOnError)
+	file := flags.String("file", "import.json", "import file the stack was imported from")
+	stack := flags.String("stack", "", "stack the resources were imported into, defaults to the selected stack")
+	sample := flags.Int("sample", 0, "number of resources picked at random to verify, 0 verifies all of them")
+	seed := flags.Int64("seed", 0, "seed of the random sample, to verify the same resources again")
+	batchSize := flags.Int("batch-size", 100, "number of resources read at once")
+	credentials := credentialFlags(flags)
+	flags.Parse(args)
+
+	// import files written with --compress are read as they are
+	b, err := compress.ReadFile(*file)
+	if err != nil {
+		panic(err)
+	}
+	var imports importFile
+	if err := json.Unmarshal(b, &imports); err != nil {
+		panic(err)
+	}
+	state, err := stackstate.Load(*stack)
+	if err != nil {
+		panic(err)
+	}
+
+	resources := imports.Resources
+	if *sample > 0 && *sample < len(resources) {
+		if *seed == 0 {
+			*seed = time.Now().UnixNano()
+		}
+		fmt.Printf("verifying %d of %d resources, pass --seed %d to verify the same ones again\n", *sample, len(resources), *seed)
+		rand.New(rand.NewSource(*seed)).Shuffle(len(resources), func(i, j int) {
+			resources[i], resources[j] = resources[j], resources[i]
+		})
+		resources = resources[:*sample]
+	}
+
+	notImported := []map[string]interface{}{}
+	urns := []resource.URN{}
+	byURN := map[resource.URN]map[string]interface{}{}
+	for _, r := range resources {
+		urn, ok := state.URN(str(r, "type"), str(r, "id"))
+		if !ok {
+			notImported = append(notImported, r)
+			continue
+		}
+		urns = append(urns, urn)
+		byURN[urn] = r
+	}
+
+	results := map[resource.URN]verifyResult{}
+	for i := 0; i < len(urns); i += *batchSize {
+		end := i + *batchSize
+		if end > len(urns) {
+			end = len(urns)
+		}
+		fmt.Printf("reading %d resources, %d read so far\n", end-i, i)
+		batch, err := readBack(*stack, credentials, urns[i:end])
+		if err != nil {
+			panic(err)
+		}
+		for urn, res := range batch {
+			results[urn] = res
+		}
+	}
+
+	missing, mismatched := 0, 0
+	for _, r := range notImported {
+		fmt.Printf("not in the stack: %s %s\n", str(r, "type"), str(r, "id"))
+	}
+	for _, urn := range urns {
+		r, res := byURN[urn], results[urn]
+		switch {
+		case res.missing:
+			missing++
+			fmt.Printf("missing from the cloud: %s %s\n", str(r, "type"), str(r, "id"))
+		case len(res.changed) > 0:
+			mismatched++
+			fmt.Printf("mismatch: %s %s: %s\n", str(r, "type"), str(r, "id"), strings.Join(res.changed, ", "))
+		}
+	}
+	fmt.Printf("verified %d resources: %d not in the stack, %d missing from the cloud, %d mismatched\n",
+		len(resources), len(notImported), missing, mismatched)
+	if len(notImported)+missing+mismatched > 0 {
+		os.Exit(1)
+	}
+}
+
+// readBack runs a preview-only `pulumi refresh` of the resources with the given URNs and returns
+// how the state the provider read differs from the state in the stack.
+func readBack(stack string, credentials *importCredentials, urns []resource.URN) (map[resource.URN]verifyResult, error) {
+	dir, err := os.MkdirTemp("", "pulumi-cloud-import-verify")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	eventLog := filepath.Join(dir, "events.json")
+
+	args := []string{"refresh", "--preview-only", "--non-interactive", "--event-log", eventLog}
+	if stack != "" {
+		args = append(args, "--stack", stack)
+	}
+	for _, urn := range urns {
+		args = append(args, "--target", string(urn))
+	}
+	env, err := credentials.environ()
+	if err != nil {
+		return nil, err
+	}
+	cmd := exec.Command("pulumi", args...)
+	cmd.Env = env
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("failed to refresh: %w", err)
+	}
+
+	f, err := os.Open(eventLog)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	results := map[resource.URN]verifyResult{}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 64*1024*1024)
+	for scanner.Scan() {
+		var e apitype.EngineEvent
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("failed to parse event log: %w", err)
+		}
+		if e.ResOutputsEvent == nil || e.ResOutputsEvent.Metadata.Op != apitype.OpRefresh {
+			continue
+		}
+		m := e.ResOutputsEvent.Metadata
+		if m.New == nil {
+			results[resource.URN(m.URN)] = verifyResult{missing: true}
+			continue
+		}
+		var old map[string]interface{}
+		if m.Old != nil {
+			old = m.Old.Outputs
+		}
+		results[resource.URN(m.URN)] = verifyResult{changed: changedOutputs(old, m.New.Outputs)}
+	}
+	return results, scanner.Err()
+}
+
+// changedOutputs returns the outputs whose values differ between old and new. Internal outputs
+// like __meta are left out.
+func changedOutputs(old, new map[string]interface{}) []string {
+	keys := map[string]bool{}
+	for k := range old {
+		keys[k] = true
+	}
+	for k := range new {
+		keys[k] = true
+	}
+	changed := []string{}
+	for k := range keys {
+		if strings.HasPrefix(k, "__") {
+			continue
+		}
+		if !reflect.DeepEqual(old[k], new[k]) {
+			changed = append(changed, k)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/pulumi/pulumi-cloud-import/pkg/config"
+	"github.com/pulumi/pulumi-cloud-import/pkg/importer"
+	"github.com/pulumi/pulumi-cloud-import/pkg/naming"
+	"github.com/pulumi/pulumi-cloud-import/pkg/version"
+)
+
+const fastlyAPIURL = "https://api.fastly.com"
+
+const (
+	serviceVclType             = "fastly:index/serviceVcl:ServiceVcl"
+	serviceComputeType         = "fastly:index/serviceCompute:ServiceCompute"
+	serviceACLEntriesType      = "fastly:index/serviceACLEntries:ServiceACLEntries"
+	serviceDictionaryItemsType = "fastly:index/serviceDictionaryItems:ServiceDictionaryItems"
+)
+
+type fastlyService struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	// vcl or wasm
+	Type string `json:"type"`
+	// the currently active version
+	Version int `json:"version"`
+}
+
+type fastlyObject struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type fastlyDiscoverer struct {
+	apiKey string
+}
+
+func main() {
+	// --version is answered before the credentials are read
+	version.HandleFlag()
+	config.HandleCommand()
+	importer.Main(fastlyDiscoverer{apiKey: getAPIKey()})
+}
+
+func (d fastlyDiscoverer) Types() []string {
+	return []string{serviceVclType, serviceComputeType, serviceACLEntriesType, serviceDictionaryItemsType}
+}
+
+// List reads all services of the account. ACLs and dictionaries are part of the service definition,
+// their entries are managed by separate resources imported as <serviceID>/<aclID|dictionaryID>.
+func (d fastlyDiscoverer) List(ctx context.Context, typ string, emit func(importer.ImportSpec)) error {
+	services, err := d.listServices(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, s := range services {
+		switch typ {
+		case serviceVclType, serviceComputeType:
+			if (s.Type == "wasm") != (typ == serviceComputeType) {
+				continue
+			}
+			emit(importer.ImportSpec{ID: s.ID, Type: typ, Name: naming.Default.Name(s.Name)})
+		case serviceACLEntriesType, serviceDictionaryItemsType:
+			// compute services don't support ACLs or dictionaries, inactive services don't have any yet
+			if s.Type != "vcl" || s.Version == 0 {
+				continue
+			}
+			kind := "acl"
+			if typ == serviceDictionaryItemsType {
+				kind = "dictionary"
+			}
+			var objects []fastlyObject
+			if err := d.get(ctx, fmt.Sprintf("/service/%s/version/%d/%s", s.ID, s.Version, kind), &objects); err != nil {
+				return err
+			}
+			for _, o := range objects {
+				emit(importer.ImportSpec{
+					ID:   fmt.Sprintf("%s/%s", s.ID, o.ID),
+					Type: typ,
+					Name: naming.Default.Name(s.Name + o.Name),
+				})
+			}
+		default:
+			return fmt.Errorf("unknown type %s", typ)
+		}
+	}
+
+	return nil
+}
+
+func (d fastlyDiscoverer) listServices(ctx context.Context) ([]fastlyService, error) {
+	const perPage = 100
+	services := []fastlyService{}
+	for page := 1; ; page++ {
+		var p []fastlyService
+		if err := d.get(ctx, fmt.Sprintf("/service?page=%d&per_page=%d", page, perPage), &p); err != nil {
+			return nil, err
+		}
+		services = append(services, p...)
+		if len(p) < perPage {
+			return services, nil
+		}
+	}
+}
+
+func (d fastlyDiscoverer) get(ctx context.Context, path string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fastlyAPIURL+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Fastly-Key", d.apiKey)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s returned %s", path, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// reads FASTLY_API_KEY env var or panics if it is not set
+func getAPIKey() string {
+	apiKey := os.Getenv("FASTLY_API_KEY")
+	if apiKey == "" {
+		panic("FASTLY_API_KEY env var must be set")
+	}
+	return apiKey
+}
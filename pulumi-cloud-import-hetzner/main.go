@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/pulumi/pulumi-cloud-import/pkg/config"
+	"github.com/pulumi/pulumi-cloud-import/pkg/importer"
+	"github.com/pulumi/pulumi-cloud-import/pkg/naming"
+	"github.com/pulumi/pulumi-cloud-import/pkg/version"
+)
+
+const hetznerAPIURL = "https://api.hetzner.cloud/v1"
+
+type hetznerType struct {
+	// Path of the list endpoint
+	Path string
+	// Key of the JSON array holding the resources in the response
+	Key string
+}
+
+// hetznerTypes maps pulumi-hcloud type tokens to the Hetzner Cloud API endpoint that lists them.
+// DNS zones live in the separate Hetzner DNS API which pulumi-hcloud does not cover.
+var hetznerTypes = map[string]hetznerType{
+	"hcloud:index/server:Server":             {Path: "/servers", Key: "servers"},
+	"hcloud:index/volume:Volume":             {Path: "/volumes", Key: "volumes"},
+	"hcloud:index/firewall:Firewall":         {Path: "/firewalls", Key: "firewalls"},
+	"hcloud:index/loadBalancer:LoadBalancer": {Path: "/load_balancers", Key: "load_balancers"},
+	"hcloud:index/network:Network":           {Path: "/networks", Key: "networks"},
+}
+
+type hetznerObject struct {
+	ID      int       `json:"id"`
+	Name    string    `json:"name"`
+	Created time.Time `json:"created"`
+}
+
+type hetznerMeta struct {
+	Pagination struct {
+		NextPage *int `json:"next_page"`
+	} `json:"pagination"`
+}
+
+type hetznerDiscoverer struct {
+	token string
+}
+
+func main() {
+	// --version is answered before the credentials are read
+	version.HandleFlag()
+	config.HandleCommand()
+	importer.Main(hetznerDiscoverer{token: getToken()})
+}
+
+func (d hetznerDiscoverer) Types() []string {
+	types := []string{}
+	for k := range hetznerTypes {
+		types = append(types, k)
+	}
+	return types
+}
+
+func (d hetznerDiscoverer) List(ctx context.Context, typ string, emit func(importer.ImportSpec)) error {
+	t, ok := hetznerTypes[typ]
+	if !ok {
+		return fmt.Errorf("unknown type %s", typ)
+	}
+
+	page := 1
+	for {
+		url := fmt.Sprintf("%s%s?page=%d&per_page=50", hetznerAPIURL, t.Path, page)
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+d.token)
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return err
+		}
+		// responses look like {"servers": [...], "meta": {...}}
+		var body map[string]json.RawMessage
+		err = json.NewDecoder(resp.Body).Decode(&body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("listing %s returned %s", t.Path, resp.Status)
+		}
+		if err != nil {
+			return err
+		}
+
+		var objects []hetznerObject
+		if err := json.Unmarshal(body[t.Key], &objects); err != nil {
+			return err
+		}
+		for _, o := range objects {
+			emit(importer.ImportSpec{
+				ID:      strconv.Itoa(o.ID),
+				Type:    typ,
+				Name:    naming.Default.Name(o.Name),
+				Created: o.Created,
+			})
+		}
+
+		var meta hetznerMeta
+		if err := json.Unmarshal(body["meta"], &meta); err != nil {
+			return err
+		}
+		if meta.Pagination.NextPage == nil {
+			return nil
+		}
+		page = *meta.Pagination.NextPage
+	}
+}
+
+// reads HCLOUD_TOKEN env var or panics if it is not set
+func getToken() string {
+	token := os.Getenv("HCLOUD_TOKEN")
+	if token == "" {
+		panic("HCLOUD_TOKEN env var must be set")
+	}
+	return token
+}
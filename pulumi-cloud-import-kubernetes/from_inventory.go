@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/pulumi/pulumi-cloud-import/pkg/config"
+	"github.com/pulumi/pulumi-cloud-import/pkg/created"
+	"github.com/pulumi/pulumi-cloud-import/pkg/ignore"
+	"github.com/pulumi/pulumi-cloud-import/pkg/manifest"
+	"github.com/pulumi/pulumi-cloud-import/pkg/naming"
+	"github.com/pulumi/pulumi-cloud-import/pkg/offline"
+	"github.com/pulumi/pulumi-cloud-import/pkg/redact"
+	"github.com/pulumi/pulumi-cloud-import/pkg/terminal"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// importFromInventory builds the import file from the kubectl get -o json dump passed with
+// --from-inventory rather than from the API server, for the context kubeconfig points to like a
+// scan. Objects are imported underneath their namespace, whether or not the dump has it, and the
+// --kinds, --api-groups and creation time filters apply. Objects being deleted are left out.
+func importFromInventory(path string) (importFile, error) {
+	imports := importFile{
+		Resources: []importSpec{},
+	}
+	objects, err := offline.ReadKubectl(path)
+	if err != nil {
+		return imports, err
+	}
+	_, provider := loadKubeConfig()
+
+	token := func(x *unstructured.Unstructured) string {
+		return kindToken(x.GroupVersionKind().GroupVersion(), x.GroupVersionKind().Kind)
+	}
+	id := func(x *unstructured.Unstructured) string {
+		if x.GetNamespace() != "" {
+			return fmt.Sprintf("%s/%s", x.GetNamespace(), x.GetName())
+		}
+		return x.GetName()
+	}
+
+	apiGroups := config.APIGroups.List()
+	excludeAPIGroups := config.ExcludeAPIGroups.List()
+	kinds := config.Kinds.List()
+	excludeKinds := config.ExcludeKinds.List()
+	// namespaces are imported even when not in --kinds unless explicitly excluded, like in a scan
+	importNamespaces := includeKind("Namespace", nil, excludeKinds)
+	namespaceToken := kindToken(namespaceGVR.GroupVersion(), "Namespace")
+	window := created.FromFlags()
+
+	// objects with a deletion timestamp are only waiting for their finalizers, and so are the
+	// objects of a namespace being deleted
+	terminating := map[string]bool{}
+	for _, object := range objects {
+		item := unstructured.Unstructured{Object: object}
+		if item.GetKind() == "Namespace" && item.GetDeletionTimestamp() != nil {
+			terminal.Skip(token(&item), id(&item), "Terminating")
+			terminating[item.GetName()] = true
+		}
+	}
+
+	namespaces := map[string]bool{}
+	addNamespace := func(name string) {
+		if !importNamespaces || name == "" || namespaces[name] || ignore.Skip(namespaceToken, name) {
+			return
+		}
+		namespaces[name] = true
+		imports.Resources = append(imports.Resources, importSpec{
+			Type:     namespaceToken,
+			Name:     naming.Kubernetes.Name(name),
+			ID:       name,
+			Provider: provider.Name,
+		})
+	}
+
+	seen := map[string]bool{}
+	for _, object := range objects {
+		item := unstructured.Unstructured{Object: object}
+		if item.GetKind() == "Namespace" {
+			if !terminating[item.GetName()] {
+				addNamespace(item.GetName())
+			}
+			continue
+		}
+		gvk := item.GroupVersionKind()
+		if !includeGroup(gvk.Group, apiGroups, excludeAPIGroups) || !includeKind(gvk.Kind, kinds, excludeKinds) {
+			continue
+		}
+		if !window.Keep(item.GetCreationTimestamp().Time) {
+			continue
+		}
+		if item.GetDeletionTimestamp() != nil || terminating[item.GetNamespace()] {
+			terminal.Skip(token(&item), id(&item), "Terminating")
+			continue
+		}
+		if seen[token(&item)+"|"+id(&item)] || ignore.Skip(token(&item), id(&item)) {
+			continue
+		}
+		seen[token(&item)+"|"+id(&item)] = true
+		r := importSpec{
+			Type:     token(&item),
+			Name:     naming.Kubernetes.Name(id(&item)),
+			ID:       id(&item),
+			Provider: provider.Name,
+		}
+		// the objects of namespaces left out have no parent
+		addNamespace(item.GetNamespace())
+		if namespaces[item.GetNamespace()] {
+			r.Parent = naming.Kubernetes.Name(item.GetNamespace())
+		}
+		if err := renderYAML(&item); err != nil {
+			redact.Println("Failed to render", r.ID, err)
+		}
+		imports.Resources = append(imports.Resources, r)
+	}
+
+	window.Report()
+	terminal.Report()
+	ignore.Report()
+	if provider.Context != "" {
+		manifest.Identify("context", provider.Context)
+	} else {
+		manifest.Identify("context", provider.Name)
+	}
+	manifest.Identify("inventory", path)
+	return imports, manifest.Write()
+}
@@ -3,34 +3,83 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
 	"os"
-	"strconv"
+	"path/filepath"
+	"sort"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
 
+	"github.com/pulumi/pulumi-cloud-import/pkg/aliases"
+	"github.com/pulumi/pulumi-cloud-import/pkg/backpressure"
+	"github.com/pulumi/pulumi-cloud-import/pkg/cancel"
+	"github.com/pulumi/pulumi-cloud-import/pkg/chaos"
+	"github.com/pulumi/pulumi-cloud-import/pkg/compress"
+	"github.com/pulumi/pulumi-cloud-import/pkg/config"
+	"github.com/pulumi/pulumi-cloud-import/pkg/costtags"
+	"github.com/pulumi/pulumi-cloud-import/pkg/coverage"
+	"github.com/pulumi/pulumi-cloud-import/pkg/created"
+	"github.com/pulumi/pulumi-cloud-import/pkg/debuglog"
+	"github.com/pulumi/pulumi-cloud-import/pkg/denied"
+	"github.com/pulumi/pulumi-cloud-import/pkg/events"
+	"github.com/pulumi/pulumi-cloud-import/pkg/hierarchy"
+	"github.com/pulumi/pulumi-cloud-import/pkg/ignore"
+	"github.com/pulumi/pulumi-cloud-import/pkg/ignorechanges"
+	"github.com/pulumi/pulumi-cloud-import/pkg/insights"
+	"github.com/pulumi/pulumi-cloud-import/pkg/manifest"
+	"github.com/pulumi/pulumi-cloud-import/pkg/naming"
+	"github.com/pulumi/pulumi-cloud-import/pkg/offline"
+	"github.com/pulumi/pulumi-cloud-import/pkg/ownership"
+	"github.com/pulumi/pulumi-cloud-import/pkg/quarantine"
+	"github.com/pulumi/pulumi-cloud-import/pkg/recorder"
+	"github.com/pulumi/pulumi-cloud-import/pkg/redact"
+	"github.com/pulumi/pulumi-cloud-import/pkg/retry"
+	"github.com/pulumi/pulumi-cloud-import/pkg/shard"
+	"github.com/pulumi/pulumi-cloud-import/pkg/stackchunk"
+	"github.com/pulumi/pulumi-cloud-import/pkg/stackstate"
+	"github.com/pulumi/pulumi-cloud-import/pkg/summary"
+	"github.com/pulumi/pulumi-cloud-import/pkg/supportbundle"
+	"github.com/pulumi/pulumi-cloud-import/pkg/terminal"
+	"github.com/pulumi/pulumi-cloud-import/pkg/version"
+	"github.com/pulumi/pulumi-cloud-import/pkg/workers"
 	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
 	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 )
 
 type importFile struct {
+	// Metadata tells which environment the import file was produced from, `pulumi import`
+	// ignores it
+	Metadata  *manifest.Header        `json:"metadata,omitempty"`
 	NameTable map[string]resource.URN `json:"nameTable"`
 	Resources []importSpec            `json:"resources"`
 }
 
 type importSpec struct {
-	Token string `json:"token"`
-	Name  string `json:"name"`
-	ID    string `json:"id"`
+	Type              string   `json:"type"`
+	Name              string   `json:"name"`
+	ID                string   `json:"id"`
+	Parent            string   `json:"parent"`
+	Provider          string   `json:"provider"`
+	Version           string   `json:"version"`
+	PluginDownloadURL string   `json:"pluginDownloadUrl"`
+	Properties        []string `json:"properties"`
 }
 
+var namespaceGVR = schema.GroupVersionResource{Version: "v1", Resource: "namespaces"}
+
 type Mode int64
 
 const (
@@ -38,29 +87,66 @@ const (
 	ReadMode
 )
 
-func debugLog(a ...any) {
-	if os.Getenv("PULUMI_CLOUD_IMPORT_DEBUG") != "" {
-		fmt.Println(a...)
-	}
-}
-
 func main() {
+	// resources are discovered from the cluster, the client decides which API versions are understood
+	version.HandleFlag(version.Schema{Name: "k8s.io/client-go", Version: version.Dependency("k8s.io/client-go")})
+	config.HandleCommand()
+	supportbundle.Start()
+	defer supportbundle.Write()
+
 	isImportMode := isImportMode()
+	offline.Check(isImportMode)
 
 	// pulumi read resource mode
 	if !isImportMode {
-		pulumi.Run(func(ctx *pulumi.Context) error {
+		// the scan is recorded for the stacks the resources are split into to replay
+		stackchunk.Record()
+		supportbundle.Run(func(ctx *pulumi.Context) error {
 			_, err := buildImportSpec(ctx, ReadMode)
-			return err
+			if err != nil {
+				return err
+			}
+			return quarantine.Save()
 		})
 	} else {
 		mode := ImportMode
-		imports, err := buildImportSpec(nil, mode)
+		// resources already in the stack given with --stack are left out of the import file
+		state, err := stackstate.FromFlags()
 		if err != nil {
 			panic(err)
 		}
+		// with --from-inventory, resources are found in a dump rather than in the cluster
+		var imports importFile
+		if path := offline.Path(); path != "" {
+			imports, err = importFromInventory(path)
+		} else {
+			imports, err = buildImportSpec(nil, mode)
+		}
+		if err != nil {
+			panic(err)
+		}
+		if err := insights.Diff(imports.Resources, specRef); err != nil {
+			panic(err)
+		}
+		imports.Resources, imports.NameTable = stackstate.Filter(state, imports.Resources, specRef)
+
+		// resources are imported with the provider of the scanned context, which has to be in the stack
+		_, provider := loadKubeConfig()
+		if urn, ok := state.Named(providerType, provider.Name); ok {
+			imports.NameTable[provider.Name] = urn
+		} else {
+			if state == nil {
+				fmt.Printf("Pass --stack to import resources with the %s provider of the stack, they will be imported with the default provider of whoever runs pulumi import\n", provider.Name)
+			} else {
+				fmt.Printf("No provider named %s in the stack, resources will be imported with the default provider of whoever runs pulumi import\n", provider.Name)
+			}
+			for i := range imports.Resources {
+				imports.Resources[i].Provider = ""
+			}
+		}
 		fmt.Printf("Total resources: %d", len(imports.Resources))
 
+		imports.Metadata = manifest.NewHeader()
 		err = writeImportFile(imports)
 		if err != nil {
 			panic(err)
@@ -74,47 +160,67 @@ func buildImportSpec(ctx *pulumi.Context, mode Mode) (importFile, error) {
 		Resources: []importSpec{},
 	}
 
-	// Load kubeconfig file
-	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
-	configOverrides := &clientcmd.ConfigOverrides{}
-	kubeConfig := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, configOverrides)
-	config, err := kubeConfig.ClientConfig()
+	kubeConfig, provider := loadKubeConfig()
+	restConfig, err := kubeConfig.ClientConfig()
+	if recorder.Replaying() {
+		// recorded responses are matched by path, so any API server address will do
+		restConfig, err = &rest.Config{Host: "https://replay.invalid"}, nil
+	}
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to load kubeconfig: %v\n", err)
-		os.Exit(1)
+		fmt.Fprint(os.Stderr, redact.String(fmt.Sprintf("Failed to load kubeconfig: %v\n", err)))
+		supportbundle.Exit(1)
+	}
+	restConfig.Burst = 120
+	restConfig.QPS = 50
+	restConfig.WrapTransport = func(rt http.RoundTripper) http.RoundTripper {
+		return denied.Wrap(workers.Wrap(chaos.Wrap(recorder.Wrap(rt))))
 	}
-	config.Burst = 120
-	config.QPS = 50
 
 	// Create Kubernetes clientset
-	clientset, err := kubernetes.NewForConfig(config)
+	clientset, err := kubernetes.NewForConfig(restConfig)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Failed to create Kubernetes clientset: %v\n", err)
-		os.Exit(1)
+		supportbundle.Exit(1)
 	}
 
 	// Create dynamic client
-	dynamicClient, err := dynamic.NewForConfig(config)
+	dynamicClient, err := dynamic.NewForConfig(restConfig)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Failed to create dynamic client: %v\n", err)
-		os.Exit(1)
+		supportbundle.Exit(1)
 	}
 
 	// List API resources
+	apiGroups := config.APIGroups.List()
+	excludeAPIGroups := config.ExcludeAPIGroups.List()
 	apiResources, err := clientset.Discovery().ServerPreferredResources()
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to list API resources: %v\n", err)
-		os.Exit(1)
+	var discoveryFailed *discovery.ErrGroupDiscoveryFailed
+	if errors.As(err, &discoveryFailed) {
+		// a broken aggregated API, like a metrics server that is down, only fails its own group
+		for gv := range discoveryFailed.Groups {
+			if !includeGroup(gv.Group, apiGroups, excludeAPIGroups) {
+				delete(discoveryFailed.Groups, gv)
+			}
+		}
+		if len(discoveryFailed.Groups) > 0 {
+			reportBrokenGroups(discoveryFailed)
+		}
+	} else if err != nil {
+		fmt.Fprint(os.Stderr, redact.String(fmt.Sprintf("Failed to list API resources: %v\n", err)))
+		supportbundle.Exit(1)
+	}
+	// the resources of groups that are filtered out are never listed
+	filtered := []*metav1.APIResourceList{}
+	for _, list := range apiResources {
+		if gv, err := schema.ParseGroupVersion(list.GroupVersion); err == nil && !includeGroup(gv.Group, apiGroups, excludeAPIGroups) {
+			continue
+		}
+		filtered = append(filtered, list)
 	}
+	apiResources = filtered
 
 	token := func(x *unstructured.Unstructured) string {
-		var gv string
-		if x.GroupVersionKind().Group == "" {
-			gv = fmt.Sprintf("core/%s", x.GroupVersionKind().Version)
-		} else {
-			gv = x.GroupVersionKind().GroupVersion().String()
-		}
-		return fmt.Sprintf("kubernetes:%s:%s", gv, x.GroupVersionKind().Kind)
+		return kindToken(x.GroupVersionKind().GroupVersion(), x.GroupVersionKind().Kind)
 	}
 	id := func(x *unstructured.Unstructured) string {
 		if x.GetNamespace() != "" {
@@ -123,9 +229,62 @@ func buildImportSpec(ctx *pulumi.Context, mode Mode) (importFile, error) {
 		return x.GetName()
 	}
 
+	kinds := config.Kinds.List()
+	excludeKinds := config.ExcludeKinds.List()
+
+	// namespaces are listed first so that namespaced resources can be imported underneath them, they
+	// are imported even when not in --kinds unless explicitly excluded
+	importNamespaces := includeKind("Namespace", nil, excludeKinds)
+	namespaces := &unstructured.UnstructuredList{}
+	coverage.Known(kindToken(namespaceGVR.GroupVersion(), "Namespace"))
+	if importNamespaces {
+		namespaces, err = dynamicClient.Resource(namespaceGVR).List(context.Background(), metav1.ListOptions{})
+		if err != nil {
+			fmt.Fprint(os.Stderr, redact.String(fmt.Sprintf("Failed to list namespaces: %v\n", err)))
+			supportbundle.Exit(1)
+		}
+		coverage.Scanned(kindToken(namespaceGVR.GroupVersion(), "Namespace"))
+	}
+
 	var ops uint64
 
-	importChan := make(chan importSpec, 100000)
+	importChan := make(chan importSpec, backpressure.MaxBuffer()+len(namespaces.Items))
+	watchdog := backpressure.NewWatchdog()
+	// namespaces are kept whatever their creation time, for the resources created in them
+	window := created.FromFlags()
+	events.Started(namespaceGVR.String())
+	namespaceCount := 0
+	terminating := map[string]bool{}
+	// imported are the namespaces imported, which the objects in them are imported underneath
+	imported := map[string]bool{}
+	for _, item := range namespaces.Items {
+		// objects with a deletion timestamp are only waiting for their finalizers, and so are the
+		// objects of a namespace being deleted
+		if item.GetDeletionTimestamp() != nil {
+			terminal.Skip(token(&item), id(&item), "Terminating")
+			terminating[item.GetName()] = true
+			continue
+		}
+		if ignore.Skip(token(&item), id(&item)) {
+			continue
+		}
+		r := importSpec{
+			Type: token(&item),
+			Name: naming.Kubernetes.Name(id(&item)),
+			ID:   id(&item),
+		}
+		owner, evidence := classifyOwnership(&item)
+		ownership.Add(r.Type, r.Name, r.ID, owner, evidence)
+		costtags.Add(r.Type, r.Name, r.ID, item.GetLabels())
+		if err := renderYAML(&item); err != nil {
+			redact.Println("Failed to render", r.ID, err)
+		}
+		events.Discovered(namespaceGVR.String(), r.Type, r.Name, r.ID)
+		namespaceCount++
+		imported[item.GetName()] = true
+		importChan <- r
+	}
+	events.Finished(namespaceGVR.String(), namespaceCount)
 	var wg sync.WaitGroup
 
 	chunks := getConcurrentWorkers()
@@ -139,7 +298,7 @@ func buildImportSpec(ctx *pulumi.Context, mode Mode) (importFile, error) {
 	}
 
 	setupTime := time.Since(start)
-	debugLog(fmt.Sprintf("Initialization time: %s\n", setupTime))
+	debuglog.Println("Initialization time:", setupTime)
 
 	for i := 0; i < chunks; i++ {
 		pkgs := pkgChunks[i]
@@ -147,13 +306,14 @@ func buildImportSpec(ctx *pulumi.Context, mode Mode) (importFile, error) {
 		go func(pkgChunk []*metav1.APIResourceList, i int) {
 			defer func() {
 				if r := recover(); r != nil {
-					fmt.Printf("encountered error processing AWS resources: %v \n", r)
+					redact.Printf("encountered error processing Kubernetes resources: %v \n", r)
 				}
 			}()
 			defer wg.Done()
 
 			start := time.Now()
 			for _, group := range pkgChunk {
+				workers.Wait(i)
 				for _, res := range group.APIResources {
 					gv, err := schema.ParseGroupVersion(group.GroupVersion)
 					if err != nil {
@@ -161,26 +321,83 @@ func buildImportSpec(ctx *pulumi.Context, mode Mode) (importFile, error) {
 						continue
 					}
 					gvr := gv.WithResource(res.Name)
-					obj, err := dynamicClient.Resource(gvr).List(context.Background(), metav1.ListOptions{})
+					if gvr == namespaceGVR {
+						continue
+					}
+					typ := kindToken(gv, res.Kind)
+					coverage.Known(typ)
+					if !includeKind(res.Kind, kinds, excludeKinds) {
+						continue
+					}
+					// kinds named in --kinds are listed whatever their cost
+					named := len(kinds) > 0 && includeKind(res.Kind, kinds, nil)
+					if expensiveKinds[res.Kind] && !config.IncludeExpensive.Bool() && !named {
+						coverage.Skip(typ, "expensive to list, pass --include-expensive or --kinds to list it")
+						continue
+					}
+					// virtual resources, like token reviews, can only be created
+					if !listable(res) {
+						coverage.Skip(typ, "the API doesn't support listing it")
+						continue
+					}
+					scope := gvr.String()
+					events.Started(scope)
+					ctx, done := cancel.Scope(scope)
+					obj, err := list(ctx, dynamicClient, gvr)
+					done()
+					if cancel.Cancelled(scope) {
+						events.Finished(scope, 0)
+						coverage.Skip(typ, "cancelled with --cancel-file")
+						continue
+					}
 					if err != nil {
-						// TODO: skip unsupported resource types
 						//fmt.Fprintf(os.Stderr, "Failed to list objects for %s: %v\n", gvr.String(), err)
+						events.Error(scope, err)
+						events.Finished(scope, 0)
+						coverage.Failed(typ, err)
 						continue
 					}
+					debuglog.For(i+1, scope).Println("listed", len(obj.Items), "objects")
+					count := 0
 					for _, item := range obj.Items {
+						if !window.Keep(item.GetCreationTimestamp().Time) {
+							continue
+						}
+						if item.GetDeletionTimestamp() != nil || terminating[item.GetNamespace()] {
+							terminal.Skip(token(&item), id(&item), "Terminating")
+							continue
+						}
+						if ignore.Skip(token(&item), id(&item)) {
+							continue
+						}
 						r := importSpec{
-							Token: token(&item),
-							Name:  id(&item),
-							ID:    id(&item),
+							Type: token(&item),
+							Name: naming.Kubernetes.Name(id(&item)),
+							ID:   id(&item),
+						}
+						// cluster-scoped objects, and the objects of namespaces left out, have no parent
+						if imported[item.GetNamespace()] {
+							r.Parent = naming.Kubernetes.Name(item.GetNamespace())
+						}
+						owner, evidence := classifyOwnership(&item)
+						ownership.Add(r.Type, r.Name, r.ID, owner, evidence)
+						costtags.Add(r.Type, r.Name, r.ID, item.GetLabels())
+						if err := renderYAML(&item); err != nil {
+							redact.Println("Failed to render", r.ID, err)
 						}
 
 						atomic.AddUint64(&ops, 1)
+						events.Discovered(scope, r.Type, r.Name, r.ID)
+						watchdog.Wait(func() int { return len(importChan) })
+						count++
 						importChan <- r
 					}
+					events.Finished(scope, count)
+					coverage.Scanned(typ)
 				}
 			}
 			stop := time.Since(start)
-			debugLog("worker:", i+1, "count:", atomic.LoadUint64(&ops), "read time:", stop)
+			debuglog.For(i+1, "").Println("count:", atomic.LoadUint64(&ops), "read time:", stop)
 			fmt.Printf("worker %d of %d completed\n", i+1, chunks)
 		}(pkgs, i)
 	}
@@ -189,36 +406,283 @@ func buildImportSpec(ctx *pulumi.Context, mode Mode) (importFile, error) {
 		wg.Wait()
 		close(importChan)
 	}()
+	defer window.Report()
+
+	reader := hierarchy.NewReader()
+
+	var providerResource pulumi.ProviderResourceState
+	if mode == ReadMode {
+		if err := ctx.RegisterResource(providerType, provider.Name, provider.inputs(), &providerResource); err != nil {
+			return imports, err
+		}
+	}
 
 	for r := range importChan {
+		r.Provider = provider.Name
 		imports.Resources = append(imports.Resources, r)
+		previous := aliases.Record(r.Type, r.ID, r.Name)
 		if mode == ReadMode {
-			var res pulumi.CustomResourceState
-			// currently ignore errors
-			_ = ctx.ReadResource(r.Token, r.Name, pulumi.ID(r.ID), nil, &res)
+			r := r
+			// namespaced resources are read once their namespace is
+			reader.Read(r.Name, r.Parent, func(parent ...pulumi.ResourceOption) pulumi.Resource {
+				var res pulumi.CustomResourceState
+				opts := append([]pulumi.ResourceOption{pulumi.Provider(&providerResource)}, parent...)
+				opts = append(opts, ignorechanges.Options(r.Type)...)
+				opts = append(opts, aliases.Options(previous)...)
+				// resources failing to register or to be read are set aside in quarantine.json
+				if err := ctx.ReadResource(r.Type, r.Name, pulumi.ID(r.ID), nil, &res, opts...); err != nil {
+					quarantine.Add(r, quarantine.StageRead, err)
+					return nil
+				}
+				quarantine.Watch(r, &res, nil)
+				return &res
+			})
 		}
 
 	}
+	if mode == ReadMode {
+		reader.Flush()
+		// the run of the first stack reports on the scan and updates the others
+		if stackchunk.Additional() {
+			return imports, nil
+		}
+		if err := stackchunk.Rotate(ctx, reader.Chunks()); err != nil {
+			return imports, err
+		}
+	}
 
-	return imports, nil
+	terminal.Report()
+	ignore.Report()
+	if err := summary.Report(); err != nil {
+		return imports, err
+	}
+	if provider.Context != "" {
+		manifest.Identify("context", provider.Context)
+	} else {
+		manifest.Identify("context", provider.Name)
+	}
+	manifest.Identify("server", restConfig.Host)
+	if err := manifest.Write(); err != nil {
+		return imports, err
+	}
+	if err := coverage.Report(); err != nil {
+		return imports, err
+	}
+	if err := aliases.Write(); err != nil {
+		return imports, err
+	}
+	if err := costtags.Report(); err != nil {
+		return imports, err
+	}
+	if err := denied.Report(); err != nil {
+		return imports, err
+	}
+	return imports, ownership.Report()
 }
 
-// write import file to disk
-func writeImportFile(imports importFile) error {
-	// write the import file to disk
-	importFile, err := json.MarshalIndent(imports, "", "    ")
-	if err != nil {
-		return err
+const providerType = "pulumi:providers:kubernetes"
+
+// kubernetesProvider is the provider resources are read and imported with. It is configured for
+// the context resources are discovered from, so that reads and imports never target whatever
+// cluster the current context of the operator points to.
+type kubernetesProvider struct {
+	Name       string
+	Context    string
+	Kubeconfig string
+}
+
+func (p kubernetesProvider) inputs() pulumi.Map {
+	inputs := pulumi.Map{}
+	if p.Context != "" {
+		inputs["context"] = pulumi.String(p.Context)
+	}
+	if p.Kubeconfig != "" {
+		inputs["kubeconfig"] = pulumi.String(p.Kubeconfig)
+	}
+	return inputs
+}
+
+// loadKubeConfig loads the kubeconfig, using the context given with --kube-context if any, and
+// returns the provider for the context it points to.
+func loadKubeConfig() (clientcmd.ClientConfig, kubernetesProvider) {
+	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+	configOverrides := &clientcmd.ConfigOverrides{
+		CurrentContext: config.KubeContext.Value(),
 	}
+	kubeConfig := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, configOverrides)
 
-	err = os.WriteFile("import.json", importFile, 0644)
-	if err != nil {
-		return err
+	context := configOverrides.CurrentContext
+	if context == "" {
+		if raw, err := kubeConfig.RawConfig(); err == nil {
+			context = raw.CurrentContext
+		}
+	}
+	if context == "" {
+		// without a kubeconfig the client falls back to the in-cluster configuration
+		return kubeConfig, kubernetesProvider{Name: "in-cluster"}
+	}
+	provider := kubernetesProvider{Name: naming.Kubernetes.Name(context), Context: context}
+	// the provider takes a single kubeconfig, a list of them is left to the provider to merge
+	if path := os.Getenv("KUBECONFIG"); path != "" && !strings.ContainsRune(path, filepath.ListSeparator) {
+		provider.Kubeconfig = path
+	}
+	return kubeConfig, provider
+}
+
+// reportBrokenGroups prints the group versions whose resources couldn't be discovered, and which
+// aren't imported as a result.
+func reportBrokenGroups(failed *discovery.ErrGroupDiscoveryFailed) {
+	groups := make([]schema.GroupVersion, 0, len(failed.Groups))
+	for gv := range failed.Groups {
+		groups = append(groups, gv)
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].String() < groups[j].String() })
+	fmt.Printf("%d API groups couldn't be discovered, their resources are not imported:\n", len(groups))
+	for _, gv := range groups {
+		redact.Printf("  %s: %v\n", gv, failed.Groups[gv])
+		events.Error(gv.String(), failed.Groups[gv])
+	}
+}
+
+// classifyOwnership classifies a resource from its labels and annotations. Resources owned by
+// another resource, like the pods of a replica set, are managed by its controller.
+func classifyOwnership(item *unstructured.Unstructured) (string, string) {
+	if len(item.GetOwnerReferences()) > 0 {
+		return "controller", "ownerReferences"
+	}
+	tags := map[string]string{}
+	for k, v := range item.GetAnnotations() {
+		tags[k] = v
+	}
+	for k, v := range item.GetLabels() {
+		tags[k] = v
+	}
+	return ownership.Classify(tags)
+}
+
+// kindToken returns the kubernetes provider token of kind in gv, e.g. kubernetes:apps/v1:Deployment.
+func kindToken(gv schema.GroupVersion, kind string) string {
+	version := gv.String()
+	if gv.Group == "" {
+		version = "core/" + gv.Version
+	}
+	return fmt.Sprintf("kubernetes:%s:%s", version, kind)
+}
+
+// listable reports whether the API supports listing res.
+func listable(res metav1.APIResource) bool {
+	for _, verb := range res.Verbs {
+		if verb == "list" {
+			return true
+		}
+	}
+	return false
+}
+
+// list lists the objects of gvr, retrying the requests the API server throttled or failed to serve
+// with the policies of package retry.
+func list(ctx context.Context, client dynamic.Interface, gvr schema.GroupVersionResource) (*unstructured.UnstructuredList, error) {
+	var obj *unstructured.UnstructuredList
+	err := retry.Do(ctx, func() error {
+		var err error
+		obj, err = client.Resource(gvr).List(ctx, metav1.ListOptions{})
+		return classifyListError(err)
+	}, func(err error, class retry.Class, delay time.Duration) {
+		debuglog.Println("failed to list", gvr.String(), "retrying in", delay, err)
+	})
+	return obj, err
+}
+
+// classifyListError classifies a failed list for retries: throttled when the API server sheds load,
+// transient when it times out or fails, permanent otherwise, like when the list is forbidden.
+func classifyListError(err error) error {
+	if err == nil {
+		return nil
+	}
+	var after time.Duration
+	if seconds, ok := apierrors.SuggestsClientDelay(err); ok {
+		after = time.Duration(seconds) * time.Second
+	}
+	switch {
+	case apierrors.IsTooManyRequests(err):
+		return retry.Classify(err, retry.Throttled, after)
+	case apierrors.IsServerTimeout(err), apierrors.IsTimeout(err), apierrors.IsInternalError(err), apierrors.IsServiceUnavailable(err):
+		return retry.Classify(err, retry.Transient, after)
+	}
+	return retry.Stop(err)
+}
+
+// expensiveKinds are kinds with an object per occurrence, often tens of thousands in a busy cluster
+// and gone within the hour, which are skipped unless --include-expensive is passed or they're
+// listed in --kinds.
+var expensiveKinds = map[string]bool{
+	"Event": true,
+}
+
+// includeGroup reports whether the API group passes the --api-groups and --exclude-api-groups
+// filters. The core group, whose name is empty, is named core.
+func includeGroup(group string, include []string, exclude []string) bool {
+	if group == "" {
+		group = "core"
+	}
+	for _, g := range exclude {
+		if strings.EqualFold(g, group) {
+			return false
+		}
+	}
+	if len(include) == 0 {
+		return true
+	}
+	for _, g := range include {
+		if strings.EqualFold(g, group) {
+			return true
+		}
+	}
+	return false
+}
+
+// includeKind reports whether resources of kind pass the --kinds and --exclude-kinds filters.
+func includeKind(kind string, include []string, exclude []string) bool {
+	for _, k := range exclude {
+		if strings.EqualFold(k, kind) {
+			return false
+		}
+	}
+	if len(include) == 0 {
+		return true
+	}
+	for _, k := range include {
+		if strings.EqualFold(k, kind) {
+			return true
+		}
+	}
+	return false
+}
+
+// write import file to disk, split into shards when --shards is passed
+func writeImportFile(imports importFile) error {
+	shards := shard.Split(imports.Resources, specRef)
+	for i, resources := range shards {
+		f := importFile{Metadata: imports.Metadata, NameTable: imports.NameTable, Resources: resources}
+		// write the import file to disk
+		importFile, err := json.MarshalIndent(f, "", "    ")
+		if err != nil {
+			return err
+		}
+
+		err = compress.WriteFile(shard.Path(config.Output.Value(), i, len(shards)), importFile)
+		if err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
 
+func specRef(r importSpec) stackstate.Ref {
+	return stackstate.Ref{Type: r.Type, ID: r.ID, Name: r.Name, Parent: r.Parent}
+}
+
 // check for presence of --import flag
 func isImportMode() bool {
 	for _, arg := range os.Args {
@@ -229,11 +693,8 @@ func isImportMode() bool {
 	return false
 }
 
-// getConcurrentWorkers the number of workers specified in PULUMI_CLOUD_IMPORT_WORKERS or returns a default of 3
+// getConcurrentWorkers returns the number of workers specified in PULUMI_CLOUD_IMPORT_WORKERS, or a
+// default of 3 to go easy on the API server
 func getConcurrentWorkers() int {
-	workers, err := strconv.Atoi(os.Getenv("PULUMI_CLOUD_IMPORT_WORKERS"))
-	if err != nil {
-		return 10
-	}
-	return workers
+	return workers.Count(3)
 }
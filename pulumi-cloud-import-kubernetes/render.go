@@ -0,0 +1,57 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pulumi/pulumi-cloud-import/pkg/config"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+)
+
+// clusterScoped is the directory cluster scoped objects are rendered to, as no namespace is named
+// with an underscore.
+const clusterScoped = "_cluster"
+
+// serverFields are the metadata fields the API server sets, which manifests leave out.
+var serverFields = []string{
+	"uid", "resourceVersion", "generation", "creationTimestamp", "deletionTimestamp",
+	"deletionGracePeriodSeconds", "selfLink", "managedFields", "ownerReferences",
+}
+
+// renderYAML writes item to the --render-yaml directory as <namespace>/<kind>/<name>.yaml, so that
+// it can be adopted with a ConfigGroup instead of being imported. Objects owned by another object,
+// like the pods of a replica set, are recreated by their controller and left out.
+func renderYAML(item *unstructured.Unstructured) error {
+	dir := config.RenderYAML.Value()
+	if dir == "" || len(item.GetOwnerReferences()) > 0 {
+		return nil
+	}
+
+	obj := item.DeepCopy()
+	for _, field := range serverFields {
+		unstructured.RemoveNestedField(obj.Object, "metadata", field)
+	}
+	unstructured.RemoveNestedField(obj.Object, "metadata", "annotations", "kubectl.kubernetes.io/last-applied-configuration")
+	if len(obj.GetAnnotations()) == 0 {
+		unstructured.RemoveNestedField(obj.Object, "metadata", "annotations")
+	}
+	unstructured.RemoveNestedField(obj.Object, "status")
+
+	b, err := yaml.Marshal(obj.Object)
+	if err != nil {
+		return err
+	}
+	namespace := item.GetNamespace()
+	if namespace == "" {
+		namespace = clusterScoped
+	}
+	// names like system:controller:job-controller aren't valid file names everywhere
+	name := strings.ReplaceAll(item.GetName(), ":", "_")
+	path := filepath.Join(dir, namespace, item.GetKind(), name+".yaml")
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, b, 0644)
+}
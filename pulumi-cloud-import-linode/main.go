@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/pulumi/pulumi-cloud-import/pkg/config"
+	"github.com/pulumi/pulumi-cloud-import/pkg/importer"
+	"github.com/pulumi/pulumi-cloud-import/pkg/naming"
+	"github.com/pulumi/pulumi-cloud-import/pkg/version"
+)
+
+const linodeAPIURL = "https://api.linode.com/v4"
+
+// linodeTypes maps pulumi-linode type tokens to the Linode API path that lists them.
+var linodeTypes = map[string]string{
+	"linode:index/instance:Instance":         "/linode/instances",
+	"linode:index/volume:Volume":             "/volumes",
+	"linode:index/firewall:Firewall":         "/networking/firewalls",
+	"linode:index/nodeBalancer:NodeBalancer": "/nodebalancers",
+	"linode:index/domain:Domain":             "/domains",
+	"linode:index/domainRecord:DomainRecord": "/domains",
+}
+
+// Linode API responses share the same paging envelope. We only need the ID and a label to name the resource.
+type linodeObject struct {
+	ID     int    `json:"id"`
+	Label  string `json:"label"`
+	Domain string `json:"domain"`
+	Name   string `json:"name"`
+	// Created is in UTC, without a time zone, e.g. 2018-01-01T00:01:01
+	Created string `json:"created"`
+}
+
+// createdAt parses the creation time of o, returning the zero time when it's missing.
+func (o linodeObject) createdAt() time.Time {
+	t, _ := time.Parse("2006-01-02T15:04:05", o.Created)
+	return t
+}
+
+type linodePage struct {
+	Data  []linodeObject `json:"data"`
+	Page  int            `json:"page"`
+	Pages int            `json:"pages"`
+}
+
+type linodeDiscoverer struct {
+	token string
+}
+
+func main() {
+	// --version is answered before the credentials are read
+	version.HandleFlag()
+	config.HandleCommand()
+	importer.Main(linodeDiscoverer{token: getToken()})
+}
+
+func (d linodeDiscoverer) Types() []string {
+	types := []string{}
+	for k := range linodeTypes {
+		types = append(types, k)
+	}
+	return types
+}
+
+func (d linodeDiscoverer) List(ctx context.Context, typ string, emit func(importer.ImportSpec)) error {
+	path, ok := linodeTypes[typ]
+	if !ok {
+		return fmt.Errorf("unknown type %s", typ)
+	}
+	objects, err := d.listAll(ctx, path)
+	if err != nil {
+		return err
+	}
+
+	for _, o := range objects {
+		switch typ {
+		case "linode:index/domain:Domain":
+			emit(importer.ImportSpec{
+				ID:      strconv.Itoa(o.ID),
+				Type:    typ,
+				Name:    naming.Default.Name(o.Domain),
+				Created: o.createdAt(),
+			})
+		case "linode:index/domainRecord:DomainRecord":
+			// records are listed per domain and imported as <domainID>,<recordID>
+			records, err := d.listAll(ctx, fmt.Sprintf("/domains/%d/records", o.ID))
+			if err != nil {
+				return err
+			}
+			for _, r := range records {
+				emit(importer.ImportSpec{
+					ID:      fmt.Sprintf("%d,%d", o.ID, r.ID),
+					Type:    typ,
+					Name:    naming.Default.Name(fmt.Sprintf("%s%s%d", o.Domain, r.Name, r.ID)),
+					Created: r.createdAt(),
+				})
+			}
+		default:
+			emit(importer.ImportSpec{
+				ID:      strconv.Itoa(o.ID),
+				Type:    typ,
+				Name:    naming.Default.Name(o.Label),
+				Created: o.createdAt(),
+			})
+		}
+	}
+
+	return nil
+}
+
+// listAll follows the Linode API paging until all objects under path have been read.
+func (d linodeDiscoverer) listAll(ctx context.Context, path string) ([]linodeObject, error) {
+	objects := []linodeObject{}
+	for page := 1; ; page++ {
+		url := fmt.Sprintf("%s%s?page=%d&page_size=500", linodeAPIURL, path, page)
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Authorization", "Bearer "+d.token)
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		var p linodePage
+		err = json.NewDecoder(resp.Body).Decode(&p)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("listing %s returned %s", path, resp.Status)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		objects = append(objects, p.Data...)
+		if p.Page >= p.Pages {
+			return objects, nil
+		}
+	}
+}
+
+// reads LINODE_TOKEN env var or panics if it is not set
+func getToken() string {
+	token := os.Getenv("LINODE_TOKEN")
+	if token == "" {
+		panic("LINODE_TOKEN env var must be set")
+	}
+	return token
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/mongodb-forks/digest"
+	"github.com/pulumi/pulumi-cloud-import/pkg/config"
+	"github.com/pulumi/pulumi-cloud-import/pkg/importer"
+	"github.com/pulumi/pulumi-cloud-import/pkg/naming"
+	"github.com/pulumi/pulumi-cloud-import/pkg/version"
+	"go.mongodb.org/atlas/mongodbatlas"
+)
+
+const (
+	projectType        = "mongodbatlas:index/project:Project"
+	clusterType        = "mongodbatlas:index/cluster:Cluster"
+	databaseUserType   = "mongodbatlas:index/databaseUser:DatabaseUser"
+	networkPeeringType = "mongodbatlas:index/networkPeering:NetworkPeering"
+)
+
+// network peering connections are listed per cloud provider
+var peeringProviders = []string{"AWS", "AZURE", "GCP"}
+
+type atlasDiscoverer struct {
+	client *mongodbatlas.Client
+}
+
+func main() {
+	// --version is answered before the credentials are read
+	version.HandleFlag()
+	config.HandleCommand()
+	client, err := newClient()
+	if err != nil {
+		panic(fmt.Sprintf("Authentication failure: %+v", err))
+	}
+	importer.Main(atlasDiscoverer{client: client})
+}
+
+func (d atlasDiscoverer) Types() []string {
+	return []string{projectType, clusterType, databaseUserType, networkPeeringType}
+}
+
+// List reads all projects the API key has access to and the resources of the given type within them.
+// Import IDs follow the formats documented by the mongodbatlas provider, e.g. <projectId>-<clusterName>.
+func (d atlasDiscoverer) List(ctx context.Context, typ string, emit func(importer.ImportSpec)) error {
+	projects, err := d.listProjects(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, p := range projects {
+		switch typ {
+		case projectType:
+			emit(importer.ImportSpec{
+				ID:   p.ID,
+				Type: typ,
+				Name: naming.Default.Name(p.Name),
+			})
+		case clusterType:
+			for page := 1; ; page++ {
+				clusters, resp, err := d.client.Clusters.List(ctx, p.ID, &mongodbatlas.ListOptions{PageNum: page, ItemsPerPage: 500})
+				if err != nil {
+					return err
+				}
+				for _, c := range clusters {
+					emit(importer.ImportSpec{
+						ID:   fmt.Sprintf("%s-%s", p.ID, c.Name),
+						Type: typ,
+						Name: naming.Default.Name(fmt.Sprintf("%s%s", p.Name, c.Name)),
+					})
+				}
+				if resp.IsLastPage() {
+					break
+				}
+			}
+		case databaseUserType:
+			for page := 1; ; page++ {
+				users, resp, err := d.client.DatabaseUsers.List(ctx, p.ID, &mongodbatlas.ListOptions{PageNum: page, ItemsPerPage: 500})
+				if err != nil {
+					return err
+				}
+				for _, u := range users {
+					emit(importer.ImportSpec{
+						ID:   fmt.Sprintf("%s-%s-%s", p.ID, u.Username, u.DatabaseName),
+						Type: typ,
+						Name: naming.Default.Name(fmt.Sprintf("%s%s", p.Name, u.Username)),
+					})
+				}
+				if resp.IsLastPage() {
+					break
+				}
+			}
+		case networkPeeringType:
+			for _, provider := range peeringProviders {
+				for page := 1; ; page++ {
+					peers, resp, err := d.client.Peers.List(ctx, p.ID, &mongodbatlas.ContainersListOptions{
+						ProviderName: provider,
+						ListOptions:  mongodbatlas.ListOptions{PageNum: page, ItemsPerPage: 500},
+					})
+					if err != nil {
+						return err
+					}
+					for _, peer := range peers {
+						emit(importer.ImportSpec{
+							ID:   fmt.Sprintf("%s-%s-%s", p.ID, peer.ID, provider),
+							Type: typ,
+							Name: naming.Default.Name(fmt.Sprintf("%s%s%s", p.Name, provider, peer.ID)),
+						})
+					}
+					if resp.IsLastPage() {
+						break
+					}
+				}
+			}
+		default:
+			return fmt.Errorf("unknown type %s", typ)
+		}
+	}
+
+	return nil
+}
+
+func (d atlasDiscoverer) listProjects(ctx context.Context) ([]*mongodbatlas.Project, error) {
+	projects := []*mongodbatlas.Project{}
+	for page := 1; ; page++ {
+		res, resp, err := d.client.Projects.GetAllProjects(ctx, &mongodbatlas.ListOptions{PageNum: page, ItemsPerPage: 500})
+		if err != nil {
+			return nil, err
+		}
+		projects = append(projects, res.Results...)
+		if resp.IsLastPage() {
+			return projects, nil
+		}
+	}
+}
+
+// creates an Atlas Admin API client from MONGODB_ATLAS_PUBLIC_KEY and MONGODB_ATLAS_PRIVATE_KEY or panics if they are not set
+func newClient() (*mongodbatlas.Client, error) {
+	publicKey := os.Getenv("MONGODB_ATLAS_PUBLIC_KEY")
+	privateKey := os.Getenv("MONGODB_ATLAS_PRIVATE_KEY")
+	if publicKey == "" || privateKey == "" {
+		panic("MONGODB_ATLAS_PUBLIC_KEY and MONGODB_ATLAS_PRIVATE_KEY env vars must be set")
+	}
+
+	httpClient, err := digest.NewTransport(publicKey, privateKey).Client()
+	if err != nil {
+		return nil, err
+	}
+	return mongodbatlas.NewClient(httpClient), nil
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/pulumi/pulumi-cloud-import/pkg/broker"
+	"gopkg.in/yaml.v3"
+)
+
+// config is the content of cloud-import.yaml
+type config struct {
+	// Directory containing the pulumi-cloud-import-<provider> programs, defaults to the parent of the working directory
+	ProgramsDir string   `yaml:"programsDir"`
+	Targets     []target `yaml:"targets"`
+	// Credentials of the AWS targets with an account
+	Credentials *broker.Config `yaml:"credentials"`
+
+	broker broker.Broker
+}
+
+// target is a single account, subscription or cluster to scan
+type target struct {
+	Name     string `yaml:"name"`
+	Provider string `yaml:"provider"`
+
+	// aws
+	Profile string `yaml:"profile"`
+	Region  string `yaml:"region"`
+	// Account ID whose credentials are obtained from the credential broker
+	Account string `yaml:"account"`
+	// azure
+	Subscription string `yaml:"subscription"`
+	Location     string `yaml:"location"`
+	// kubernetes
+	Kubeconfig string `yaml:"kubeconfig"`
+	Context    string `yaml:"context"`
+
+	// Any additional environment variables passed to the program
+	Env map[string]string `yaml:"env"`
+
+	Filters filters `yaml:"filters"`
+	// Path of the import file written for this target, defaults to <name>.json
+	Output string `yaml:"output"`
+}
+
+// filters select which resources of a target are scanned and end up in its import file. Patterns
+// use path.Match syntax against the resource type, * matching / too, e.g. aws-native:s3:* or
+// kubernetes:*:Deployment
+type filters struct {
+	Types        []string `yaml:"types"`
+	ExcludeTypes []string `yaml:"excludeTypes"`
+}
+
+var supportedProviders = map[string]bool{
+	"aws":        true,
+	"azure":      true,
+	"kubernetes": true,
+}
+
+func loadConfig(path string) (*config, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var c config
+	if err := yaml.Unmarshal(b, &c); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	if c.Credentials != nil {
+		if c.broker, err = c.Credentials.New(); err != nil {
+			return nil, err
+		}
+	}
+
+	seen := map[string]bool{}
+	for i, t := range c.Targets {
+		if t.Name == "" {
+			return nil, fmt.Errorf("target %d has no name", i)
+		}
+		if seen[t.Name] {
+			return nil, fmt.Errorf("target %s is declared more than once", t.Name)
+		}
+		seen[t.Name] = true
+		if !supportedProviders[t.Provider] {
+			return nil, fmt.Errorf("target %s has unsupported provider %q", t.Name, t.Provider)
+		}
+		if t.Account != "" && t.Provider != "aws" {
+			return nil, fmt.Errorf("target %s has an account but only aws targets do", t.Name)
+		}
+		if t.Account != "" && c.broker == nil {
+			return nil, fmt.Errorf("target %s has an account but no credential broker is configured", t.Name)
+		}
+		if t.Output == "" {
+			c.Targets[i].Output = t.Name + ".json"
+		}
+	}
+	return &c, nil
+}
+
+// environ returns the environment variables that point the provider program at this target. The
+// credentials of a target with an account are obtained from broker.
+func (t target) environ(b broker.Broker) ([]string, error) {
+	env := map[string]string{}
+	switch t.Provider {
+	case "aws":
+		env["AWS_PROFILE"] = t.Profile
+		env["AWS_REGION"] = t.Region
+		if t.Account != "" {
+			creds, err := b.Credentials(t.Account)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get the credentials of account %s: %w", t.Account, err)
+			}
+			for k, v := range creds.Environ() {
+				env[k] = v
+			}
+		}
+	case "azure":
+		env["ARM_SUBSCRIPTION_ID"] = t.Subscription
+		env["ARM_LOCATION"] = t.Location
+	case "kubernetes":
+		env["KUBECONFIG"] = t.Kubeconfig
+		env["PULUMI_CLOUD_IMPORT_KUBE_CONTEXT"] = t.Context
+	}
+	for k, v := range t.Env {
+		env[k] = v
+	}
+
+	vars := []string{}
+	for k, v := range env {
+		if v != "" {
+			vars = append(vars, fmt.Sprintf("%s=%s", k, v))
+		}
+	}
+	return vars, nil
+}
@@ -0,0 +1,10 @@
+module github.com/pulumi/pulumi-cloud-import/pulumi-cloud-import-multi
+
+go 1.19
+
+require (
+	github.com/pulumi/pulumi-cloud-import/pkg v0.0.0
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+replace github.com/pulumi/pulumi-cloud-import/pkg => ../pkg
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/pulumi/pulumi-cloud-import/pkg/broker"
+	"github.com/pulumi/pulumi-cloud-import/pkg/compress"
+)
+
+// importFile mirrors the import file written by the provider programs. Resources are kept as raw
+// maps so that every field written by a program survives filtering untouched.
+type importFile struct {
+	Metadata  json.RawMessage          `json:"metadata,omitempty"`
+	NameTable map[string]interface{}   `json:"nameTable"`
+	Resources []map[string]interface{} `json:"resources"`
+}
+
+func main() {
+	configPath := flag.String("config", "cloud-import.yaml", "path to the cloud-import.yaml describing the targets to scan")
+	flag.Parse()
+
+	c, err := loadConfig(*configPath)
+	if err != nil {
+		panic(err)
+	}
+	programsDir := c.ProgramsDir
+	if programsDir == "" {
+		programsDir = ".."
+	}
+
+	failed := []string{}
+	for i, t := range c.Targets {
+		fmt.Printf("target %d of %d: %s (%s)\n", i+1, len(c.Targets), t.Name, t.Provider)
+		if err := runTarget(programsDir, t, c.broker); err != nil {
+			fmt.Printf("target %s failed: %v\n", t.Name, err)
+			failed = append(failed, t.Name)
+			continue
+		}
+		fmt.Printf("target %s written to %s\n", t.Name, t.Output)
+	}
+
+	if len(failed) > 0 {
+		fmt.Printf("%d of %d targets failed: %v\n", len(failed), len(c.Targets), failed)
+		os.Exit(1)
+	}
+}
+
+// runTarget runs the provider program in import mode and writes the filtered import file to the target's output.
+func runTarget(programsDir string, t target, creds broker.Broker) error {
+	environ, err := t.environ(creds)
+	if err != nil {
+		return err
+	}
+	dir := filepath.Join(programsDir, "pulumi-cloud-import-"+t.Provider)
+	// the program scans only the types its flags select, the other filters apply to its import file
+	pushed, rest := t.Filters.pushDown(t.Provider)
+	cmd := exec.Command("go", append([]string{"run", ".", "--import", "--output", "import.json"}, pushed...)...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), environ...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return err
+	}
+
+	generated := filepath.Join(dir, "import.json")
+	b, err := compress.ReadFile(generated)
+	if err != nil {
+		return err
+	}
+	var imports importFile
+	if err := json.Unmarshal(b, &imports); err != nil {
+		return err
+	}
+
+	resources := []map[string]interface{}{}
+	for _, r := range imports.Resources {
+		keep, err := rest.match(resourceType(r))
+		if err != nil {
+			return err
+		}
+		if keep {
+			resources = append(resources, r)
+		}
+	}
+	imports.Resources = resources
+
+	out, err := json.MarshalIndent(imports, "", "    ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(t.Output), 0755); err != nil {
+		return err
+	}
+	// outputs ending in .gz are compressed like the import files of --compress
+	if strings.HasSuffix(t.Output, compress.Ext) {
+		if out, err = compress.Compress(out); err != nil {
+			return err
+		}
+	}
+	if err := os.WriteFile(t.Output, out, 0644); err != nil {
+		return err
+	}
+	return removeImportFile(generated)
+}
+
+// removeImportFile removes the import file a program wrote to path, or to path.gz when it was
+// passed --compress.
+func removeImportFile(path string) error {
+	err := os.Remove(path)
+	if os.IsNotExist(err) {
+		return os.Remove(path + compress.Ext)
+	}
+	return err
+}
+
+func resourceType(r map[string]interface{}) string {
+	t, _ := r["type"].(string)
+	return t
+}
+
+// pushDown splits the filters into the flags of the program of provider, which then doesn't scan
+// the types left out, and the filters its import file is still matched against. The AWS program
+// matches type patterns itself. The Kubernetes program selects kinds, so patterns naming a kind are
+// passed as --kinds, and exclusions of a kind in every group as --exclude-kinds; it also imports
+// the namespaces of the kinds, so the types are matched again.
+func (f filters) pushDown(provider string) ([]string, filters) {
+	switch provider {
+	case "aws":
+		args := []string{}
+		if len(f.Types) > 0 {
+			args = append(args, "--types="+strings.Join(f.Types, ","))
+		}
+		if len(f.ExcludeTypes) > 0 {
+			args = append(args, "--exclude-types="+strings.Join(f.ExcludeTypes, ","))
+		}
+		return args, filters{}
+	case "kubernetes":
+		args := []string{}
+		rest := filters{Types: f.Types}
+		kinds := []string{}
+		for _, p := range f.Types {
+			group, kind, ok := kindPattern(p)
+			if !ok || group == "" {
+				kinds = nil
+				break
+			}
+			kinds = append(kinds, kind)
+		}
+		if len(kinds) > 0 {
+			args = append(args, "--kinds="+strings.Join(kinds, ","))
+		}
+		excluded := []string{}
+		for _, p := range f.ExcludeTypes {
+			if group, kind, ok := kindPattern(p); ok && group == "*" {
+				excluded = append(excluded, kind)
+			} else {
+				rest.ExcludeTypes = append(rest.ExcludeTypes, p)
+			}
+		}
+		if len(excluded) > 0 {
+			args = append(args, "--exclude-kinds="+strings.Join(excluded, ","))
+		}
+		return args, rest
+	default:
+		return nil, f
+	}
+}
+
+// kindPattern returns the group/version and the kind of a pattern of Kubernetes types such as
+// kubernetes:apps/v1:Deployment, or false when it doesn't name a single kind.
+func kindPattern(p string) (string, string, bool) {
+	parts := strings.Split(p, ":")
+	if len(parts) != 3 || parts[0] != "kubernetes" || parts[2] == "" || strings.ContainsAny(parts[2], `*?[\`) {
+		return "", "", false
+	}
+	return parts[1], parts[2], true
+}
+
+// match reports whether typ is kept by the filters. Unlike in path.Match, * matches / too, so that
+// kubernetes:*:Deployment matches kubernetes:apps/v1:Deployment.
+func (f filters) match(typ string) (bool, error) {
+	for _, p := range f.ExcludeTypes {
+		ok, err := glob(p, typ)
+		if err != nil {
+			return false, err
+		}
+		if ok {
+			return false, nil
+		}
+	}
+	if len(f.Types) == 0 {
+		return true, nil
+	}
+	for _, p := range f.Types {
+		ok, err := glob(p, typ)
+		if err != nil {
+			return false, err
+		}
+		if ok {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// glob matches typ against pattern in path.Match syntax, with / matched like any other character.
+func glob(pattern, typ string) (bool, error) {
+	return path.Match(strings.ReplaceAll(pattern, "/", "\x00"), strings.ReplaceAll(typ, "/", "\x00"))
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPushDown(t *testing.T) {
+	tests := []struct {
+		name     string
+		provider string
+		filters  filters
+		args     []string
+		rest     filters
+	}{
+		{
+			name:     "aws matches the patterns itself",
+			provider: "aws",
+			filters:  filters{Types: []string{"aws-native:s3:*", "aws-native:ec2:*"}, ExcludeTypes: []string{"aws-native:ec2:VPC"}},
+			args:     []string{"--types=aws-native:s3:*,aws-native:ec2:*", "--exclude-types=aws-native:ec2:VPC"},
+		},
+		{
+			name:     "kubernetes kinds",
+			provider: "kubernetes",
+			filters:  filters{Types: []string{"kubernetes:apps/v1:Deployment", "kubernetes:*:Service"}, ExcludeTypes: []string{"kubernetes:*:Secret", "kubernetes:batch/v1:Job"}},
+			args:     []string{"--kinds=Deployment,Service", "--exclude-kinds=Secret"},
+			rest:     filters{Types: []string{"kubernetes:apps/v1:Deployment", "kubernetes:*:Service"}, ExcludeTypes: []string{"kubernetes:batch/v1:Job"}},
+		},
+		{
+			name:     "kubernetes patterns of several kinds",
+			provider: "kubernetes",
+			filters:  filters{Types: []string{"kubernetes:apps/v1:Deployment", "kubernetes:apps/v1:*"}},
+			args:     []string{},
+			rest:     filters{Types: []string{"kubernetes:apps/v1:Deployment", "kubernetes:apps/v1:*"}},
+		},
+		{
+			name:     "azure",
+			provider: "azure",
+			filters:  filters{ExcludeTypes: []string{"azure-native:insights:*"}},
+			rest:     filters{ExcludeTypes: []string{"azure-native:insights:*"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args, rest := tt.filters.pushDown(tt.provider)
+			if len(args) != 0 || len(tt.args) != 0 {
+				if !reflect.DeepEqual(args, tt.args) {
+					t.Errorf("got args %q, want %q", args, tt.args)
+				}
+			}
+			if !reflect.DeepEqual(rest, tt.rest) {
+				t.Errorf("got filters %+v, want %+v", rest, tt.rest)
+			}
+		})
+	}
+}
+
+func TestMatch(t *testing.T) {
+	f := filters{Types: []string{"kubernetes:*:Deployment", "azure-native:*"}, ExcludeTypes: []string{"azure-native:insights:*"}}
+	for typ, want := range map[string]bool{
+		"kubernetes:apps/v1:Deployment":       true,
+		"kubernetes:core/v1:Service":          false,
+		"azure-native:storage:StorageAccount": true,
+		"azure-native:insights:Component":     false,
+		"aws-native:s3:Bucket":                false,
+	} {
+		got, err := f.match(typ)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("match(%q) = %v, want %v", typ, got, want)
+		}
+	}
+}
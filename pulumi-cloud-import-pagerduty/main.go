@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/pulumi/pulumi-cloud-import/pkg/config"
+	"github.com/pulumi/pulumi-cloud-import/pkg/importer"
+	"github.com/pulumi/pulumi-cloud-import/pkg/naming"
+	"github.com/pulumi/pulumi-cloud-import/pkg/version"
+)
+
+const pagerdutyAPIURL = "https://api.pagerduty.com"
+
+const (
+	serviceType            = "pagerduty:index/service:Service"
+	escalationPolicyType   = "pagerduty:index/escalationPolicy:EscalationPolicy"
+	scheduleType           = "pagerduty:index/schedule:Schedule"
+	serviceIntegrationType = "pagerduty:index/serviceIntegration:ServiceIntegration"
+)
+
+type pagerdutyType struct {
+	// Path of the list endpoint
+	Path string
+	// Key of the JSON array holding the resources in the response
+	Key string
+}
+
+var pagerdutyTypes = map[string]pagerdutyType{
+	serviceType:          {Path: "/services", Key: "services"},
+	escalationPolicyType: {Path: "/escalation_policies", Key: "escalation_policies"},
+	scheduleType:         {Path: "/schedules", Key: "schedules"},
+	// integrations are only listed as part of their service
+	serviceIntegrationType: {Path: "/services?include[]=integrations", Key: "services"},
+}
+
+type pagerdutyObject struct {
+	ID           string `json:"id"`
+	Name         string `json:"name"`
+	Summary      string `json:"summary"`
+	Integrations []struct {
+		ID      string `json:"id"`
+		Summary string `json:"summary"`
+	} `json:"integrations"`
+}
+
+type pagerdutyDiscoverer struct {
+	token string
+}
+
+func main() {
+	// --version is answered before the credentials are read
+	version.HandleFlag()
+	config.HandleCommand()
+	importer.Main(pagerdutyDiscoverer{token: getToken()})
+}
+
+func (d pagerdutyDiscoverer) Types() []string {
+	types := []string{}
+	for k := range pagerdutyTypes {
+		types = append(types, k)
+	}
+	return types
+}
+
+// List pages through the REST API list endpoint of the given type. Integrations are imported as
+// <serviceID>.<integrationID>, everything else by ID.
+func (d pagerdutyDiscoverer) List(ctx context.Context, typ string, emit func(importer.ImportSpec)) error {
+	t, ok := pagerdutyTypes[typ]
+	if !ok {
+		return fmt.Errorf("unknown type %s", typ)
+	}
+
+	const limit = 100
+	for offset := 0; ; offset += limit {
+		sep := "?"
+		if typ == serviceIntegrationType {
+			sep = "&"
+		}
+		url := fmt.Sprintf("%s%s%slimit=%d&offset=%d", pagerdutyAPIURL, t.Path, sep, limit, offset)
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Token token="+d.token)
+		req.Header.Set("Accept", "application/vnd.pagerduty+json;version=2")
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return err
+		}
+		// responses look like {"services": [...], "more": true, ...}
+		var body map[string]json.RawMessage
+		err = json.NewDecoder(resp.Body).Decode(&body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("listing %s returned %s", t.Path, resp.Status)
+		}
+		if err != nil {
+			return err
+		}
+
+		var objects []pagerdutyObject
+		if err := json.Unmarshal(body[t.Key], &objects); err != nil {
+			return err
+		}
+		for _, o := range objects {
+			if typ == serviceIntegrationType {
+				for _, i := range o.Integrations {
+					emit(importer.ImportSpec{
+						ID:   fmt.Sprintf("%s.%s", o.ID, i.ID),
+						Type: typ,
+						Name: naming.Default.Name(o.Name + i.Summary),
+					})
+				}
+				continue
+			}
+			emit(importer.ImportSpec{
+				ID:   o.ID,
+				Type: typ,
+				Name: naming.Default.Name(o.Name),
+			})
+		}
+
+		var more bool
+		if err := json.Unmarshal(body["more"], &more); err != nil || !more {
+			return err
+		}
+	}
+}
+
+// reads PAGERDUTY_TOKEN env var or panics if it is not set
+func getToken() string {
+	token := os.Getenv("PAGERDUTY_TOKEN")
+	if token == "" {
+		panic("PAGERDUTY_TOKEN env var must be set")
+	}
+	return token
+}
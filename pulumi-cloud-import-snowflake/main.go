@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/pulumi/pulumi-cloud-import/pkg/config"
+	"github.com/pulumi/pulumi-cloud-import/pkg/importer"
+	"github.com/pulumi/pulumi-cloud-import/pkg/naming"
+	"github.com/pulumi/pulumi-cloud-import/pkg/version"
+)
+
+const (
+	databaseType   = "snowflake:index/database:Database"
+	schemaType     = "snowflake:index/schema:Schema"
+	warehouseType  = "snowflake:index/warehouse:Warehouse"
+	roleType       = "snowflake:index/role:Role"
+	roleGrantsType = "snowflake:index/roleGrants:RoleGrants"
+)
+
+// objects created by Snowflake itself that can't be managed by pulumi
+var systemObjects = map[string]bool{
+	"SNOWFLAKE":          true,
+	"INFORMATION_SCHEMA": true,
+	"ACCOUNTADMIN":       true,
+	"ORGADMIN":           true,
+	"SECURITYADMIN":      true,
+	"SYSADMIN":           true,
+	"USERADMIN":          true,
+	"PUBLIC":             true,
+}
+
+type snowflakeDiscoverer struct {
+	client *sqlAPIClient
+}
+
+func main() {
+	// --version is answered before the credentials are read
+	version.HandleFlag()
+	config.HandleCommand()
+	client, err := newSQLAPIClient()
+	if err != nil {
+		panic(fmt.Sprintf("Authentication failure: %+v", err))
+	}
+	importer.Main(snowflakeDiscoverer{client: client})
+}
+
+// Identity returns the account scanned and who it was scanned as, recorded in the run manifest.
+func (d snowflakeDiscoverer) Identity() map[string]string {
+	return map[string]string{"account": d.client.account, "user": d.client.user, "role": d.client.role}
+}
+
+func (d snowflakeDiscoverer) Types() []string {
+	return []string{databaseType, schemaType, warehouseType, roleType, roleGrantsType}
+}
+
+// List runs the SHOW command matching the given type through the SQL API. Objects are imported by
+// name, schemas as <database>|<schema>.
+func (d snowflakeDiscoverer) List(ctx context.Context, typ string, emit func(importer.ImportSpec)) error {
+	switch typ {
+	case databaseType:
+		rows, err := d.client.query(ctx, "SHOW DATABASES")
+		if err != nil {
+			return err
+		}
+		for _, r := range rows {
+			// databases created from shares are read only
+			if systemObjects[r["name"]] || r["kind"] == "IMPORTED DATABASE" {
+				continue
+			}
+			emit(importer.ImportSpec{ID: r["name"], Type: typ, Name: naming.Default.Name(r["name"])})
+		}
+	case schemaType:
+		rows, err := d.client.query(ctx, "SHOW SCHEMAS IN ACCOUNT")
+		if err != nil {
+			return err
+		}
+		for _, r := range rows {
+			if systemObjects[r["database_name"]] || systemObjects[r["name"]] {
+				continue
+			}
+			emit(importer.ImportSpec{
+				ID:   fmt.Sprintf("%s|%s", r["database_name"], r["name"]),
+				Type: typ,
+				Name: naming.Default.Name(r["database_name"] + r["name"]),
+			})
+		}
+	case warehouseType:
+		rows, err := d.client.query(ctx, "SHOW WAREHOUSES")
+		if err != nil {
+			return err
+		}
+		for _, r := range rows {
+			emit(importer.ImportSpec{ID: r["name"], Type: typ, Name: naming.Default.Name(r["name"])})
+		}
+	case roleType, roleGrantsType:
+		rows, err := d.client.query(ctx, "SHOW ROLES")
+		if err != nil {
+			return err
+		}
+		for _, r := range rows {
+			name := r["name"]
+			if systemObjects[name] {
+				continue
+			}
+			if typ == roleGrantsType {
+				// only roles that are granted to other roles or users have a grants resource
+				grants, err := d.client.query(ctx, fmt.Sprintf(`SHOW GRANTS OF ROLE "%s"`, strings.ReplaceAll(name, `"`, `""`)))
+				if err != nil {
+					return err
+				}
+				if len(grants) == 0 {
+					continue
+				}
+			}
+			emit(importer.ImportSpec{ID: name, Type: typ, Name: naming.Default.Name(name)})
+		}
+	default:
+		return fmt.Errorf("unknown type %s", typ)
+	}
+
+	return nil
+}
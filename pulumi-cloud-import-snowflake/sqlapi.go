@@ -0,0 +1,255 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	// statementTimeout is how long Snowflake runs a statement before cancelling it.
+	statementTimeout = 60 * time.Second
+	// requestTimeout bounds every request to the SQL API, and the polling of a statement past its
+	// own timeout.
+	requestTimeout = 30 * time.Second
+	// pollInterval is the delay between polls of a running statement.
+	pollInterval = time.Second
+)
+
+// httpClient sends the requests of the SQL API through the transport of the importer, which
+// records, replays and injects failures.
+var httpClient = &http.Client{Timeout: requestTimeout}
+
+// sqlAPIClient runs statements through the Snowflake SQL API
+// (https://docs.snowflake.com/en/developer-guide/sql-api/index).
+type sqlAPIClient struct {
+	account   string
+	user      string
+	baseURL   string
+	role      string
+	warehouse string
+
+	tokenType string
+	token     func() (string, error)
+}
+
+type statementRequest struct {
+	Statement string `json:"statement"`
+	Timeout   int    `json:"timeout"`
+	Role      string `json:"role,omitempty"`
+	Warehouse string `json:"warehouse,omitempty"`
+}
+
+type statementResponse struct {
+	Code              string `json:"code"`
+	Message           string `json:"message"`
+	StatementHandle   string `json:"statementHandle"`
+	ResultSetMetaData struct {
+		RowType []struct {
+			Name string `json:"name"`
+		} `json:"rowType"`
+		PartitionInfo []json.RawMessage `json:"partitionInfo"`
+	} `json:"resultSetMetaData"`
+	Data [][]*string `json:"data"`
+}
+
+// newSQLAPIClient reads the same env vars as the pulumi-snowflake provider. An OAuth access token is
+// used when SNOWFLAKE_OAUTH_ACCESS_TOKEN is set, key pair authentication otherwise.
+func newSQLAPIClient() (*sqlAPIClient, error) {
+	account := os.Getenv("SNOWFLAKE_ACCOUNT")
+	if account == "" {
+		panic("SNOWFLAKE_ACCOUNT env var must be set")
+	}
+	c := &sqlAPIClient{
+		account:   account,
+		baseURL:   fmt.Sprintf("https://%s.snowflakecomputing.com/api/v2/statements", account),
+		role:      os.Getenv("SNOWFLAKE_ROLE"),
+		warehouse: os.Getenv("SNOWFLAKE_WAREHOUSE"),
+	}
+
+	if token := os.Getenv("SNOWFLAKE_OAUTH_ACCESS_TOKEN"); token != "" {
+		c.tokenType = "OAUTH"
+		c.token = func() (string, error) { return token, nil }
+		return c, nil
+	}
+
+	user := os.Getenv("SNOWFLAKE_USER")
+	keyPath := os.Getenv("SNOWFLAKE_PRIVATE_KEY_PATH")
+	if user == "" || keyPath == "" {
+		panic("SNOWFLAKE_OAUTH_ACCESS_TOKEN or SNOWFLAKE_USER and SNOWFLAKE_PRIVATE_KEY_PATH env vars must be set")
+	}
+	key, err := readPrivateKey(keyPath)
+	if err != nil {
+		return nil, err
+	}
+	c.user = user
+	c.tokenType = "KEYPAIR_JWT"
+	c.token = func() (string, error) { return keyPairJWT(account, user, key) }
+	return c, nil
+}
+
+// query runs a statement and returns every row of every partition as a column name to value map.
+func (c *sqlAPIClient) query(ctx context.Context, statement string) ([]map[string]string, error) {
+	body, err := json.Marshal(statementRequest{
+		Statement: statement,
+		Timeout:   int(statementTimeout / time.Second),
+		Role:      c.role,
+		Warehouse: c.warehouse,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := c.do(ctx, http.MethodPost, c.baseURL, body)
+	if err != nil {
+		return nil, err
+	}
+
+	rows := []map[string]string{}
+	appendRows := func(r *statementResponse) {
+		for _, d := range r.Data {
+			row := map[string]string{}
+			for i, col := range res.ResultSetMetaData.RowType {
+				if i < len(d) && d[i] != nil {
+					row[col.Name] = *d[i]
+				}
+			}
+			rows = append(rows, row)
+		}
+	}
+	appendRows(res)
+
+	// large results are split into partitions which need to be fetched one by one
+	for p := 1; p < len(res.ResultSetMetaData.PartitionInfo); p++ {
+		part, err := c.do(ctx, http.MethodGet, fmt.Sprintf("%s/%s?partition=%d", c.baseURL, res.StatementHandle, p), nil)
+		if err != nil {
+			return nil, err
+		}
+		appendRows(part)
+	}
+
+	return rows, nil
+}
+
+// do sends a request to the SQL API, polling for the result of a statement still running until
+// Snowflake times it out, and a little longer.
+func (c *sqlAPIClient) do(ctx context.Context, method, url string, body []byte) (*statementResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, statementTimeout+requestTimeout)
+	defer cancel()
+	for {
+		token, err := c.token()
+		if err != nil {
+			return nil, err
+		}
+		req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("X-Snowflake-Authorization-Token-Type", c.tokenType)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Accept", "application/json")
+
+		resp, err := httpClient.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		b, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		// failures aren't always JSON, e.g. the errors of a proxy or a load balancer
+		var res statementResponse
+		if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusAccepted {
+			if json.Unmarshal(b, &res) != nil || res.Message == "" {
+				res.Message = strings.TrimSpace(string(b))
+			}
+			return nil, fmt.Errorf("statement failed with %s: %s", resp.Status, res.Message)
+		}
+		if err := json.Unmarshal(b, &res); err != nil {
+			return nil, fmt.Errorf("failed to parse the response of the SQL API: %w", err)
+		}
+
+		if resp.StatusCode == http.StatusOK {
+			return &res, nil
+		}
+
+		// the statement is still running, poll for its result
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("statement %s didn't complete: %w", res.StatementHandle, ctx.Err())
+		case <-time.After(pollInterval):
+		}
+		method, url, body = http.MethodGet, fmt.Sprintf("%s/%s", c.baseURL, res.StatementHandle), nil
+	}
+}
+
+func readPrivateKey(path string) (*rsa.PrivateKey, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data found in %s", path)
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("private key must be an RSA key")
+	}
+	return rsaKey, nil
+}
+
+// keyPairJWT builds the JWT described in
+// https://docs.snowflake.com/en/developer-guide/sql-api/authenticating#using-key-pair-authentication
+func keyPairJWT(account, user string, key *rsa.PrivateKey) (string, error) {
+	pub, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		return "", err
+	}
+	fingerprint := sha256.Sum256(pub)
+	// the account identifier in the token must not include the region or cloud
+	accountLocator := strings.Split(account, ".")[0]
+	qualifiedUser := fmt.Sprintf("%s.%s", strings.ToUpper(accountLocator), strings.ToUpper(user))
+
+	now := time.Now()
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+	claims, err := json.Marshal(map[string]interface{}{
+		"iss": fmt.Sprintf("%s.SHA256:%s", qualifiedUser, base64.StdEncoding.EncodeToString(fingerprint[:])),
+		"sub": qualifiedUser,
+		"iat": now.Unix(),
+		"exp": now.Add(time.Hour).Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(nil, key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
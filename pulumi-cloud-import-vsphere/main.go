@@ -0,0 +1,210 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+
+	"github.com/pulumi/pulumi-cloud-import/pkg/config"
+	"github.com/pulumi/pulumi-cloud-import/pkg/importer"
+	"github.com/pulumi/pulumi-cloud-import/pkg/naming"
+	"github.com/pulumi/pulumi-cloud-import/pkg/version"
+	"github.com/vmware/govmomi"
+	"github.com/vmware/govmomi/find"
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+const (
+	datacenterType               = "vsphere:index/datacenter:Datacenter"
+	folderType                   = "vsphere:index/folder:Folder"
+	virtualMachineType           = "vsphere:index/virtualMachine:VirtualMachine"
+	vmfsDatastoreType            = "vsphere:index/vmfsDatastore:VmfsDatastore"
+	nasDatastoreType             = "vsphere:index/nasDatastore:NasDatastore"
+	distributedVirtualSwitchType = "vsphere:index/distributedVirtualSwitch:DistributedVirtualSwitch"
+	distributedPortGroupType     = "vsphere:index/distributedPortGroup:DistributedPortGroup"
+)
+
+type vsphereDiscoverer struct {
+	client *govmomi.Client
+}
+
+func main() {
+	// --version is answered before the credentials are read
+	version.HandleFlag()
+	config.HandleCommand()
+	client, err := newClient(context.Background())
+	if err != nil {
+		panic(fmt.Sprintf("Failed to connect to vCenter: %+v", err))
+	}
+	importer.Main(vsphereDiscoverer{client: client})
+}
+
+// Identity returns the vCenter scanned and the user logged into it, recorded in the run manifest.
+func (d vsphereDiscoverer) Identity() map[string]string {
+	return map[string]string{"server": os.Getenv("VSPHERE_SERVER"), "user": os.Getenv("VSPHERE_USER")}
+}
+
+func (d vsphereDiscoverer) Types() []string {
+	return []string{
+		datacenterType,
+		folderType,
+		virtualMachineType,
+		vmfsDatastoreType,
+		nasDatastoreType,
+		distributedVirtualSwitchType,
+		distributedPortGroupType,
+	}
+}
+
+// List walks every datacenter of the vCenter inventory. Most pulumi-vsphere resources are
+// imported by inventory path (e.g. /dc1/vm/folder/srv1), datastores by managed object ID.
+func (d vsphereDiscoverer) List(ctx context.Context, typ string, emit func(importer.ImportSpec)) error {
+	finder := find.NewFinder(d.client.Client, true)
+	dcs, err := finder.DatacenterList(ctx, "...")
+	if err != nil {
+		return ignoreNotFound(err)
+	}
+
+	for _, dc := range dcs {
+		finder.SetDatacenter(dc)
+		dcPath := dc.InventoryPath
+
+		switch typ {
+		case datacenterType:
+			emit(importer.ImportSpec{
+				ID:   dcPath,
+				Type: typ,
+				Name: naming.Default.Name(dc.Name()),
+			})
+		case folderType:
+			for _, root := range []string{"vm", "host", "datastore", "network"} {
+				rootPath := path.Join(dcPath, root)
+				folders, err := finder.FolderList(ctx, rootPath+"/...")
+				if err != nil {
+					if err = ignoreNotFound(err); err != nil {
+						return err
+					}
+					continue
+				}
+				for _, f := range folders {
+					// the datacenter's default folders can't be managed by pulumi
+					if f.InventoryPath == rootPath {
+						continue
+					}
+					emit(importer.ImportSpec{
+						ID:   f.InventoryPath,
+						Type: typ,
+						Name: naming.Default.Name(f.InventoryPath),
+					})
+				}
+			}
+		case virtualMachineType:
+			vms, err := finder.VirtualMachineList(ctx, path.Join(dcPath, "vm")+"/...")
+			if err != nil {
+				if err = ignoreNotFound(err); err != nil {
+					return err
+				}
+				continue
+			}
+			for _, vm := range vms {
+				emit(importer.ImportSpec{
+					ID:   vm.InventoryPath,
+					Type: typ,
+					Name: naming.Default.Name(vm.InventoryPath),
+				})
+			}
+		case vmfsDatastoreType, nasDatastoreType:
+			datastores, err := finder.DatastoreList(ctx, path.Join(dcPath, "datastore")+"/...")
+			if err != nil {
+				if err = ignoreNotFound(err); err != nil {
+					return err
+				}
+				continue
+			}
+			for _, ds := range datastores {
+				fsType, err := ds.Type(ctx)
+				if err != nil {
+					return err
+				}
+				isNas := fsType == types.HostFileSystemVolumeFileSystemTypeNFS || fsType == types.HostFileSystemVolumeFileSystemTypeNFS41
+				if isNas != (typ == nasDatastoreType) {
+					continue
+				}
+				// vSAN and vVol datastores are created by their services and can't be imported
+				if !isNas && fsType != types.HostFileSystemVolumeFileSystemTypeVMFS {
+					importer.DebugLog("skipping datastore", ds.InventoryPath, "of type", fsType)
+					continue
+				}
+				emit(importer.ImportSpec{
+					ID:   ds.Reference().Value,
+					Type: typ,
+					Name: naming.Default.Name(fmt.Sprintf("%s%s", dc.Name(), ds.Name())),
+				})
+			}
+		case distributedVirtualSwitchType, distributedPortGroupType:
+			networks, err := finder.NetworkList(ctx, path.Join(dcPath, "network")+"/...")
+			if err != nil {
+				if err = ignoreNotFound(err); err != nil {
+					return err
+				}
+				continue
+			}
+			for _, n := range networks {
+				var inventoryPath string
+				switch o := n.(type) {
+				case *object.DistributedVirtualSwitch:
+					if typ != distributedVirtualSwitchType {
+						continue
+					}
+					inventoryPath = o.InventoryPath
+				case *object.DistributedVirtualPortgroup:
+					if typ != distributedPortGroupType {
+						continue
+					}
+					inventoryPath = o.InventoryPath
+				default:
+					// standard port groups are host scoped and imported through vsphere:index/hostPortGroup
+					continue
+				}
+				emit(importer.ImportSpec{
+					ID:   inventoryPath,
+					Type: typ,
+					Name: naming.Default.Name(inventoryPath),
+				})
+			}
+		default:
+			return fmt.Errorf("unknown type %s", typ)
+		}
+	}
+
+	return nil
+}
+
+// newClient logs into vCenter using the same env vars as the pulumi-vsphere provider.
+func newClient(ctx context.Context) (*govmomi.Client, error) {
+	server := os.Getenv("VSPHERE_SERVER")
+	if server == "" {
+		panic("VSPHERE_SERVER env var must be set")
+	}
+	u, err := url.Parse(fmt.Sprintf("https://%s/sdk", server))
+	if err != nil {
+		return nil, err
+	}
+	u.User = url.UserPassword(os.Getenv("VSPHERE_USER"), os.Getenv("VSPHERE_PASSWORD"))
+
+	insecure := os.Getenv("VSPHERE_ALLOW_UNVERIFIED_SSL") == "true"
+	return govmomi.NewClient(ctx, u, insecure)
+}
+
+// an empty inventory folder is reported as a NotFoundError by the finder
+func ignoreNotFound(err error) error {
+	var notFound *find.NotFoundError
+	if errors.As(err, &notFound) {
+		return nil
+	}
+	return err
+}